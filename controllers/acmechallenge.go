@@ -0,0 +1,402 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=zones,verbs=delete
+// +kubebuilder:rbac:groups=dockyards.io,resources=workloads,verbs=delete
+
+// acmeChallengeZoneName returns the name of the zone that holds the DNS-01 challenges of a cluster zone.
+func acmeChallengeZoneName(zone *pdnsv1.Zone) string {
+	return acmeChallengeZonePrefix + zone.Name
+}
+
+// isACMEEnabled reports whether DNS-01 challenges are enabled for the zone, the annotation overrides the config key.
+func (r *ZoneReconciler) isACMEEnabled(zone *pdnsv1.Zone) (bool, error) {
+	value, found := zone.Annotations[AnnotationACMEChallenges]
+	if !found {
+		value = r.GetValueOrDefault(KeyACMEChallenges, "false")
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %s for ACME challenges: %w", value, err)
+	}
+
+	return enabled, nil
+}
+
+// parseACMEHosts parses the comma-separated list of hosts that need DNS-01 challenges, "@" is the zone apex.
+func parseACMEHosts(value string) ([]string, error) {
+	var hosts []string
+
+	for _, host := range strings.Split(value, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+
+		if host != "@" {
+			errs := validation.IsDNS1123Subdomain(host)
+			if len(errs) > 0 {
+				return nil, fmt.Errorf("invalid host %s: %s", host, strings.Join(errs, ", "))
+			}
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+// acmeChallengeRecord returns the relative challenge name in the cluster zone and its CNAME target in the challenge zone.
+func acmeChallengeRecord(zone *pdnsv1.Zone, host string) (string, string) {
+	challengeZoneName := acmeChallengeZoneName(zone)
+
+	if host == "@" {
+		return acmeChallengeLabel, challengeZoneName + "."
+	}
+
+	return acmeChallengeLabel + "." + host, host + "." + challengeZoneName + "."
+}
+
+// reconcileACMEChallenge provisions a delegated challenge zone with a zone scoped TSIG key and a cert-manager issuer workload.
+//
+// Challenge names in the cluster zone are CNAMEs into the challenge zone, so the key only allows updates to the challenge zone.
+func (r *ZoneReconciler) reconcileACMEChallenge(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, ips *PDNSIPs) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	enabled, err := r.isACMEEnabled(zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !enabled {
+		return r.deleteACMEChallenge(ctx, zone, cluster, ips)
+	}
+
+	hosts, err := parseACMEHosts(r.GetValueOrDefault(KeyACMEHosts, defaultACMEHosts))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("invalid value for config key `%s`: %w", KeyACMEHosts, err)
+	}
+
+	challengeZone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      acmeChallengeZoneName(zone),
+			Namespace: zone.Namespace,
+		},
+	}
+
+	claimed, err := r.Shard.claimZone(ctx, r.Client, client.ObjectKeyFromObject(&challengeZone))
+	if err != nil || !claimed {
+		return ctrl.Result{}, err
+	}
+
+	// The challenge zone is served by the nameservers of the cluster zone. Resolvers already know their addresses,
+	// ns1 has an A record in the cluster zone, so the delegation needs no glue.
+	nameservers := delegationRecords(zone)
+
+	spec := pdnsv1.ZoneSpec{
+		Kind: "Native",
+	}
+
+	for _, nameserver := range nameservers {
+		spec.Nameservers = append(spec.Nameservers, strings.TrimSuffix(nameserver, "."))
+	}
+
+	ac := zoneApply(challengeZone.Name, challengeZone.Namespace, spec)
+
+	ac.WithLabels(map[string]string{
+		dockyardsv1.LabelClusterName: cluster.Name,
+		LabelZoneType:                ZoneTypeACMEChallenge,
+	})
+	r.Shard.applyLabel(ac.ObjectMetaApplyConfiguration)
+	// The challenge zone is served next to the cluster zone, the ACME workload uses its endpoints.
+	ac.WithAnnotations(map[string]string{
+		AnnotationPDNSBackend: zone.Annotations[AnnotationPDNSBackend],
+	})
+	ac.WithOwnerReferences(ownerReferenceApplyConfiguration(zoneOwnerReference(zone)))
+
+	operationResult, err := r.Shard.applyZone(ctx, r.Client, &challengeZone, ac)
+	if errors.Is(err, errClaimLost) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Reconciled ACME challenge Zone", "zone", zone.Name, "challengeZone", challengeZone.Name, "operationResult", operationResult)
+
+	desired := map[string]pdnsv1.RRsetSpec{
+		"acme-ns." + zone.Name: {
+			Type:    "NS",
+			TTL:     uint32(zoneTTL),
+			Name:    strings.TrimSuffix(acmeChallengeZonePrefix, "."),
+			Records: nameservers,
+			ZoneRef: pdnsv1.ZoneRef{
+				Name: zone.Name,
+				Kind: zone.Kind,
+			},
+		},
+	}
+
+	for _, host := range hosts {
+		name, target := acmeChallengeRecord(zone, host)
+
+		key := host
+		if host == "@" {
+			key = "apex"
+		}
+
+		desired["acme."+key+"."+zone.Name] = pdnsv1.RRsetSpec{
+			Type: "CNAME",
+			TTL:  uint32(zoneTTL),
+			Name: name,
+			Records: []string{
+				target,
+			},
+			ZoneRef: pdnsv1.ZoneRef{
+				Name: zone.Name,
+				Kind: zone.Kind,
+			},
+		}
+	}
+
+	backend, err := r.backend(ctx, zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for rrsetName, spec := range desired {
+		record := BackendRecord{
+			ID: rrsetName,
+			Labels: map[string]string{
+				LabelACMEChallenge: challengeZone.Name,
+			},
+			Spec: spec,
+		}
+
+		operationResult, err := backend.EnsureRecord(ctx, zone, record)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Reconciled ACME challenge RRSet", "zone", zone.Name, "rrset", record.ID, "operationResult", operationResult)
+	}
+
+	err = r.deleteACMEChallengeRRsets(ctx, zone, desired)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status, err := backend.ZoneStatus(ctx, &challengeZone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !status.Ready {
+		logger.Info("Waiting for ACME challenge zone", "challengeZone", challengeZone.Name, "syncStatus", status.Message)
+
+		return ctrl.Result{}, nil
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	key, err := r.reconcileTSIGKey(ctx, pdnsClient, &challengeZone, tsigSecretName(&challengeZone), challengeZone.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = pdnsClient.EnsureZoneMetadata(ctx, challengeZone.Name, pdnsapi.MetadataTSIGAllowDNSUpdate, []string{key.Name})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	networks, err := r.allowDNSUpdateFrom()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = pdnsClient.EnsureZoneMetadata(ctx, challengeZone.Name, pdnsapi.MetadataAllowDNSUpdateFrom, networks)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	publicNamespace, found := r.GetValueForKey(dyconfig.KeyPublicNamespace)
+	if !found {
+		return ctrl.Result{}, fmt.Errorf("config key `%s` not found", dyconfig.KeyPublicNamespace)
+	}
+	if publicNamespace == "" {
+		return ctrl.Result{}, fmt.Errorf("no value for config key `%s`", dyconfig.KeyPublicNamespace)
+	}
+
+	workload := dockyardsv1.Workload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.Name + "-acme-dns01",
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"acmeServer":    r.GetValueOrDefault(KeyACMEServer, defaultACMEServer),
+		"email":         r.GetValueOrDefault(KeyACMEEmail, ""),
+		"nameserver":    net.JoinHostPort(ips.DNSIP, "53"),
+		"zone":          challengeZone.Name,
+		"cnameStrategy": "Follow",
+		"tsigKeyName":   key.Name,
+		"tsigAlgorithm": acmeCertManagerTSIGAlgorithm,
+		"credentials": map[string]string{
+			"tsigSecret": key.Key,
+		},
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	workloadApply := clusterComponentWorkloadApply(workload.Name, cluster, acmeWorkloadTargetNamespace, acmeWorkloadTemplateName, publicNamespace, raw)
+
+	operationResult, err = applyObject(ctx, r.Client, &workload, workloadApply)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Reconciled ACME Workload", "cluster", cluster.Name, "workload", workload.Name, "operationResult", operationResult)
+
+	return ctrl.Result{}, nil
+}
+
+// deleteACMEChallengeRRsets removes challenge RRsets of the zone that are not desired.
+func (r *ZoneReconciler) deleteACMEChallengeRRsets(ctx context.Context, zone *pdnsv1.Zone, desired map[string]pdnsv1.RRsetSpec) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	backend, err := r.backend(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	records, err := backend.ListRecords(ctx, zone, LabelACMEChallenge)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		_, isDesired := desired[record.ID]
+		if isDesired || record.Labels[LabelACMEChallenge] != acmeChallengeZoneName(zone) {
+			continue
+		}
+
+		deleted, err := backend.DeleteRecord(ctx, zone, record)
+		if err != nil {
+			return err
+		}
+
+		if deleted {
+			logger.Info("Deleted ACME challenge RRSet", "zone", zone.Name, "rrset", record.ID)
+		}
+	}
+
+	return nil
+}
+
+// deleteACMEChallenge removes the challenge zone, its records, TSIG key and issuer workload once challenges are disabled.
+func (r *ZoneReconciler) deleteACMEChallenge(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, ips *PDNSIPs) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	err := r.deleteACMEChallengeRRsets(ctx, zone, nil)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	workload := dockyardsv1.Workload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.Name + "-acme-dns01",
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	err = r.Get(ctx, client.ObjectKeyFromObject(&workload), &workload)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err == nil && !metav1.HasAnnotation(workload.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) {
+		err := r.Delete(ctx, &workload)
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Deleted ACME Workload", "cluster", cluster.Name, "workload", workload.Name)
+	}
+
+	challengeZone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      acmeChallengeZoneName(zone),
+			Namespace: zone.Namespace,
+		},
+	}
+
+	err = r.Get(ctx, client.ObjectKeyFromObject(&challengeZone), &challengeZone)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if metav1.HasAnnotation(challengeZone.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) || !r.Shard.Owns(&challengeZone) {
+		return ctrl.Result{}, nil
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = pdnsClient.DeleteTSIGKey(ctx, challengeZone.Name)
+	if pdnsapi.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = r.Delete(ctx, &challengeZone)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Deleted ACME challenge Zone", "zone", zone.Name, "challengeZone", challengeZone.Name)
+
+	return ctrl.Result{}, nil
+}
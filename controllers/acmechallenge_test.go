@@ -0,0 +1,286 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"github.com/sudoswedenab/dockyards-pdns/test/fakepdns"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileACMEChallenge(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = corev1.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	server := fakepdns.NewServer("test-api-key")
+	t.Cleanup(server.Close)
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster",
+			Namespace: "testing",
+			UID:       "d4e5f6",
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-cluster.test.com",
+			Namespace: "testing",
+			UID:       "a1b2c3",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: cluster.Name,
+			},
+			Annotations: map[string]string{
+				AnnotationACMEChallenges: "true",
+			},
+		},
+	}
+
+	dnsService := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pdns-dns",
+			Namespace: "pdns",
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "2001:db8::53"}},
+			},
+		},
+	}
+
+	apiService := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pdns-api",
+			Namespace: "pdns",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIPs: []string{"10.0.0.1"},
+		},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&cluster, &zone, &dnsService, &apiService).
+		WithStatusSubresource(&pdnsv1.Zone{}).
+		Build()
+
+	r := ZoneReconciler{
+		Client: c,
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyPDNSName):                 "pdns",
+			string(KeyPDNSNamespace):            "pdns",
+			string(dyconfig.KeyPublicNamespace): "dockyards-public",
+			string(KeyACMEHosts):                "@,apps",
+		}),
+		PDNSClient: pdnsapi.NewClient(server.URL, "test-api-key"),
+	}
+
+	ips := PDNSIPs{
+		DNSIP: "2001:db8::53",
+	}
+
+	challengeZoneName := acmeChallengeZoneName(&zone)
+	challengeZoneID := pdnsapi.CanonicalName(challengeZoneName)
+	workloadKey := client.ObjectKey{Name: cluster.Name + "-acme-dns01", Namespace: cluster.Namespace}
+
+	t.Run("test challenge zone", func(t *testing.T) {
+		_, err := r.reconcileACMEChallenge(ctx, &zone, &cluster, &ips)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var challengeZone pdnsv1.Zone
+		err = c.Get(ctx, client.ObjectKey{Name: challengeZoneName, Namespace: zone.Namespace}, &challengeZone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if challengeZone.Labels[LabelZoneType] != ZoneTypeACMEChallenge {
+			t.Errorf("expected zone type %s, got %s", ZoneTypeACMEChallenge, challengeZone.Labels[LabelZoneType])
+		}
+
+		if !metav1.IsControlledBy(&challengeZone, &zone) {
+			t.Error("expected challenge zone to be controlled by the cluster zone")
+		}
+
+		expectedNameservers := []string{"ns1." + zone.Name}
+		if !cmp.Equal(challengeZone.Spec.Nameservers, expectedNameservers) {
+			t.Error(cmp.Diff(expectedNameservers, challengeZone.Spec.Nameservers))
+		}
+
+		var delegation pdnsv1.RRset
+		err = c.Get(ctx, client.ObjectKey{Name: "acme-ns." + zone.Name, Namespace: zone.Namespace}, &delegation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedRecords := []string{"ns1." + zone.Name + "."}
+		if delegation.Spec.Type != "NS" || !cmp.Equal(delegation.Spec.Records, expectedRecords) {
+			t.Errorf("unexpected delegation %s %v", delegation.Spec.Type, delegation.Spec.Records)
+		}
+
+		for _, key := range []string{"apex", "apps"} {
+			var cname pdnsv1.RRset
+			err = c.Get(ctx, client.ObjectKey{Name: "acme." + key + "." + zone.Name, Namespace: zone.Namespace}, &cname)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cname.Spec.Type != "CNAME" {
+				t.Errorf("expected CNAME, got %s", cname.Spec.Type)
+			}
+		}
+
+		err = c.Get(ctx, workloadKey, &dockyardsv1.Workload{})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected workload to wait for the challenge zone, got %v", err)
+		}
+	})
+
+	t.Run("test challenge zone records", func(t *testing.T) {
+		var challengeZone pdnsv1.Zone
+		err := c.Get(ctx, client.ObjectKey{Name: challengeZoneName, Namespace: zone.Namespace}, &challengeZone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		challengeZone.Status.SyncStatus = ptr.To("Succeeded")
+		challengeZone.Status.ObservedGeneration = ptr.To(int64(1))
+
+		err = c.Status().Update(ctx, &challengeZone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: challengeZoneName, Namespace: zone.Namespace}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var soa pdnsv1.RRset
+		err = c.Get(ctx, client.ObjectKey{Name: "soa." + challengeZoneName, Namespace: zone.Namespace}, &soa)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if soa.Spec.Type != "SOA" || soa.Spec.ZoneRef.Name != challengeZoneName {
+			t.Errorf("unexpected SOA %s in zone %s", soa.Spec.Type, soa.Spec.ZoneRef.Name)
+		}
+	})
+
+	t.Run("test tsig key and workload", func(t *testing.T) {
+		server.AddZone(challengeZoneName)
+
+		_, err := r.reconcileACMEChallenge(ctx, &zone, &cluster, &ips)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, found := server.TSIGKey(challengeZoneID)
+		if !found {
+			t.Fatal("expected challenge zone TSIG key to be created")
+		}
+
+		expected := []string{key.Name}
+		if !cmp.Equal(server.Metadata(challengeZoneID, pdnsapi.MetadataTSIGAllowDNSUpdate), expected) {
+			t.Error(cmp.Diff(expected, server.Metadata(challengeZoneID, pdnsapi.MetadataTSIGAllowDNSUpdate)))
+		}
+
+		if len(server.Metadata(challengeZoneID, pdnsapi.MetadataAllowDNSUpdateFrom)) == 0 {
+			t.Error("expected update networks to be set")
+		}
+
+		var workload dockyardsv1.Workload
+		err = c.Get(ctx, workloadKey, &workload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var input map[string]any
+		err = json.Unmarshal(workload.Spec.Input.Raw, &input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if input["nameserver"] != "[2001:db8::53]:53" {
+			t.Errorf("expected nameserver [2001:db8::53]:53, got %v", input["nameserver"])
+		}
+
+		if input["zone"] != challengeZoneName || input["tsigKeyName"] != key.Name {
+			t.Errorf("unexpected zone %v and key %v", input["zone"], input["tsigKeyName"])
+		}
+	})
+
+	t.Run("test delete challenge", func(t *testing.T) {
+		zone.Annotations[AnnotationACMEChallenges] = "false"
+
+		err := c.Update(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileACMEChallenge(ctx, &zone, &cluster, &ips)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, found := server.TSIGKey(challengeZoneID)
+		if found {
+			t.Error("expected challenge zone TSIG key to be deleted")
+		}
+
+		err = c.Get(ctx, workloadKey, &dockyardsv1.Workload{})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected workload to be deleted, got %v", err)
+		}
+
+		err = c.Get(ctx, client.ObjectKey{Name: challengeZoneName, Namespace: zone.Namespace}, &pdnsv1.Zone{})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected challenge zone to be deleted, got %v", err)
+		}
+
+		var rrsetList pdnsv1.RRsetList
+		err = c.List(ctx, &rrsetList, client.HasLabels{LabelACMEChallenge})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rrsetList.Items) != 0 {
+			t.Errorf("expected challenge RRsets to be deleted, got %d", len(rrsetList.Items))
+		}
+	})
+}
@@ -0,0 +1,437 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/prometheus/client_golang/prometheus"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/csaupgrade"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// fieldConflicts counts the fields other field managers own with a different value than dockyards-pdns applies.
+var fieldConflicts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "dockyards_pdns_field_conflicts_total",
+		Help: "Fields owned by another field manager that dockyards-pdns applies a different value to.",
+	},
+	[]string{"kind", "manager"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(fieldConflicts)
+}
+
+// FieldConflict is a field that another manager owns with a different value than dockyards-pdns applies.
+type FieldConflict struct {
+	Manager string
+	Field   string
+}
+
+// conflictManager matches the manager in the message of a field manager conflict cause.
+var conflictManager = regexp.MustCompile(`conflict with "([^"]*)"`)
+
+// fieldConflictsFromError returns the field manager conflicts of a failed apply request.
+func fieldConflictsFromError(err error) []FieldConflict {
+	var statusError *apierrors.StatusError
+	if !errors.As(err, &statusError) || !apierrors.IsConflict(err) || statusError.ErrStatus.Details == nil {
+		return nil
+	}
+
+	var conflicts []FieldConflict
+
+	for _, cause := range statusError.ErrStatus.Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+
+		conflict := FieldConflict{
+			Field: cause.Field,
+		}
+
+		match := conflictManager.FindStringSubmatch(cause.Message)
+		if match != nil {
+			conflict.Manager = match[1]
+		}
+
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts
+}
+
+// fieldOwnershipClient reports the field manager conflicts of apply requests and takes the fields over when the
+// config allows it.
+type fieldOwnershipClient struct {
+	client.Client
+	*dyconfig.ConfigManager
+	recorder record.EventRecorder
+}
+
+// NewFieldOwnershipClient wraps a client so that field manager conflicts of apply requests are counted and recorded
+// as Events on the applied object, see KeyForceFieldOwnership. Other writes are made as patchFieldManager.
+func NewFieldOwnershipClient(c client.Client, configManager *dyconfig.ConfigManager, recorder record.EventRecorder) client.Client {
+	return &fieldOwnershipClient{
+		Client:        client.WithFieldOwner(c, patchFieldManager),
+		ConfigManager: configManager,
+		recorder:      recorder,
+	}
+}
+
+// Apply applies the configuration and reports its conflicts, the conflicting fields are only applied with forced
+// ownership when KeyForceFieldOwnership is enabled.
+func (c *fieldOwnershipClient) Apply(ctx context.Context, obj runtime.ApplyConfiguration, opts ...client.ApplyOption) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	err := c.Client.Apply(ctx, obj, opts...)
+
+	conflicts := fieldConflictsFromError(err)
+	if len(conflicts) == 0 {
+		return err
+	}
+
+	var object metav1.PartialObjectMetadata
+
+	ac, ok := obj.(interface {
+		GetAPIVersion() *string
+		GetKind() *string
+		GetNamespace() *string
+		GetName() *string
+	})
+	if ok {
+		object.APIVersion = ptr.Deref(ac.GetAPIVersion(), "")
+		object.Kind = ptr.Deref(ac.GetKind(), "")
+		object.Namespace = ptr.Deref(ac.GetNamespace(), "")
+		object.Name = ptr.Deref(ac.GetName(), "")
+	}
+
+	force, parseErr := parseForceFieldOwnership(c.ConfigManager)
+	if parseErr != nil {
+		return errors.Join(err, parseErr)
+	}
+
+	for _, conflict := range conflicts {
+		fieldConflicts.WithLabelValues(object.Kind, conflict.Manager).Inc()
+
+		c.recorder.Eventf(&object, corev1.EventTypeWarning, FieldConflictReason, "field %s is owned by %s", conflict.Field, conflict.Manager)
+
+		if force {
+			logger.Info("Taking over conflicting field", "kind", object.Kind, "namespace", object.Namespace, "name", object.Name, "field", conflict.Field, "manager", conflict.Manager)
+		}
+	}
+
+	if !force {
+		return err
+	}
+
+	return c.Client.Apply(ctx, obj, append(opts, client.ForceOwnership)...)
+}
+
+// parseForceFieldOwnership reads whether conflicting fields are taken over from other managers.
+func parseForceFieldOwnership(configManager *dyconfig.ConfigManager) (bool, error) {
+	value := configManager.GetValueOrDefault(KeyForceFieldOwnership, "false")
+
+	force, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for config key `%s`: %w", KeyForceFieldOwnership, err)
+	}
+
+	return force, nil
+}
+
+// applyObject applies the configuration with server-side apply as the dockyards-pdns field manager and reads the
+// result into the object.
+//
+// Only the fields in the configuration are owned by dockyards-pdns, labels, annotations and spec fields set by other
+// managers are kept. Fields that another manager owns with a different value fail the apply with a conflict unless
+// the client takes them over, see NewFieldOwnershipClient. Fields written by earlier versions with client-side patches
+// are moved to the apply manager first, so fields dockyards-pdns stops setting are removed. Objects that skip
+// remediation are left unchanged.
+func applyObject(ctx context.Context, c client.Client, obj client.Object, ac runtime.ApplyConfiguration) (controllerutil.OperationResult, error) {
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if client.IgnoreNotFound(err) != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	created := apierrors.IsNotFound(err)
+
+	_, skip := obj.GetAnnotations()[dockyardsv1.AnnotationSkipRemediation]
+	if !created && skip {
+		return controllerutil.OperationResultNone, nil
+	}
+
+	if !created {
+		err := upgradeManagedFields(ctx, c, obj)
+		if err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+
+	current := versionless(obj)
+
+	err = c.Apply(ctx, ac, client.FieldOwner(fieldManager))
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	b, err := json.Marshal(ac)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	// The applied object replaces the metadata read earlier, fields the configuration does not know about are kept.
+	gvk := obj.GetObjectKind().GroupVersionKind()
+
+	obj.SetLabels(nil)
+	obj.SetAnnotations(nil)
+	obj.SetOwnerReferences(nil)
+	obj.SetFinalizers(nil)
+
+	err = json.Unmarshal(b, obj)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+
+	if created {
+		return controllerutil.OperationResultCreated, nil
+	}
+
+	if !equality.Semantic.DeepEqual(current, versionless(obj)) {
+		return controllerutil.OperationResultUpdated, nil
+	}
+
+	return controllerutil.OperationResultNone, nil
+}
+
+// upgradeManagedFields moves the fields owned by the client-side update manager of dockyards-pdns to its apply
+// manager.
+func upgradeManagedFields(ctx context.Context, c client.Client, obj client.Object) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(obj, sets.New(fieldManager), fieldManager)
+	if err != nil {
+		return err
+	}
+
+	if patch == nil {
+		return nil
+	}
+
+	return c.Patch(ctx, obj, client.RawPatch(types.JSONPatchType, patch))
+}
+
+// versionless returns a copy of the object without the fields the API server changes on every write.
+func versionless(obj client.Object) client.Object {
+	c := obj.DeepCopyObject().(client.Object)
+
+	c.SetResourceVersion("")
+	c.SetGeneration(0)
+	c.SetManagedFields(nil)
+
+	return c
+}
+
+// ownerReferenceApplyConfiguration returns the apply configuration of an owner reference.
+func ownerReferenceApplyConfiguration(ownerReference metav1.OwnerReference) *metav1ac.OwnerReferenceApplyConfiguration {
+	ac := metav1ac.OwnerReference().
+		WithAPIVersion(ownerReference.APIVersion).
+		WithKind(ownerReference.Kind).
+		WithName(ownerReference.Name).
+		WithUID(ownerReference.UID)
+
+	if ownerReference.Controller != nil {
+		ac.WithController(*ownerReference.Controller)
+	}
+
+	if ownerReference.BlockOwnerDeletion != nil {
+		ac.WithBlockOwnerDeletion(*ownerReference.BlockOwnerDeletion)
+	}
+
+	return ac
+}
+
+// zoneApplyConfiguration is the apply configuration of a powerdns-operator Zone with the fields dockyards-pdns sets.
+type zoneApplyConfiguration struct {
+	metav1ac.TypeMetaApplyConfiguration    `json:",inline"`
+	*metav1ac.ObjectMetaApplyConfiguration `json:"metadata,omitempty"`
+	Spec                                   *zoneSpecApplyConfiguration `json:"spec,omitempty"`
+}
+
+type zoneSpecApplyConfiguration struct {
+	Kind        *string  `json:"kind,omitempty"`
+	Nameservers []string `json:"nameservers,omitempty"`
+}
+
+// zoneApply returns the apply configuration of a zone with the spec.
+func zoneApply(name, namespace string, spec pdnsv1.ZoneSpec) *zoneApplyConfiguration {
+	ac := zoneApplyConfiguration{
+		ObjectMetaApplyConfiguration: metav1ac.ObjectMeta().WithName(name).WithNamespace(namespace),
+		Spec: &zoneSpecApplyConfiguration{
+			Kind:        &spec.Kind,
+			Nameservers: spec.Nameservers,
+		},
+	}
+
+	ac.WithAPIVersion(pdnsv1.GroupVersion.String())
+	ac.WithKind("Zone") // PDNS library does not offer ZoneKind
+
+	return &ac
+}
+
+// clusterZoneApply returns the apply configuration of a cluster zone with the spec, ClusterZone shares the Zone fields.
+func clusterZoneApply(name string, spec pdnsv1.ZoneSpec) *zoneApplyConfiguration {
+	ac := zoneApply(name, "", spec)
+
+	ac.ObjectMetaApplyConfiguration = metav1ac.ObjectMeta().WithName(name)
+	ac.WithKind("ClusterZone")
+
+	return ac
+}
+
+func (ac *zoneApplyConfiguration) IsApplyConfiguration() {}
+
+// rrsetApplyConfiguration is the apply configuration of a powerdns-operator RRset with the fields dockyards-pdns sets.
+type rrsetApplyConfiguration struct {
+	metav1ac.TypeMetaApplyConfiguration    `json:",inline"`
+	*metav1ac.ObjectMetaApplyConfiguration `json:"metadata,omitempty"`
+	Spec                                   *rrsetSpecApplyConfiguration `json:"spec,omitempty"`
+}
+
+type rrsetSpecApplyConfiguration struct {
+	Type    *string                    `json:"type,omitempty"`
+	Name    *string                    `json:"name,omitempty"`
+	TTL     *uint32                    `json:"ttl,omitempty"`
+	Records []string                   `json:"records,omitempty"`
+	Comment *string                    `json:"comment,omitempty"`
+	ZoneRef *zoneRefApplyConfiguration `json:"zoneRef,omitempty"`
+}
+
+type zoneRefApplyConfiguration struct {
+	Name *string `json:"name,omitempty"`
+	Kind *string `json:"kind,omitempty"`
+}
+
+// rrsetApply returns the apply configuration of an RRset with the spec.
+func rrsetApply(name, namespace string, spec pdnsv1.RRsetSpec) *rrsetApplyConfiguration {
+	ac := rrsetApplyConfiguration{
+		ObjectMetaApplyConfiguration: metav1ac.ObjectMeta().WithName(name).WithNamespace(namespace),
+		Spec: &rrsetSpecApplyConfiguration{
+			Type:    &spec.Type,
+			Name:    &spec.Name,
+			TTL:     &spec.TTL,
+			Records: spec.Records,
+			Comment: spec.Comment,
+			ZoneRef: &zoneRefApplyConfiguration{
+				Name: &spec.ZoneRef.Name,
+				Kind: &spec.ZoneRef.Kind,
+			},
+		},
+	}
+
+	ac.WithAPIVersion(pdnsv1.GroupVersion.String())
+	ac.WithKind("RRset")
+
+	return &ac
+}
+
+// clusterRRsetApply returns the apply configuration of a cluster RRset with the spec, ClusterRRset shares the RRset fields.
+func clusterRRsetApply(name string, spec pdnsv1.RRsetSpec) *rrsetApplyConfiguration {
+	ac := rrsetApply(name, "", spec)
+
+	ac.ObjectMetaApplyConfiguration = metav1ac.ObjectMeta().WithName(name)
+	ac.WithKind("ClusterRRset")
+
+	return ac
+}
+
+func (ac *rrsetApplyConfiguration) IsApplyConfiguration() {}
+
+// workloadApplyConfiguration is the apply configuration of a Dockyards Workload with the fields dockyards-pdns sets.
+type workloadApplyConfiguration struct {
+	metav1ac.TypeMetaApplyConfiguration    `json:",inline"`
+	*metav1ac.ObjectMetaApplyConfiguration `json:"metadata,omitempty"`
+	Spec                                   *workloadSpecApplyConfiguration `json:"spec,omitempty"`
+}
+
+type workloadSpecApplyConfiguration struct {
+	ClusterComponent    *bool                                            `json:"clusterComponent,omitempty"`
+	TargetNamespace     *string                                          `json:"targetNamespace,omitempty"`
+	Input               *apiextensionsv1.JSON                            `json:"input,omitempty"`
+	WorkloadTemplateRef *corev1ac.TypedObjectReferenceApplyConfiguration `json:"workloadTemplateRef,omitempty"`
+	Provenience         *string                                          `json:"provenience,omitempty"`
+}
+
+// clusterComponentWorkloadApply returns the apply configuration of a workload that Dockyards installs as a cluster
+// component of the cluster, rendered from the workload template with the input.
+func clusterComponentWorkloadApply(name string, cluster *dockyardsv1.Cluster, targetNamespace, templateName, templateNamespace string, input []byte) *workloadApplyConfiguration {
+	ac := workloadApplyConfiguration{
+		ObjectMetaApplyConfiguration: metav1ac.ObjectMeta().
+			WithName(name).
+			WithNamespace(cluster.Namespace).
+			WithLabels(map[string]string{
+				dockyardsv1.LabelClusterName: cluster.Name,
+			}).
+			WithOwnerReferences(
+				metav1ac.OwnerReference().
+					WithAPIVersion(dockyardsv1.GroupVersion.String()).
+					WithKind(dockyardsv1.ClusterKind).
+					WithName(cluster.Name).
+					WithUID(cluster.UID).
+					WithController(true).
+					WithBlockOwnerDeletion(true),
+			),
+		Spec: &workloadSpecApplyConfiguration{
+			ClusterComponent: ptr.To(true),
+			TargetNamespace:  &targetNamespace,
+			Input:            &apiextensionsv1.JSON{Raw: input},
+			WorkloadTemplateRef: corev1ac.TypedObjectReference().
+				WithKind(dockyardsv1.WorkloadTemplateKind).
+				WithName(templateName).
+				WithNamespace(templateNamespace),
+			Provenience: ptr.To(dockyardsv1.ProvenienceDockyards),
+		},
+	}
+
+	ac.WithAPIVersion(dockyardsv1.GroupVersion.String())
+	ac.WithKind(dockyardsv1.WorkloadKind)
+
+	return &ac
+}
+
+func (ac *workloadApplyConfiguration) IsApplyConfiguration() {}
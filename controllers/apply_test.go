@@ -0,0 +1,369 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestApplyObject(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = pdnsv1.AddToScheme(scheme)
+
+	spec := pdnsv1.ZoneSpec{
+		Kind:        "Native",
+		Nameservers: []string{"ns1.test.com"},
+	}
+
+	t.Run("test create", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		ac := zoneApply("test.com", "testing", spec)
+		ac.WithLabels(map[string]string{"dockyards.io/cluster": "test"})
+
+		zone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: "test.com", Namespace: "testing"}}
+
+		operationResult, err := applyObject(ctx, c, &zone, ac)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if operationResult != controllerutil.OperationResultCreated {
+			t.Errorf("expected created, got %s", operationResult)
+		}
+
+		if !cmp.Equal(zone.Spec, spec) {
+			t.Errorf("diff: %s", cmp.Diff(spec, zone.Spec))
+		}
+
+		operationResult, err = applyObject(ctx, c, &zone, ac)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if operationResult != controllerutil.OperationResultNone {
+			t.Errorf("expected unchanged, got %s", operationResult)
+		}
+	})
+
+	t.Run("test fields of other managers", func(t *testing.T) {
+		existing := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test.com",
+				Namespace:   "testing",
+				Labels:      map[string]string{"team": "platform"},
+				Annotations: map[string]string{"example.com/note": "keep"},
+			},
+			Spec: pdnsv1.ZoneSpec{
+				Kind:        "Native",
+				Nameservers: []string{"ns1.test.com"},
+				Catalog:     ptr.To("catalog.test.com."),
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&existing).Build()
+
+		ac := zoneApply("test.com", "testing", spec)
+		ac.WithAnnotations(map[string]string{AnnotationManagementDomain: "test.com"})
+
+		zone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: "test.com", Namespace: "testing"}}
+
+		_, err := applyObject(ctx, c, &zone, ac)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// An annotation that is no longer applied is removed, fields of other managers are kept.
+		ac = zoneApply("test.com", "testing", spec)
+
+		_, err = applyObject(ctx, c, &zone, ac)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actual pdnsv1.Zone
+		err = c.Get(ctx, client.ObjectKeyFromObject(&existing), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]string{"example.com/note": "keep"}
+		if !cmp.Equal(actual.Annotations, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual.Annotations))
+		}
+
+		if actual.Labels["team"] != "platform" {
+			t.Errorf("expected label of other manager to be kept, got %v", actual.Labels)
+		}
+
+		if actual.Spec.Catalog == nil || *actual.Spec.Catalog != "catalog.test.com." {
+			t.Errorf("expected catalog of other manager to be kept, got %v", actual.Spec.Catalog)
+		}
+	})
+
+	t.Run("test conflict", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		other := zoneApply("test.com", "testing", pdnsv1.ZoneSpec{
+			Kind:        "Master",
+			Nameservers: []string{"ns1.test.com"},
+		})
+
+		err := c.Apply(ctx, other, client.FieldOwner("other"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.Apply(ctx, zoneApply("test.com", "testing", spec), client.FieldOwner(fieldManager))
+
+		conflicts := fieldConflictsFromError(err)
+
+		expected := []FieldConflict{
+			{
+				Manager: "other",
+				Field:   ".spec.kind",
+			},
+		}
+
+		if !cmp.Equal(conflicts, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, conflicts))
+		}
+
+		zone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: "test.com", Namespace: "testing"}}
+
+		_, err = applyObject(ctx, c, &zone, zoneApply("test.com", "testing", spec))
+		if len(fieldConflictsFromError(err)) == 0 {
+			t.Fatalf("expected conflict error, got %v", err)
+		}
+	})
+
+	t.Run("test field ownership client", func(t *testing.T) {
+		tt := []struct {
+			name     string
+			config   map[string]string
+			expected string
+		}{
+			{
+				name:     "test report",
+				expected: "Master",
+			},
+			{
+				name: "test force",
+				config: map[string]string{
+					string(KeyForceFieldOwnership): "true",
+				},
+				expected: "Native",
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				other := zoneApply("test.com", "testing", pdnsv1.ZoneSpec{
+					Kind:        "Master",
+					Nameservers: []string{"ns1.test.com"},
+				})
+
+				fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+				err := fakeClient.Apply(ctx, other, client.FieldOwner("other"))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				recorder := record.NewFakeRecorder(10)
+
+				c := NewFieldOwnershipClient(fakeClient, dyconfig.NewFakeConfigManager(tc.config), recorder)
+
+				zone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: "test.com", Namespace: "testing"}}
+
+				_, err = applyObject(ctx, c, &zone, zoneApply("test.com", "testing", spec))
+				if tc.expected == "Native" && err != nil {
+					t.Fatal(err)
+				}
+				if tc.expected == "Master" && err == nil {
+					t.Fatal("expected conflict error")
+				}
+
+				var actual pdnsv1.Zone
+				err = fakeClient.Get(ctx, client.ObjectKeyFromObject(&zone), &actual)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if actual.Spec.Kind != tc.expected {
+					t.Errorf("expected kind %s, got %s", tc.expected, actual.Spec.Kind)
+				}
+
+				expectedEvent := "Warning FieldConflict field .spec.kind is owned by other"
+
+				select {
+				case event := <-recorder.Events:
+					if event != expectedEvent {
+						t.Errorf("expected event %q, got %q", expectedEvent, event)
+					}
+				default:
+					t.Error("expected conflict event")
+				}
+			})
+		}
+	})
+
+	t.Run("test patch manager", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithReturnManagedFields().Build()
+
+		c := NewFieldOwnershipClient(fakeClient, dyconfig.NewFakeConfigManager(nil), record.NewFakeRecorder(10))
+
+		zone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: "test.com", Namespace: "testing"}}
+
+		_, err := applyObject(ctx, c, &zone, zoneApply("test.com", "testing", spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		patch := client.MergeFrom(zone.DeepCopy())
+
+		controllerutil.AddFinalizer(&zone, FinalizerBackendZone)
+
+		err = c.Patch(ctx, &zone, patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = applyObject(ctx, c, &zone, zoneApply("test.com", "testing", spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actual pdnsv1.Zone
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !controllerutil.ContainsFinalizer(&actual, FinalizerBackendZone) {
+			t.Error("expected patched finalizer to be kept")
+		}
+
+		var managers []string
+		for _, managedField := range actual.ManagedFields {
+			managers = append(managers, managedField.Manager+" "+string(managedField.Operation))
+		}
+
+		expected := []string{
+			fieldManager + " Apply",
+			patchFieldManager + " Update",
+		}
+
+		if !cmp.Equal(managers, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, managers))
+		}
+	})
+
+	t.Run("test client-side upgrade", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithReturnManagedFields().Build()
+
+		existing := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test.com",
+				Namespace: "testing",
+				Annotations: map[string]string{
+					AnnotationPaused: "true",
+				},
+			},
+			Spec: spec,
+		}
+
+		err := c.Create(ctx, &existing, client.FieldOwner(fieldManager))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: "test.com", Namespace: "testing"}}
+
+		_, err = applyObject(ctx, c, &zone, zoneApply("test.com", "testing", spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actual pdnsv1.Zone
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(actual.Annotations) != 0 {
+			t.Errorf("expected annotation of client-side update to be removed, got %v", actual.Annotations)
+		}
+
+		for _, managedField := range actual.ManagedFields {
+			if managedField.Operation != metav1.ManagedFieldsOperationApply {
+				t.Errorf("expected only apply managers, got %s %s", managedField.Manager, managedField.Operation)
+			}
+		}
+	})
+
+	t.Run("test skip remediation", func(t *testing.T) {
+		existing := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test.com",
+				Namespace: "testing",
+				Annotations: map[string]string{
+					dockyardsv1.AnnotationSkipRemediation: "true",
+				},
+			},
+			Spec: pdnsv1.ZoneSpec{
+				Kind:        "Master",
+				Nameservers: []string{"ns1.test.com"},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&existing).Build()
+
+		zone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: "test.com", Namespace: "testing"}}
+
+		operationResult, err := applyObject(ctx, c, &zone, zoneApply("test.com", "testing", spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if operationResult != controllerutil.OperationResultNone {
+			t.Errorf("expected unchanged, got %s", operationResult)
+		}
+
+		if zone.Spec.Kind != "Master" {
+			t.Errorf("expected zone to be left unchanged, got %s", zone.Spec.Kind)
+		}
+	})
+}
@@ -0,0 +1,791 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// BackendRecord is an RRset that a zone keeps in a backend, possibly in another zone such as its parent.
+type BackendRecord struct {
+	// ID identifies the RRset, the CRD backend uses it as the name of the RRset resource.
+	ID     string
+	Labels map[string]string
+	Spec   pdnsv1.RRsetSpec
+}
+
+// BackendZoneStatus is the state of a zone in a backend.
+type BackendZoneStatus struct {
+	Ready   bool
+	Serial  *uint32
+	Message string
+}
+
+// Backend stores the zones and records that the reconcilers derive from Zone resources.
+//
+// Records are owned by a zone. A backend never changes or deletes records it does not own, nor records that are
+// annotated to skip remediation.
+type Backend interface {
+	// EnsureZone creates or updates the zone.
+	EnsureZone(ctx context.Context, zone *pdnsv1.Zone) error
+	// ZoneStatus reports whether the zone is served.
+	ZoneStatus(ctx context.Context, zone *pdnsv1.Zone) (*BackendZoneStatus, error)
+	// DeleteZone deletes the zone and its records.
+	DeleteZone(ctx context.Context, zone *pdnsv1.Zone) error
+	// EnsureRecord creates or updates a record owned by the zone.
+	EnsureRecord(ctx context.Context, owner *pdnsv1.Zone, record BackendRecord) (controllerutil.OperationResult, error)
+	// DeleteRecord deletes a record owned by the zone and reports whether it was deleted.
+	DeleteRecord(ctx context.Context, owner *pdnsv1.Zone, record BackendRecord) (bool, error)
+	// ListRecords returns the records in the zone that carry the label.
+	ListRecords(ctx context.Context, owner *pdnsv1.Zone, label string) ([]BackendRecord, error)
+}
+
+// parseBackend reads the backend kind from the config key.
+func parseBackend(configManager *dyconfig.ConfigManager) (string, error) {
+	backend := configManager.GetValueOrDefault(KeyBackend, backendCRD)
+
+	switch backend {
+	case backendCRD, backendAPI:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("invalid value for config key `%s`: unsupported backend %s", KeyBackend, backend)
+	}
+}
+
+// backend returns the configured backend for the records of the zone, Backend is used instead when set.
+//
+// The API backend talks to the PowerDNS backend the zone is placed on.
+func (r *ZoneReconciler) backend(ctx context.Context, zone *pdnsv1.Zone) (Backend, error) {
+	if r.Backend != nil {
+		return r.Backend, nil
+	}
+
+	kind, err := parseBackend(r.ConfigManager)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == backendCRD {
+		return &CRDBackend{Client: r.Client}, nil
+	}
+
+	ips, err := r.getPDNSIPs(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return nil, err
+	}
+
+	return &APIBackend{Client: pdnsClient}, nil
+}
+
+// parentBackend returns the backend for the records a zone keeps in its parent zone, such as delegation and DS records.
+//
+// The parent zone may be placed on another PowerDNS backend, parents that are not Zone resources are on the default
+// backend.
+func (r *ZoneReconciler) parentBackend(ctx context.Context, zone *pdnsv1.Zone, parentZoneRef pdnsv1.ZoneRef) (Backend, error) {
+	parent := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      parentZoneRef.Name,
+			Namespace: zone.Namespace,
+		},
+	}
+
+	if parentZoneRef.Kind == "Zone" && parentZoneRef.Name != "" {
+		err := r.Get(ctx, client.ObjectKeyFromObject(&parent), &parent)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+	}
+
+	return r.backend(ctx, &parent)
+}
+
+// backendOwnsZones reports whether a backend, rather than powerdns-operator, creates and deletes the zones in PowerDNS.
+func backendOwnsZones(backend Backend) bool {
+	_, isCRD := backend.(*CRDBackend)
+
+	return !isCRD
+}
+
+// deleteBackendZone deletes a zone from the backend before its finalizer is removed.
+func (r *ZoneReconciler) deleteBackendZone(ctx context.Context, backend Backend, zone *pdnsv1.Zone) error {
+	if !controllerutil.ContainsFinalizer(zone, FinalizerBackendZone) {
+		return nil
+	}
+
+	err := backend.DeleteZone(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Deleted zone from backend", "zone", zone.Name)
+
+	patch := client.MergeFrom(zone.DeepCopy())
+
+	controllerutil.RemoveFinalizer(zone, FinalizerBackendZone)
+
+	return r.Patch(ctx, zone, patch)
+}
+
+// CRDBackend keeps records as powerdns-operator RRset resources, powerdns-operator maintains the zones.
+type CRDBackend struct {
+	client.Client
+}
+
+var _ Backend = &CRDBackend{}
+
+// EnsureZone does nothing, the Zone resource is reconciled by powerdns-operator.
+func (b *CRDBackend) EnsureZone(_ context.Context, _ *pdnsv1.Zone) error {
+	return nil
+}
+
+// ZoneStatus reports the sync status powerdns-operator sets on the zone.
+func (b *CRDBackend) ZoneStatus(_ context.Context, zone *pdnsv1.Zone) (*BackendZoneStatus, error) {
+	status := BackendZoneStatus{
+		Ready:  zone.IsInExpectedStatus(1, "Succeeded"),
+		Serial: zone.Status.Serial,
+	}
+
+	if zone.Status.SyncStatus != nil {
+		status.Message = *zone.Status.SyncStatus
+	}
+
+	return &status, nil
+}
+
+// DeleteZone does nothing, powerdns-operator deletes the zone together with the Zone resource.
+func (b *CRDBackend) DeleteZone(_ context.Context, _ *pdnsv1.Zone) error {
+	return nil
+}
+
+// EnsureRecord applies an RRset resource controlled by the zone.
+func (b *CRDBackend) EnsureRecord(ctx context.Context, owner *pdnsv1.Zone, record BackendRecord) (controllerutil.OperationResult, error) {
+	rrset := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      record.ID,
+			Namespace: owner.Namespace,
+		},
+	}
+
+	ac := rrsetApply(rrset.Name, rrset.Namespace, record.Spec)
+
+	ac.WithLabels(record.Labels)
+	ac.WithLabels(owner.Labels)
+	ac.WithOwnerReferences(ownerReferenceApplyConfiguration(zoneOwnerReference(owner)))
+
+	return applyObject(ctx, b.Client, &rrset, ac)
+}
+
+// DeleteRecord deletes the RRset resource unless it skips remediation.
+func (b *CRDBackend) DeleteRecord(ctx context.Context, owner *pdnsv1.Zone, record BackendRecord) (bool, error) {
+	rrset := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      record.ID,
+			Namespace: owner.Namespace,
+		},
+	}
+
+	return deleteRemediable(ctx, b.Client, &rrset)
+}
+
+// ListRecords returns the RRset resources in the zone that carry the label, regardless of their controller.
+//
+// Resources that skip remediation are left out since they are never changed or deleted.
+func (b *CRDBackend) ListRecords(ctx context.Context, owner *pdnsv1.Zone, label string) ([]BackendRecord, error) {
+	var rrsetList pdnsv1.RRsetList
+	err := b.List(ctx, &rrsetList, client.InNamespace(owner.Namespace), client.HasLabels{label})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []BackendRecord
+
+	for _, rrset := range rrsetList.Items {
+		if rrset.Spec.ZoneRef.Name != owner.Name || metav1.HasAnnotation(rrset.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) {
+			continue
+		}
+
+		records = append(records, BackendRecord{
+			ID:     rrset.Name,
+			Labels: rrset.Labels,
+			Spec:   rrset.Spec,
+		})
+	}
+
+	return records, nil
+}
+
+// backendRecordOwner is stored as an RRset comment to mark the records the API backend owns.
+type backendRecordOwner struct {
+	ID        string            `json:"id"`
+	Namespace string            `json:"namespace"`
+	Zone      string            `json:"zone"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// recordOwner returns the owner comment of an RRset, or nil when it is not owned by the API backend.
+func recordOwner(rrset pdnsapi.RRset) *backendRecordOwner {
+	for _, comment := range rrset.Comments {
+		if comment.Account != backendRecordAccount {
+			continue
+		}
+
+		var owner backendRecordOwner
+
+		err := json.Unmarshal([]byte(comment.Content), &owner)
+		if err == nil {
+			return &owner
+		}
+	}
+
+	return nil
+}
+
+// APIBackend keeps zones and records in PowerDNS through its HTTP API, without powerdns-operator.
+//
+// The records it owns are marked with a comment naming the zone that owns them.
+type APIBackend struct {
+	Client *pdnsapi.Client
+
+	// zones caches the served zones for the lifetime of the backend, which the zone reconciler creates per step, so
+	// that a zone is fetched once for all of its records.
+	mu    sync.Mutex
+	zones map[string]*pdnsapi.Zone
+}
+
+var _ Backend = &APIBackend{}
+
+// EnsureZone creates the zone with its nameservers, or updates the kind and apex NS records of an existing zone.
+func (b *APIBackend) EnsureZone(ctx context.Context, zone *pdnsv1.Zone) error {
+	b.forgetZone(zone.Name)
+
+	served, err := b.Client.GetZone(ctx, zone.Name)
+	if pdnsapi.IsNotFound(err) {
+		_, err := b.Client.CreateZone(ctx, pdnsapi.Zone{
+			Name:        zone.Name,
+			Kind:        zone.Spec.Kind,
+			Nameservers: zone.Spec.Nameservers,
+			SOAEditAPI:  soaEditAPI,
+		})
+
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if zone.Spec.Kind != "" && served.Kind != zone.Spec.Kind {
+		err := b.Client.SetKind(ctx, zone.Name, zone.Spec.Kind)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(zone.Spec.Nameservers) == 0 {
+		return nil
+	}
+
+	expected := make([]string, len(zone.Spec.Nameservers))
+	for i, nameserver := range zone.Spec.Nameservers {
+		expected[i] = pdnsapi.CanonicalName(nameserver)
+	}
+
+	slices.Sort(expected)
+
+	nameservers := pdnsapi.RRset{
+		Name:       pdnsapi.CanonicalName(zone.Name),
+		Type:       "NS",
+		TTL:        zoneTTL,
+		ChangeType: pdnsapi.ChangeTypeReplace,
+	}
+
+	for _, rrset := range served.RRsets {
+		if rrset.Name != nameservers.Name || rrset.Type != nameservers.Type {
+			continue
+		}
+
+		if slices.Equal(recordContents(rrset), expected) {
+			return nil
+		}
+
+		nameservers.TTL = rrset.TTL
+	}
+
+	for _, nameserver := range expected {
+		nameservers.Records = append(nameservers.Records, pdnsapi.Record{Content: nameserver})
+	}
+
+	return b.patchRRsets(ctx, zone.Name, []pdnsapi.RRset{nameservers})
+}
+
+// ZoneStatus reports the zone as ready once PowerDNS serves it.
+func (b *APIBackend) ZoneStatus(ctx context.Context, zone *pdnsv1.Zone) (*BackendZoneStatus, error) {
+	served, err := b.Client.GetZone(ctx, zone.Name)
+	if pdnsapi.IsNotFound(err) {
+		return &BackendZoneStatus{Message: "zone not found in PowerDNS"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &BackendZoneStatus{Ready: true, Serial: &served.Serial}, nil
+}
+
+// DeleteZone deletes the zone from PowerDNS together with the records it owns in its ancestor zones.
+func (b *APIBackend) DeleteZone(ctx context.Context, zone *pdnsv1.Zone) error {
+	for _, ancestor := range ancestorZones(zone.Name) {
+		owned, err := b.ownedRRsets(ctx, ancestor, zone, "")
+		if err != nil {
+			return err
+		}
+
+		if len(owned) == 0 {
+			continue
+		}
+
+		changes := make([]pdnsapi.RRset, len(owned))
+		for i, rrset := range owned {
+			changes[i] = pdnsapi.RRset{
+				Name:       rrset.Name,
+				Type:       rrset.Type,
+				ChangeType: pdnsapi.ChangeTypeDelete,
+			}
+		}
+
+		err = b.patchRRsets(ctx, ancestor, changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.forgetZone(zone.Name)
+
+	return pdnsapi.IgnoreNotFound(b.Client.DeleteZone(ctx, zone.Name))
+}
+
+// servedZone returns the zone as served by PowerDNS, fetching it once per backend.
+func (b *APIBackend) servedZone(ctx context.Context, zoneName string) (*pdnsapi.Zone, error) {
+	id := pdnsapi.CanonicalName(zoneName)
+
+	b.mu.Lock()
+	served, found := b.zones[id]
+	b.mu.Unlock()
+
+	if found {
+		return served, nil
+	}
+
+	served, err := b.Client.GetZone(ctx, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.zones == nil {
+		b.zones = make(map[string]*pdnsapi.Zone)
+	}
+
+	b.zones[id] = served
+
+	return served, nil
+}
+
+// forgetZone drops a zone from the cache so that it is fetched again.
+func (b *APIBackend) forgetZone(zoneName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.zones, pdnsapi.CanonicalName(zoneName))
+}
+
+// patchRRsets sends the changes to PowerDNS and applies them to the cached zone.
+func (b *APIBackend) patchRRsets(ctx context.Context, zoneName string, changes []pdnsapi.RRset) error {
+	err := b.Client.PatchRRsets(ctx, zoneName, changes)
+	if err != nil {
+		b.forgetZone(zoneName)
+
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	served, found := b.zones[pdnsapi.CanonicalName(zoneName)]
+	if !found {
+		return nil
+	}
+
+	for _, change := range changes {
+		served.RRsets = slices.DeleteFunc(served.RRsets, func(rrset pdnsapi.RRset) bool {
+			return rrset.Name == change.Name && rrset.Type == change.Type
+		})
+
+		if change.ChangeType == pdnsapi.ChangeTypeReplace {
+			change.ChangeType = ""
+			served.RRsets = append(served.RRsets, change)
+		}
+	}
+
+	return nil
+}
+
+// ancestorZones returns the names that may hold the delegation of a zone, from its parent up to the top-level domain.
+func ancestorZones(zoneName string) []string {
+	labels := dns.SplitDomainName(zoneName)
+
+	ancestors := make([]string, 0, len(labels))
+	for i := 1; i < len(labels); i++ {
+		ancestors = append(ancestors, strings.Join(labels[i:], "."))
+	}
+
+	return ancestors
+}
+
+// ownedRRsets returns the RRsets of a zone that are owned by the owner zone, limited to a record id when set.
+func (b *APIBackend) ownedRRsets(ctx context.Context, zoneName string, owner *pdnsv1.Zone, id string) ([]pdnsapi.RRset, error) {
+	served, err := b.servedZone(ctx, zoneName)
+	if pdnsapi.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var owned []pdnsapi.RRset
+
+	for _, rrset := range served.RRsets {
+		ownerComment := recordOwner(rrset)
+		if !isOwnedBy(ownerComment, owner) || (id != "" && ownerComment.ID != id) {
+			continue
+		}
+
+		owned = append(owned, rrset)
+	}
+
+	return owned, nil
+}
+
+// isOwnedBy reports whether an owner comment names the zone.
+func isOwnedBy(ownerComment *backendRecordOwner, owner *pdnsv1.Zone) bool {
+	return ownerComment != nil && ownerComment.Namespace == owner.Namespace && ownerComment.Zone == owner.Name
+}
+
+// findRecord returns the zone and RRset of the record as served by PowerDNS, or a nil RRset when it does not exist.
+//
+// Records without a zone reference are looked up by their id in the ancestor zones of the owner, which hold the
+// delegation and DS records of a zone.
+func (b *APIBackend) findRecord(ctx context.Context, owner *pdnsv1.Zone, record BackendRecord) (string, *pdnsapi.RRset, error) {
+	if record.Spec.ZoneRef.Name == "" {
+		for _, ancestor := range ancestorZones(owner.Name) {
+			owned, err := b.ownedRRsets(ctx, ancestor, owner, record.ID)
+			if err != nil {
+				return "", nil, err
+			}
+
+			if len(owned) > 0 {
+				return ancestor, &owned[0], nil
+			}
+		}
+
+		return "", nil, nil
+	}
+
+	served, err := b.servedZone(ctx, record.Spec.ZoneRef.Name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	expected := desiredRRset(record.Spec.ZoneRef.Name, record.Spec)
+
+	for _, rrset := range served.RRsets {
+		if rrset.Name == expected.Name && rrset.Type == expected.Type {
+			return record.Spec.ZoneRef.Name, &rrset, nil
+		}
+	}
+
+	return record.Spec.ZoneRef.Name, nil, nil
+}
+
+// EnsureRecord replaces the RRset of the record unless another zone, or no zone, owns an existing RRset.
+func (b *APIBackend) EnsureRecord(ctx context.Context, owner *pdnsv1.Zone, record BackendRecord) (controllerutil.OperationResult, error) {
+	_, current, err := b.findRecord(ctx, owner, record)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	expected := desiredRRset(record.Spec.ZoneRef.Name, record.Spec)
+
+	operationResult := controllerutil.OperationResultCreated
+
+	if current != nil {
+		currentOwner := recordOwner(*current)
+		if !isOwnedBy(currentOwner, owner) {
+			return controllerutil.OperationResultNone, fmt.Errorf("rrset %s %s is not owned by zone %s", expected.Name, expected.Type, owner.Name)
+		}
+
+		if current.TTL == expected.TTL && slices.Equal(recordContents(*current), recordContents(expected)) && maps.Equal(currentOwner.Labels, record.Labels) && currentOwner.ID == record.ID {
+			return controllerutil.OperationResultNone, nil
+		}
+
+		operationResult = controllerutil.OperationResultUpdated
+	}
+
+	content, err := json.Marshal(backendRecordOwner{
+		ID:        record.ID,
+		Namespace: owner.Namespace,
+		Zone:      owner.Name,
+		Labels:    record.Labels,
+	})
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	expected.ChangeType = pdnsapi.ChangeTypeReplace
+	expected.Comments = []pdnsapi.Comment{
+		{
+			Account: backendRecordAccount,
+			Content: string(content),
+		},
+	}
+
+	err = b.patchRRsets(ctx, record.Spec.ZoneRef.Name, []pdnsapi.RRset{expected})
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	return operationResult, nil
+}
+
+// DeleteRecord deletes the RRset of the record when the zone owns it.
+func (b *APIBackend) DeleteRecord(ctx context.Context, owner *pdnsv1.Zone, record BackendRecord) (bool, error) {
+	zoneName, current, err := b.findRecord(ctx, owner, record)
+	if err != nil {
+		return false, pdnsapi.IgnoreNotFound(err)
+	}
+
+	if current == nil || !isOwnedBy(recordOwner(*current), owner) {
+		return false, nil
+	}
+
+	change := pdnsapi.RRset{
+		Name:       current.Name,
+		Type:       current.Type,
+		ChangeType: pdnsapi.ChangeTypeDelete,
+	}
+
+	err = b.patchRRsets(ctx, zoneName, []pdnsapi.RRset{change})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ListRecords returns the RRsets in the zone that it owns and that carry the label.
+func (b *APIBackend) ListRecords(ctx context.Context, owner *pdnsv1.Zone, label string) ([]BackendRecord, error) {
+	owned, err := b.ownedRRsets(ctx, owner.Name, owner, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var records []BackendRecord
+
+	for _, rrset := range owned {
+		ownerComment := recordOwner(rrset)
+
+		_, found := ownerComment.Labels[label]
+		if !found {
+			continue
+		}
+
+		record := BackendRecord{
+			ID:     ownerComment.ID,
+			Labels: ownerComment.Labels,
+			Spec: pdnsv1.RRsetSpec{
+				Type: rrset.Type,
+				TTL:  rrset.TTL,
+				Name: rrset.Name,
+				ZoneRef: pdnsv1.ZoneRef{
+					Name: owner.Name,
+					Kind: "Zone", // PDNS library does not offer ZoneKind
+				},
+			},
+		}
+
+		for _, content := range rrset.Records {
+			record.Spec.Records = append(record.Spec.Records, content.Content)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// MemoryBackend keeps zones and records in memory, it lets reconcilers run without PowerDNS or powerdns-operator.
+type MemoryBackend struct {
+	mu      sync.Mutex
+	zones   map[string]pdnsv1.ZoneSpec
+	records map[string]BackendRecord
+}
+
+var _ Backend = &MemoryBackend{}
+
+// NewMemoryBackend returns an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		zones:   make(map[string]pdnsv1.ZoneSpec),
+		records: make(map[string]BackendRecord),
+	}
+}
+
+func memoryKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// Zone returns the spec of a stored zone.
+func (b *MemoryBackend) Zone(namespace, name string) (pdnsv1.ZoneSpec, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	spec, found := b.zones[memoryKey(namespace, name)]
+
+	return spec, found
+}
+
+// Record returns a stored record by namespace and id.
+func (b *MemoryBackend) Record(namespace, id string) (BackendRecord, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	record, found := b.records[memoryKey(namespace, id)]
+
+	return record, found
+}
+
+// EnsureZone stores the spec of the zone.
+func (b *MemoryBackend) EnsureZone(_ context.Context, zone *pdnsv1.Zone) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.zones[memoryKey(zone.Namespace, zone.Name)] = *zone.Spec.DeepCopy()
+
+	return nil
+}
+
+// ZoneStatus reports stored zones as ready.
+func (b *MemoryBackend) ZoneStatus(_ context.Context, zone *pdnsv1.Zone) (*BackendZoneStatus, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, found := b.zones[memoryKey(zone.Namespace, zone.Name)]
+	if !found {
+		return &BackendZoneStatus{Message: "zone not found"}, nil
+	}
+
+	return &BackendZoneStatus{Ready: true}, nil
+}
+
+// DeleteZone removes the zone and the records in it.
+func (b *MemoryBackend) DeleteZone(_ context.Context, zone *pdnsv1.Zone) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.zones, memoryKey(zone.Namespace, zone.Name))
+
+	for key, record := range b.records {
+		if record.Spec.ZoneRef.Name == zone.Name {
+			delete(b.records, key)
+		}
+	}
+
+	return nil
+}
+
+// EnsureRecord stores the record.
+func (b *MemoryBackend) EnsureRecord(_ context.Context, owner *pdnsv1.Zone, record BackendRecord) (controllerutil.OperationResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := memoryKey(owner.Namespace, record.ID)
+
+	current, found := b.records[key]
+
+	b.records[key] = record
+
+	switch {
+	case !found:
+		return controllerutil.OperationResultCreated, nil
+	case maps.Equal(current.Labels, record.Labels) && slices.Equal(current.Spec.Records, record.Spec.Records) && current.Spec.Name == record.Spec.Name && current.Spec.Type == record.Spec.Type && current.Spec.TTL == record.Spec.TTL && current.Spec.ZoneRef == record.Spec.ZoneRef:
+		return controllerutil.OperationResultNone, nil
+	default:
+		return controllerutil.OperationResultUpdated, nil
+	}
+}
+
+// DeleteRecord removes the record.
+func (b *MemoryBackend) DeleteRecord(_ context.Context, owner *pdnsv1.Zone, record BackendRecord) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := memoryKey(owner.Namespace, record.ID)
+
+	_, found := b.records[key]
+
+	delete(b.records, key)
+
+	return found, nil
+}
+
+// ListRecords returns the stored records in the zone that carry the label, sorted by id.
+func (b *MemoryBackend) ListRecords(_ context.Context, owner *pdnsv1.Zone, label string) ([]BackendRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var records []BackendRecord
+
+	for _, key := range slices.Sorted(maps.Keys(b.records)) {
+		record := b.records[key]
+
+		_, found := record.Labels[label]
+		if found && record.Spec.ZoneRef.Name == owner.Name {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
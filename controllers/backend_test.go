@@ -0,0 +1,356 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"github.com/sudoswedenab/dockyards-pdns/test/fakepdns"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestAPIBackend(t *testing.T) {
+	ctx := context.Background()
+
+	server := fakepdns.NewServer("test-api-key")
+	t.Cleanup(server.Close)
+
+	server.AddZone("test.com")
+
+	backend := APIBackend{
+		Client: pdnsapi.NewClient(server.URL, "test-api-key"),
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+		},
+		Spec: pdnsv1.ZoneSpec{
+			Kind:        "Native",
+			Nameservers: []string{"ns1.org-test.test.com"},
+		},
+	}
+
+	t.Run("test ensure zone", func(t *testing.T) {
+		status, err := backend.ZoneStatus(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status.Ready {
+			t.Error("expected zone not to be ready before it is created")
+		}
+
+		err = backend.EnsureZone(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zone.Spec.Kind = "Master"
+		zone.Spec.Nameservers = []string{"ns1.org-test.test.com", "ns2.org-test.test.com"}
+
+		err = backend.EnsureZone(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		served, found := server.Zone("org-test.test.com.")
+		if !found {
+			t.Fatal("expected zone to be created")
+		}
+
+		if served.Kind != "Master" {
+			t.Errorf("expected kind Master, got %s", served.Kind)
+		}
+
+		if served.SOAEditAPI != soaEditAPI {
+			t.Errorf("expected soa_edit_api %s, got %s", soaEditAPI, served.SOAEditAPI)
+		}
+
+		expected := []string{"ns1.org-test.test.com.", "ns2.org-test.test.com."}
+		if !cmp.Equal(recordContents(served.RRsets[0]), expected) {
+			t.Error(cmp.Diff(expected, recordContents(served.RRsets[0])))
+		}
+
+		status, err = backend.ZoneStatus(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !status.Ready {
+			t.Error("expected zone to be ready")
+		}
+	})
+
+	record := BackendRecord{
+		ID: "caa.org-test.test.com",
+		Labels: map[string]string{
+			LabelRecordTemplate: "test",
+		},
+		Spec: pdnsv1.RRsetSpec{
+			Type:    "CAA",
+			TTL:     zoneTTL,
+			Name:    "org-test.test.com.",
+			Records: []string{`0 issue "letsencrypt.org"`},
+			ZoneRef: pdnsv1.ZoneRef{Name: "org-test.test.com", Kind: "Zone"},
+		},
+	}
+
+	t.Run("test ensure record", func(t *testing.T) {
+		operationResult, err := backend.EnsureRecord(ctx, &zone, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if operationResult != controllerutil.OperationResultCreated {
+			t.Errorf("expected created, got %s", operationResult)
+		}
+
+		operationResult, err = backend.EnsureRecord(ctx, &zone, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if operationResult != controllerutil.OperationResultNone {
+			t.Errorf("expected unchanged, got %s", operationResult)
+		}
+
+		records, err := backend.ListRecords(ctx, &zone, LabelRecordTemplate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []BackendRecord{
+			{
+				ID:     record.ID,
+				Labels: record.Labels,
+				Spec: pdnsv1.RRsetSpec{
+					Type:    "CAA",
+					TTL:     zoneTTL,
+					Name:    "org-test.test.com.",
+					Records: []string{`0 issue "letsencrypt.org"`},
+					ZoneRef: pdnsv1.ZoneRef{Name: "org-test.test.com", Kind: "Zone"},
+				},
+			},
+		}
+
+		if !cmp.Equal(records, expected) {
+			t.Error(cmp.Diff(expected, records))
+		}
+	})
+
+	t.Run("test unowned record", func(t *testing.T) {
+		other := zone.DeepCopy()
+		other.Name = "org-other.test.com"
+
+		_, err := backend.EnsureRecord(ctx, other, record)
+		if err == nil {
+			t.Error("expected error replacing record owned by another zone")
+		}
+
+		deleted, err := backend.DeleteRecord(ctx, other, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if deleted {
+			t.Error("expected record owned by another zone to be kept")
+		}
+	})
+
+	t.Run("test zone fetched once", func(t *testing.T) {
+		var zoneRequests int
+
+		httpClient := http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/zones/org-test.test.com.") {
+					zoneRequests++
+				}
+
+				return http.DefaultTransport.RoundTrip(req)
+			}),
+		}
+
+		backend := APIBackend{
+			Client: pdnsapi.NewClient(server.URL, "test-api-key", pdnsapi.WithHTTPClient(&httpClient)),
+		}
+
+		for _, name := range []string{"www", "mail", "ftp"} {
+			_, err := backend.EnsureRecord(ctx, &zone, BackendRecord{
+				ID: "tmpl." + name + ".org-test.test.com",
+				Labels: map[string]string{
+					LabelRecordTemplate: "test",
+				},
+				Spec: pdnsv1.RRsetSpec{
+					Type:    "A",
+					TTL:     zoneTTL,
+					Name:    name + ".org-test.test.com.",
+					Records: []string{"192.0.2.1"},
+					ZoneRef: pdnsv1.ZoneRef{Name: "org-test.test.com", Kind: "Zone"},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		records, err := backend.ListRecords(ctx, &zone, LabelRecordTemplate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 4 {
+			t.Errorf("expected 4 records, got %d", len(records))
+		}
+
+		if zoneRequests != 1 {
+			t.Errorf("expected zone to be fetched once, got %d", zoneRequests)
+		}
+	})
+
+	t.Run("test delete zone", func(t *testing.T) {
+		delegation := BackendRecord{
+			ID: delegationRRsetName(&zone),
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "NS",
+				TTL:     zoneTTL,
+				Name:    "org-test.test.com.",
+				Records: []string{"ns1.org-test.test.com."},
+				ZoneRef: pdnsv1.ZoneRef{Name: "test.com", Kind: "Zone"},
+			},
+		}
+
+		_, err := backend.EnsureRecord(ctx, &zone, delegation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = backend.DeleteZone(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, found := server.Zone("org-test.test.com.")
+		if found {
+			t.Error("expected zone to be deleted")
+		}
+
+		parent, _ := server.Zone("test.com.")
+		if len(parent.RRsets) != 0 {
+			t.Errorf("expected delegation to be deleted, got %v", parent.RRsets)
+		}
+	})
+}
+
+func TestMemoryBackend(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testing",
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: cluster.Name,
+			},
+		},
+	}
+
+	backend := NewMemoryBackend()
+
+	r := ZoneReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(&cluster, &zone).WithStatusSubresource(&cluster).Build(),
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyCAAIssuers): "letsencrypt.org",
+		}),
+		Backend: backend,
+	}
+
+	_, err := r.reconcileRRsets(ctx, &zone, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.reconcileCAAPolicy(ctx, &zone, &cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns1, found := backend.Record("testing", "ns1.org-test.test.com")
+	if !found {
+		t.Fatal("expected ns1 record")
+	}
+
+	if !cmp.Equal(ns1.Spec.Records, []string{"192.0.2.1"}) {
+		t.Errorf("unexpected ns1 records %v", ns1.Spec.Records)
+	}
+
+	_, found = backend.Record("testing", "soa.org-test.test.com")
+	if !found {
+		t.Error("expected soa record")
+	}
+
+	caa, found := backend.Record("testing", "caa.org-test.test.com")
+	if !found {
+		t.Fatal("expected caa record")
+	}
+
+	expected := []string{`0 issue "letsencrypt.org"`}
+	if !cmp.Equal(caa.Spec.Records, expected) {
+		t.Error(cmp.Diff(expected, caa.Spec.Records))
+	}
+
+	r.ConfigManager = dyconfig.NewFakeConfigManager(nil)
+
+	_, err = r.reconcileCAAPolicy(ctx, &zone, &cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, found = backend.Record("testing", "caa.org-test.test.com")
+	if found {
+		t.Error("expected caa record to be deleted")
+	}
+}
+
+// roundTripperFunc lets a function count or change the requests of an HTTP client.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// caaIssuerNone is the issuer value that forbids any certificate issuance.
+const caaIssuerNone = "none"
+
+// CAAPolicy describes the certification authorities allowed to issue for a zone.
+type CAAPolicy struct {
+	// Issuers is the list of issuer domains, optionally followed by CAA parameters (e.g. "letsencrypt.org; validationmethods=dns-01").
+	Issuers []string
+	// Iodef is the optional URL that CAs report policy violations to.
+	Iodef string
+}
+
+// parseCAAPolicy builds a policy from the comma-separated issuers and the iodef values.
+func parseCAAPolicy(issuers, iodef string) *CAAPolicy {
+	policy := CAAPolicy{
+		Iodef: strings.TrimSpace(iodef),
+	}
+
+	for _, issuer := range strings.Split(issuers, ",") {
+		issuer = strings.TrimSpace(issuer)
+		if issuer != "" {
+			policy.Issuers = append(policy.Issuers, issuer)
+		}
+	}
+
+	return &policy
+}
+
+// IsEmpty reports whether the policy declares no issuers.
+func (p *CAAPolicy) IsEmpty() bool {
+	return len(p.Issuers) == 0
+}
+
+// Validate checks the issuer domains, parameters and iodef URL of the policy.
+func (p *CAAPolicy) Validate() error {
+	for _, issuer := range p.Issuers {
+		if issuer == caaIssuerNone {
+			if len(p.Issuers) > 1 {
+				return fmt.Errorf("issuer %s cannot be combined with other issuers", caaIssuerNone)
+			}
+
+			continue
+		}
+
+		domain, parameters, _ := strings.Cut(issuer, ";")
+
+		errs := validation.IsDNS1123Subdomain(strings.TrimSpace(domain))
+		if len(errs) > 0 {
+			return fmt.Errorf("invalid issuer %s: %s", issuer, strings.Join(errs, ", "))
+		}
+
+		if strings.Contains(issuer, "\"") {
+			return fmt.Errorf("invalid issuer %s: must not contain quotes", issuer)
+		}
+
+		for _, parameter := range strings.Split(parameters, ";") {
+			parameter = strings.TrimSpace(parameter)
+			if parameter == "" {
+				continue
+			}
+
+			key, value, found := strings.Cut(parameter, "=")
+			if !found || key == "" || value == "" {
+				return fmt.Errorf("invalid issuer parameter %s", parameter)
+			}
+		}
+	}
+
+	if p.Iodef != "" {
+		u, err := url.Parse(p.Iodef)
+		if err != nil {
+			return fmt.Errorf("invalid iodef %s: %w", p.Iodef, err)
+		}
+
+		switch u.Scheme {
+		case "mailto":
+			if u.Opaque == "" {
+				return fmt.Errorf("invalid iodef %s: missing address", p.Iodef)
+			}
+		case "http", "https":
+			if u.Host == "" {
+				return fmt.Errorf("invalid iodef %s: missing host", p.Iodef)
+			}
+		default:
+			return fmt.Errorf("invalid iodef %s: unsupported scheme %s", p.Iodef, u.Scheme)
+		}
+	}
+
+	return nil
+}
+
+// Records renders the policy into CAA record contents in presentation format.
+func (p *CAAPolicy) Records() []string {
+	records := make([]string, 0, len(p.Issuers)+1)
+
+	for _, issuer := range p.Issuers {
+		value := issuer
+		if issuer == caaIssuerNone {
+			value = ";"
+		}
+
+		records = append(records, "0 issue "+strconv.Quote(value))
+	}
+
+	if p.Iodef != "" {
+		records = append(records, "0 iodef "+strconv.Quote(p.Iodef))
+	}
+
+	return records
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCAAPolicy(t *testing.T) {
+	tt := []struct {
+		name     string
+		issuers  string
+		iodef    string
+		expected []string
+		invalid  bool
+	}{
+		{
+			name:    "test issuers and iodef",
+			issuers: "letsencrypt.org, sectigo.com",
+			iodef:   "mailto:security@example.com",
+			expected: []string{
+				"0 issue \"letsencrypt.org\"",
+				"0 issue \"sectigo.com\"",
+				"0 iodef \"mailto:security@example.com\"",
+			},
+		},
+		{
+			name:    "test issuer parameters",
+			issuers: "letsencrypt.org; validationmethods=dns-01",
+			expected: []string{
+				"0 issue \"letsencrypt.org; validationmethods=dns-01\"",
+			},
+		},
+		{
+			name:    "test none",
+			issuers: "none",
+			expected: []string{
+				"0 issue \";\"",
+			},
+		},
+		{
+			name:    "test none combined",
+			issuers: "none,letsencrypt.org",
+			invalid: true,
+		},
+		{
+			name:    "test invalid issuer",
+			issuers: "Lets Encrypt",
+			invalid: true,
+		},
+		{
+			name:    "test invalid parameter",
+			issuers: "letsencrypt.org; accounturi",
+			invalid: true,
+		},
+		{
+			name:    "test invalid iodef scheme",
+			issuers: "letsencrypt.org",
+			iodef:   "ftp://example.com",
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := parseCAAPolicy(tc.issuers, tc.iodef)
+
+			err := policy.Validate()
+			if tc.invalid {
+				if err == nil {
+					t.Error("expected error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := policy.Records()
+			if !cmp.Equal(actual, tc.expected) {
+				t.Error(cmp.Diff(tc.expected, actual))
+			}
+		})
+	}
+}
+
+func TestReconcileCAAPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testing",
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+		},
+	}
+
+	backend := NewMemoryBackend()
+
+	r := ZoneReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(&cluster).WithStatusSubresource(&cluster).Build(),
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyCAAIssuers): "letsencrypt.org",
+		}),
+		Backend: backend,
+	}
+
+	tt := []struct {
+		name        string
+		annotations map[string]string
+		expected    []string
+		condition   metav1.ConditionStatus
+	}{
+		{
+			name:      "test config",
+			expected:  []string{`0 issue "letsencrypt.org"`},
+			condition: metav1.ConditionTrue,
+		},
+		{
+			name: "test invalid annotation",
+			annotations: map[string]string{
+				AnnotationCAAIssuers: "letsencrypt.org; validationmethods",
+			},
+			condition: metav1.ConditionFalse,
+		},
+		{
+			name: "test empty annotation",
+			annotations: map[string]string{
+				AnnotationCAAIssuers: "",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			zone.Annotations = tc.annotations
+
+			_, err := r.reconcileCAAPolicy(ctx, &zone, &cluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			caa, found := backend.Record("testing", "caa."+zone.Name)
+			if found != (tc.expected != nil) {
+				t.Fatalf("expected caa record %t, got %t", tc.expected != nil, found)
+			}
+
+			if found && !cmp.Equal(caa.Spec.Records, tc.expected) {
+				t.Error(cmp.Diff(tc.expected, caa.Spec.Records))
+			}
+
+			condition := meta.FindStatusCondition(cluster.Status.Conditions, CAAPolicyReadyCondition)
+			if tc.condition == "" {
+				if condition != nil {
+					t.Errorf("expected no condition, got %v", condition)
+				}
+
+				return
+			}
+
+			if condition == nil || condition.Status != tc.condition {
+				t.Errorf("expected condition %s, got %v", tc.condition, condition)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CacheLayout restricts which Secrets, Services and ConfigMaps the manager caches.
+//
+// Only the PowerDNS secrets and the services in the PowerDNS namespaces are cached, along with the secrets labelled
+// with LabelSecret in other namespaces. ConfigMaps are cached in the dockyards namespace and where they are labelled
+// with LabelConfigMap. The Dockyards and PowerDNS types stay cached cluster-wide.
+type CacheLayout struct {
+	// ConfigNamespace is the dockyards namespace holding the config, status and dry-run ConfigMaps, ConfigMaps are
+	// cached cluster-wide when empty.
+	ConfigNamespace string
+
+	// PDNSName is the name of the PowerDNS secret, every secret in the PowerDNS namespace is cached when empty.
+	PDNSName string
+
+	// PDNSNamespace is the namespace of the PowerDNS secret and services.
+	PDNSNamespace string
+
+	// Backends are further PowerDNS backends whose secrets and services are cached, nothing is restricted when there
+	// are none and PDNSNamespace is empty.
+	Backends []PDNSBackend
+
+	// ServiceSelector restricts the cached services in the PowerDNS namespaces when set.
+	ServiceSelector labels.Selector
+}
+
+// Restricted reports whether the layout restricts the cached Secrets and Services.
+func (l *CacheLayout) Restricted() bool {
+	return l.PDNSNamespace != "" || len(l.Backends) != 0
+}
+
+// Options returns the cache options of the manager.
+func (l *CacheLayout) Options() cache.Options {
+	byObject := make(map[client.Object]cache.ByObject)
+
+	if l.Restricted() {
+		secrets, services := l.secretsAndServices()
+
+		byObject[&corev1.Secret{}] = cache.ByObject{
+			Namespaces: secrets,
+		}
+		byObject[&corev1.Service{}] = cache.ByObject{
+			Namespaces: services,
+		}
+	}
+
+	if l.ConfigNamespace != "" {
+		labelled, _ := labels.NewRequirement(LabelConfigMap, selection.Exists, nil)
+
+		byObject[&corev1.ConfigMap{}] = cache.ByObject{
+			Namespaces: map[string]cache.Config{
+				cache.AllNamespaces: {
+					LabelSelector: labels.NewSelector().Add(*labelled),
+				},
+				l.ConfigNamespace: {},
+			},
+		}
+	}
+
+	if len(byObject) == 0 {
+		return cache.Options{}
+	}
+
+	return cache.Options{
+		ByObject: byObject,
+	}
+}
+
+// secretsAndServices returns the namespaces the Secrets and Services are cached in.
+func (l *CacheLayout) secretsAndServices() (map[string]cache.Config, map[string]cache.Config) {
+	secretNames := make(map[string][]string)
+	if l.PDNSNamespace != "" {
+		secretNames[l.PDNSNamespace] = append(secretNames[l.PDNSNamespace], l.PDNSName)
+	}
+
+	for _, backend := range l.Backends {
+		secretNames[backend.PDNSNamespace] = append(secretNames[backend.PDNSNamespace], backend.PDNSName)
+	}
+
+	labelled, _ := labels.NewRequirement(LabelSecret, selection.Exists, nil)
+
+	secrets := map[string]cache.Config{
+		cache.AllNamespaces: {
+			LabelSelector: labels.NewSelector().Add(*labelled),
+		},
+	}
+
+	services := make(map[string]cache.Config)
+
+	for namespace, names := range secretNames {
+		slices.Sort(names)
+		names = slices.Compact(names)
+
+		// A field selector matches a single name, so every secret is cached in a namespace shared by backends.
+		pdnsSecret := cache.Config{}
+		if len(names) == 1 && names[0] != "" {
+			pdnsSecret.FieldSelector = fields.OneTermEqualSelector("metadata.name", names[0])
+		}
+
+		secrets[namespace] = pdnsSecret
+		services[namespace] = cache.Config{
+			LabelSelector: l.ServiceSelector,
+		}
+	}
+
+	return secrets, services
+}
+
+// LabelTSIGSecrets labels the TSIG secrets created before the cache was restricted so that the manager sees them.
+//
+// The reader must not be cached, TSIG secrets are found by the cluster label and their owner zone.
+func LabelTSIGSecrets(ctx context.Context, reader client.Reader, c client.Client) error {
+	logger := ctrl.Log.WithName("cache-layout")
+
+	var secretList corev1.SecretList
+	err := reader.List(ctx, &secretList, client.HasLabels{dockyardsv1.LabelClusterName})
+	if err != nil {
+		return err
+	}
+
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+
+		_, labelled := secret.Labels[LabelSecret]
+		if labelled {
+			continue
+		}
+
+		owner := metav1.GetControllerOf(secret)
+		if owner == nil || owner.Kind != "Zone" || owner.APIVersion != pdnsv1.GroupVersion.String() {
+			continue
+		}
+
+		patch := client.MergeFrom(secret.DeepCopy())
+
+		metav1.SetMetaDataLabel(&secret.ObjectMeta, LabelSecret, secretTypeTSIG)
+
+		err := c.Patch(ctx, secret, patch)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		logger.Info("Labelled TSIG secret", "name", secret.Name, "namespace", secret.Namespace)
+	}
+
+	return nil
+}
+
+// LabelRecordTemplateConfigMaps labels the record template ConfigMaps created before the cache was restricted so that
+// the manager sees them.
+//
+// The reader must not be cached, record templates are found through the zones that reference them.
+func LabelRecordTemplateConfigMaps(ctx context.Context, reader client.Reader, c client.Client) error {
+	logger := ctrl.Log.WithName("cache-layout")
+
+	var zoneList pdnsv1.ZoneList
+	err := reader.List(ctx, &zoneList)
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zoneList.Items {
+		templateName := zone.Annotations[AnnotationRecordTemplate]
+		if templateName == "" {
+			continue
+		}
+
+		var configMap corev1.ConfigMap
+		err := reader.Get(ctx, client.ObjectKey{Name: templateName, Namespace: zone.Namespace}, &configMap)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		if err != nil {
+			continue
+		}
+
+		_, labelled := configMap.Labels[LabelConfigMap]
+		if labelled {
+			continue
+		}
+
+		patch := client.MergeFrom(configMap.DeepCopy())
+
+		metav1.SetMetaDataLabel(&configMap.ObjectMeta, LabelConfigMap, configMapTypeRecordTemplate)
+
+		err = c.Patch(ctx, &configMap, patch)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		logger.Info("Labelled record template ConfigMap", "name", configMap.Name, "namespace", configMap.Namespace)
+	}
+
+	return nil
+}
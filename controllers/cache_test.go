@@ -0,0 +1,251 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCacheLayout(t *testing.T) {
+	t.Run("test cluster-wide", func(t *testing.T) {
+		layout := CacheLayout{}
+
+		options := layout.Options()
+		if options.ByObject != nil {
+			t.Errorf("expected no restrictions, got %v", options.ByObject)
+		}
+	})
+
+	t.Run("test restricted", func(t *testing.T) {
+		layout := CacheLayout{
+			PDNSName:        "pdns",
+			PDNSNamespace:   "pdns",
+			ServiceSelector: labels.SelectorFromSet(labels.Set{"app": "pdns"}),
+		}
+
+		options := layout.Options()
+
+		var secrets, services *cache.ByObject
+		for obj, byObject := range options.ByObject {
+			switch obj.(type) {
+			case *corev1.Secret:
+				secrets = &byObject
+			case *corev1.Service:
+				services = &byObject
+			}
+		}
+
+		if secrets == nil || services == nil {
+			t.Fatalf("expected secrets and services to be restricted, got %v", options.ByObject)
+		}
+
+		pdnsSecret := secrets.Namespaces["pdns"]
+		if pdnsSecret.FieldSelector.String() != "metadata.name=pdns" {
+			t.Errorf("unexpected field selector %s", pdnsSecret.FieldSelector)
+		}
+
+		labelled := secrets.Namespaces[cache.AllNamespaces]
+		if labelled.LabelSelector.String() != LabelSecret {
+			t.Errorf("unexpected label selector %s", labelled.LabelSelector)
+		}
+
+		if len(services.Namespaces) != 1 || services.Namespaces["pdns"].LabelSelector.String() != "app=pdns" {
+			t.Errorf("unexpected service namespaces %v", services.Namespaces)
+		}
+	})
+
+	t.Run("test config namespace", func(t *testing.T) {
+		layout := CacheLayout{
+			ConfigNamespace: "dockyards-system",
+		}
+
+		options := layout.Options()
+		if len(options.ByObject) != 1 {
+			t.Fatalf("expected only config maps to be restricted, got %v", options.ByObject)
+		}
+
+		for obj, byObject := range options.ByObject {
+			_, isConfigMap := obj.(*corev1.ConfigMap)
+			if !isConfigMap {
+				t.Fatalf("unexpected restricted object %T", obj)
+			}
+
+			config, found := byObject.Namespaces["dockyards-system"]
+			if !found || config.LabelSelector != nil {
+				t.Errorf("expected every config map in the dockyards namespace, got %v", byObject.Namespaces)
+			}
+
+			labelled := byObject.Namespaces[cache.AllNamespaces]
+			if labelled.LabelSelector.String() != LabelConfigMap {
+				t.Errorf("unexpected label selector %s", labelled.LabelSelector)
+			}
+		}
+	})
+
+	t.Run("test backends", func(t *testing.T) {
+		layout := CacheLayout{
+			Backends: []PDNSBackend{
+				{Name: "eu", PDNSName: "pdns", PDNSNamespace: "pdns-eu"},
+				{Name: "us", PDNSName: "pdns-us", PDNSNamespace: "pdns-shared"},
+				{Name: "ap", PDNSName: "pdns-ap", PDNSNamespace: "pdns-shared"},
+			},
+		}
+
+		options := layout.Options()
+
+		for obj, byObject := range options.ByObject {
+			switch obj.(type) {
+			case *corev1.Secret:
+				if byObject.Namespaces["pdns-eu"].FieldSelector.String() != "metadata.name=pdns" {
+					t.Errorf("unexpected field selector %s", byObject.Namespaces["pdns-eu"].FieldSelector)
+				}
+
+				if byObject.Namespaces["pdns-shared"].FieldSelector != nil {
+					t.Errorf("expected every secret in a shared namespace, got %s", byObject.Namespaces["pdns-shared"].FieldSelector)
+				}
+			case *corev1.Service:
+				if len(byObject.Namespaces) != 2 {
+					t.Errorf("unexpected service namespaces %v", byObject.Namespaces)
+				}
+			}
+		}
+	})
+}
+
+func TestLabelTSIGSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	zoneOwner := metav1.OwnerReference{
+		APIVersion: pdnsv1.GroupVersion.String(),
+		Kind:       "Zone",
+		Name:       "org-test.test.com",
+		UID:        "zone-uid",
+		Controller: ptr.To(true),
+	}
+
+	secrets := []corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "tsig.org-test.test.com",
+				Namespace:       "testing",
+				Labels:          map[string]string{dockyardsv1.LabelClusterName: "test"},
+				OwnerReferences: []metav1.OwnerReference{zoneOwner},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-kubeconfig",
+				Namespace: "testing",
+				Labels:    map[string]string{dockyardsv1.LabelClusterName: "test"},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&secrets[0], &secrets[1]).Build()
+
+	err := LabelTSIGSecrets(ctx, c, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"tsig.org-test.test.com": secretTypeTSIG,
+		"test-kubeconfig":        "",
+	}
+
+	for name, value := range expected {
+		var secret corev1.Secret
+		err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: "testing"}, &secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if secret.Labels[LabelSecret] != value {
+			t.Errorf("expected label %q on %s, got %q", value, name, secret.Labels[LabelSecret])
+		}
+	}
+}
+
+func TestLabelRecordTemplateConfigMaps(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+			Annotations: map[string]string{
+				AnnotationRecordTemplate: "baseline",
+			},
+		},
+	}
+
+	configMaps := []corev1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "baseline",
+				Namespace: "testing",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "unrelated",
+				Namespace: "testing",
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zone, &configMaps[0], &configMaps[1]).Build()
+
+	err := LabelRecordTemplateConfigMaps(ctx, c, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"baseline":  configMapTypeRecordTemplate,
+		"unrelated": "",
+	}
+
+	for name, value := range expected {
+		var configMap corev1.ConfigMap
+		err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: "testing"}, &configMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if configMap.Labels[LabelConfigMap] != value {
+			t.Errorf("expected label %q on %s, got %q", value, name, configMap.Labels[LabelConfigMap])
+		}
+	}
+}
@@ -0,0 +1,427 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=create;patch
+// +kubebuilder:rbac:groups=dockyards.io,resources=workloadtemplates,verbs=get
+
+// Config is the typed form of the `dockyards-pdns.*` config keys and the Dockyards keys the reconcilers depend on.
+//
+// Annotations on clusters, organizations and zones can still override most of these per zone.
+type Config struct {
+	ManagementDomains   []string
+	ZoneLayout          string
+	Backend             string
+	PDNSBackends        []PDNSBackend
+	PDNSPlacement       *PDNSPlacement
+	PublicNamespace     string
+	Sources             []string
+	ExternalDNSProvider string
+	AllowDNSUpdateFrom  []string
+	ACMEChallenges      bool
+	ACMEHosts           []string
+	DNSSEC              bool
+	Paused              bool
+	DNSProbe            string
+	ZoneTransfers       *ZoneTransferPolicy
+	GarbageCollection   *GarbageCollectionPolicy
+	DriftAudit          *DriftAuditPolicy
+	ZoneExport          *ZoneExportPolicy
+	ZoneMigration       *ZoneMigrationPolicy
+	ReverseDNS          *ReverseDNSPolicy
+	ForceFieldOwnership bool
+}
+
+// ConfigCheck is the result of validating a single part of the configuration, Err is nil when the check passed.
+type ConfigCheck struct {
+	Name    string
+	Message string
+	Err     error
+}
+
+// requiredConfigValue returns the value of a config key that must be set.
+func requiredConfigValue(configManager *dyconfig.ConfigManager, key dyconfig.Key) (string, error) {
+	value, found := configManager.GetValueForKey(key)
+	if !found {
+		return "", fmt.Errorf("config key `%s` not found", key)
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("no value for config key `%s`", key)
+	}
+
+	return value, nil
+}
+
+// parseConfig reads every config key and returns a check for each of them, the config holds the valid values.
+func parseConfig(configManager *dyconfig.ConfigManager) (*Config, []ConfigCheck) {
+	var config Config
+	var checks []ConfigCheck
+
+	check := func(name string, err error, message string) {
+		checks = append(checks, ConfigCheck{Name: name, Message: message, Err: err})
+	}
+
+	var err error
+
+	r := ZoneReconciler{ConfigManager: configManager}
+
+	config.ManagementDomains, err = managementDomains(configManager)
+	if err == nil && len(config.ManagementDomains) == 0 {
+		err = fmt.Errorf("no value for config key `%s`", KeyManagementDomain)
+	}
+	check("management-domains", err, strings.Join(config.ManagementDomains, ","))
+
+	_, err = parseManagementDomainMapping(configManager.GetValueOrDefault(KeyManagementDomainMapping, ""))
+	if err != nil {
+		err = fmt.Errorf("invalid value for config key `%s`: %w", KeyManagementDomainMapping, err)
+	}
+	check("management-domain-mapping", err, "")
+
+	config.ZoneLayout, err = zoneLayout(configManager, &dockyardsv1.Organization{})
+	check("zone-layout", err, config.ZoneLayout)
+
+	config.Backend, err = parseBackend(configManager)
+	check("backend", err, config.Backend)
+
+	config.PDNSBackends, err = ParsePDNSBackends(configManager)
+
+	var backends []string
+	for _, backend := range config.PDNSBackends {
+		backends = append(backends, backend.Name+"="+backend.PDNSNamespace+"/"+backend.PDNSName)
+	}
+	check("powerdns", err, strings.Join(backends, ","))
+
+	if err == nil {
+		var policy string
+
+		config.PDNSPlacement, err = parsePDNSPlacement(configManager, config.PDNSBackends)
+		if err == nil {
+			policy = config.PDNSPlacement.Policy
+		}
+		check("powerdns-placement", err, policy)
+	}
+
+	config.PublicNamespace, err = requiredConfigValue(configManager, dyconfig.KeyPublicNamespace)
+	check("public-namespace", err, config.PublicNamespace)
+
+	config.Sources, err = parseSources(configManager)
+	check("external-dns-sources", err, strings.Join(config.Sources, ","))
+
+	var zone pdnsv1.Zone
+
+	config.ExternalDNSProvider, err = r.externalDNSProvider(&zone)
+	check("external-dns-provider", err, config.ExternalDNSProvider)
+
+	config.AllowDNSUpdateFrom, err = r.allowDNSUpdateFrom()
+	check("dns-update-networks", err, strings.Join(config.AllowDNSUpdateFrom, ","))
+
+	config.ACMEChallenges, err = r.isACMEEnabled(&zone)
+	check("acme-challenges", err, fmt.Sprintf("enabled=%t", config.ACMEChallenges))
+
+	config.ACMEHosts, err = parseACMEHosts(configManager.GetValueOrDefault(KeyACMEHosts, defaultACMEHosts))
+	if err != nil {
+		err = fmt.Errorf("invalid value for config key `%s`: %w", KeyACMEHosts, err)
+	}
+	check("acme-hosts", err, strings.Join(config.ACMEHosts, ","))
+
+	config.DNSSEC, err = r.isDNSSECEnabled(&zone)
+	check("dnssec", err, fmt.Sprintf("enabled=%t", config.DNSSEC))
+
+	config.Paused, err = isGloballyPaused(configManager)
+	check("paused", err, fmt.Sprintf("paused=%t", config.Paused))
+
+	config.DNSProbe, err = parseDNSProbeMode(configManager)
+	if err == nil && config.DNSProbe == dnsProbeDelegation {
+		_, err = customDomainResolver(configManager)
+	}
+	check("dns-probe", err, config.DNSProbe)
+
+	config.ZoneTransfers, err = parseZoneTransferPolicy(nil, configManager)
+	check("zone-transfers", err, "")
+
+	config.GarbageCollection, err = parseGarbageCollectionPolicy(configManager)
+	check("garbage-collection", err, "")
+
+	config.DriftAudit, err = parseDriftAuditPolicy(configManager)
+	check("drift-audit", err, "")
+
+	config.ZoneExport, err = parseZoneExportPolicy(configManager)
+	check("zone-export", err, "")
+
+	config.ZoneMigration, err = parseZoneMigrationPolicy(configManager)
+	check("zone-migration", err, "")
+
+	config.ReverseDNS, err = parseReverseDNSPolicy(configManager)
+
+	var pools []string
+	if err == nil {
+		for _, pool := range config.ReverseDNS.Pools {
+			pools = append(pools, pool.Prefix.String()+"="+pool.Zone)
+		}
+
+		// Reverse zones are ClusterZones, which only powerdns-operator serves.
+		if len(pools) > 0 && config.Backend == backendAPI {
+			err = fmt.Errorf("reverse zones are not supported by the %s backend", backendAPI)
+		}
+	}
+	check("reverse-dns", err, strings.Join(pools, ","))
+
+	config.ForceFieldOwnership, err = parseForceFieldOwnership(configManager)
+	check("field-ownership", err, fmt.Sprintf("force=%t", config.ForceFieldOwnership))
+
+	return &config, checks
+}
+
+// LoadConfig reads and validates every config key, all invalid keys are reported together.
+func LoadConfig(configManager *dyconfig.ConfigManager) (*Config, error) {
+	config, checks := parseConfig(configManager)
+
+	var errs []error
+	for _, check := range checks {
+		if check.Err != nil {
+			errs = append(errs, check.Err)
+		}
+	}
+
+	return config, errors.Join(errs...)
+}
+
+// parseSources returns the ExternalDNS sources.
+func parseSources(configManager *dyconfig.ConfigManager) ([]string, error) {
+	value, err := requiredConfigValue(configManager, KeySources)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := parseExternalDNSSources(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for config key `%s`: %w", KeySources, err)
+	}
+
+	return sources, nil
+}
+
+// checkConfig validates the config keys and the objects they refer to: the namespace, secret and services of every
+// PowerDNS backend, and the WorkloadTemplates in the public namespace.
+func checkConfig(ctx context.Context, reader client.Reader, configManager *dyconfig.ConfigManager) []ConfigCheck {
+	config, checks := parseConfig(configManager)
+
+	check := func(name string, err error, message string) {
+		checks = append(checks, ConfigCheck{Name: name, Message: message, Err: err})
+	}
+
+	for _, backend := range config.PDNSBackends {
+		suffix := ""
+		if len(config.PDNSBackends) > 1 {
+			suffix = "-" + backend.Name
+		}
+
+		var namespace corev1.Namespace
+		err := reader.Get(ctx, client.ObjectKey{Name: backend.PDNSNamespace}, &namespace)
+		check("powerdns-namespace"+suffix, err, backend.PDNSNamespace)
+
+		var secret corev1.Secret
+		err = reader.Get(ctx, client.ObjectKey{Name: backend.PDNSName, Namespace: backend.PDNSNamespace}, &secret)
+		if err == nil && len(secret.Data[secretPDNSAPIKey]) == 0 {
+			err = errors.New(secretPDNSAPIKey + " missing from secret")
+		}
+		check("powerdns-secret"+suffix, err, backend.PDNSNamespace+"/"+backend.PDNSName)
+
+		var dnsService corev1.Service
+		err = reader.Get(ctx, client.ObjectKey{Name: backend.PDNSName + "-dns", Namespace: backend.PDNSNamespace}, &dnsService)
+		if err == nil && len(dnsService.Status.LoadBalancer.Ingress) == 0 {
+			err = fmt.Errorf("service %s has no load balancer ingress", dnsService.Name)
+		}
+
+		var message string
+		if err == nil {
+			message = dnsService.Status.LoadBalancer.Ingress[0].IP
+		}
+		check("powerdns-dns-service"+suffix, err, message)
+
+		var apiService corev1.Service
+		err = reader.Get(ctx, client.ObjectKey{Name: backend.PDNSName + "-api", Namespace: backend.PDNSNamespace}, &apiService)
+		if err == nil && len(apiService.Spec.ClusterIPs) == 0 {
+			err = errors.New("no available API addresses for PowerDNS")
+		}
+		check("powerdns-api-service"+suffix, err, strings.Join(apiService.Spec.ClusterIPs, ","))
+	}
+
+	if config.PublicNamespace != "" {
+		var namespace corev1.Namespace
+		err := reader.Get(ctx, client.ObjectKey{Name: config.PublicNamespace}, &namespace)
+		check("public-namespace-exists", err, config.PublicNamespace)
+
+		templates := []string{workloadTargetNamespace}
+		if config.ACMEChallenges {
+			templates = append(templates, acmeWorkloadTemplateName)
+		}
+
+		for _, name := range templates {
+			var workloadTemplate dockyardsv1.WorkloadTemplate
+			err := reader.Get(ctx, client.ObjectKey{Name: name, Namespace: config.PublicNamespace}, &workloadTemplate)
+			check("workload-template-"+name, err, config.PublicNamespace+"/"+name)
+		}
+	}
+
+	return checks
+}
+
+// ConfigValidator validates the configuration on startup and then periodically, it serves the result as a readiness
+// check and writes it into a status ConfigMap.
+type ConfigValidator struct {
+	client.Client
+	*dyconfig.ConfigManager
+
+	// Reader reads the objects the config refers to, the API reader avoids caching namespaces and templates.
+	Reader    client.Reader
+	ConfigMap client.ObjectKey
+
+	checks atomic.Pointer[[]ConfigCheck]
+}
+
+// Start validates the configuration every interval until the context is cancelled.
+func (v *ConfigValidator) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("config-validator")
+
+	var failed string
+
+	for {
+		checks := checkConfig(ctx, v.Reader, v.ConfigManager)
+		v.checks.Store(&checks)
+
+		err := configChecksError(checks)
+
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+
+		if message != failed {
+			if err != nil {
+				logger.Error(err, "invalid configuration")
+			} else {
+				logger.Info("configuration is valid")
+			}
+
+			failed = message
+		}
+
+		err = v.report(ctx, checks)
+		if err != nil {
+			logger.Error(err, "error reporting configuration status")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(configValidationInterval):
+		}
+	}
+}
+
+// NeedLeaderElection lets every replica validate, readiness is reported per replica.
+func (v *ConfigValidator) NeedLeaderElection() bool {
+	return false
+}
+
+// ReadyCheck fails until the configuration has been validated and as long as any check fails.
+func (v *ConfigValidator) ReadyCheck(_ *http.Request) error {
+	checks := v.checks.Load()
+	if checks == nil {
+		return errors.New("configuration not validated yet")
+	}
+
+	return configChecksError(*checks)
+}
+
+// Checks returns the results of the last validation.
+func (v *ConfigValidator) Checks() []ConfigCheck {
+	checks := v.checks.Load()
+	if checks == nil {
+		return nil
+	}
+
+	return *checks
+}
+
+func configChecksError(checks []ConfigCheck) error {
+	var errs []error
+	for _, check := range checks {
+		if check.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.Name, check.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// report writes a key per check into the status ConfigMap, the ready key is false when any check failed.
+func (v *ConfigValidator) report(ctx context.Context, checks []ConfigCheck) error {
+	configMap := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      v.ConfigMap.Name,
+			Namespace: v.ConfigMap.Namespace,
+		},
+	}
+
+	data := make(map[string]string, len(checks)+1)
+
+	ready := true
+
+	for _, check := range checks {
+		if check.Err != nil {
+			ready = false
+			data[check.Name] = "error: " + check.Err.Error()
+
+			continue
+		}
+
+		data[check.Name] = "ok"
+		if check.Message != "" {
+			data[check.Name] += ": " + check.Message
+		}
+	}
+
+	data["ready"] = fmt.Sprintf("%t", ready)
+
+	ac := corev1ac.ConfigMap(configMap.Name, configMap.Namespace).
+		WithData(data)
+
+	_, err := applyObject(ctx, v.Client, &configMap, ac)
+
+	return err
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("test valid", func(t *testing.T) {
+		configManager := dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyManagementDomain):         "test.com",
+			string(KeyPDNSName):                 "pdns",
+			string(KeyPDNSNamespace):            "pdns",
+			string(KeySources):                  "ingress, service",
+			string(dyconfig.KeyPublicNamespace): "dockyards-public",
+		})
+
+		config, err := LoadConfig(configManager)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(config.PDNSBackends) != 1 || config.PDNSBackends[0].PDNSName != "pdns" || config.Backend != backendCRD || strings.Join(config.Sources, ",") != "ingress,service" {
+			t.Errorf("unexpected config %+v", config)
+		}
+	})
+
+	t.Run("test every invalid key", func(t *testing.T) {
+		configManager := dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyManagementDomain):  "test_domain.com",
+			string(KeyPDNSName):          "pdns",
+			string(KeyGarbageCollection): "purge",
+			string(KeyDNSSEC):            "maybe",
+			string(KeyDNSProbe):          "delegation",
+		})
+
+		_, err := LoadConfig(configManager)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		for _, key := range []dyconfig.Key{KeyManagementDomain, KeyPDNSNamespace, KeySources, dyconfig.KeyPublicNamespace, KeyCustomDomainResolver} {
+			if !strings.Contains(err.Error(), string(key)) {
+				t.Errorf("expected %s in %s", key, err)
+			}
+		}
+
+		for _, message := range []string{"purge", "maybe"} {
+			if !strings.Contains(err.Error(), message) {
+				t.Errorf("expected %s in %s", message, err)
+			}
+		}
+	})
+}
+
+func TestConfigValidator(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	objects := diagnosticsTestObjects()
+
+	workloadTemplate := dockyardsv1.WorkloadTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workloadTargetNamespace,
+			Namespace: "dockyards-public",
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	v := ConfigValidator{
+		Client: c,
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyManagementDomain):         "test.com",
+			string(KeyPDNSName):                 "pdns",
+			string(KeyPDNSNamespace):            "pdns",
+			string(KeySources):                  "ingress,service",
+			string(dyconfig.KeyPublicNamespace): "dockyards-public",
+		}),
+		Reader:    c,
+		ConfigMap: client.ObjectKey{Name: "dockyards-pdns-status", Namespace: "dockyards-system"},
+	}
+
+	validate := func() {
+		checks := checkConfig(ctx, v.Reader, v.ConfigManager)
+		v.checks.Store(&checks)
+
+		err := v.report(ctx, checks)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("test not validated", func(t *testing.T) {
+		err := v.ReadyCheck(nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("test missing workload template", func(t *testing.T) {
+		validate()
+
+		err := v.ReadyCheck(nil)
+		if err == nil || !strings.Contains(err.Error(), "workload-template-external-dns") {
+			t.Fatalf("expected missing workload template, got %v", err)
+		}
+
+		var configMap corev1.ConfigMap
+		err = c.Get(ctx, v.ConfigMap, &configMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if configMap.Data["ready"] != "false" {
+			t.Errorf("expected not ready, got %s", configMap.Data["ready"])
+		}
+
+		if !strings.HasPrefix(configMap.Data["workload-template-external-dns"], "error: ") {
+			t.Errorf("expected error, got %s", configMap.Data["workload-template-external-dns"])
+		}
+	})
+
+	t.Run("test ready", func(t *testing.T) {
+		err := c.Create(ctx, &workloadTemplate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		validate()
+
+		err = v.ReadyCheck(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var configMap corev1.ConfigMap
+		err = c.Get(ctx, v.ConfigMap, &configMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if configMap.Data["ready"] != "true" {
+			t.Errorf("expected ready, got %s", configMap.Data["ready"])
+		}
+
+		if configMap.Data["powerdns-dns-service"] != "ok: 192.0.2.1" {
+			t.Errorf("unexpected dns service check %s", configMap.Data["powerdns-dns-service"])
+		}
+	})
+}
@@ -15,20 +15,221 @@
 package controllers
 
 import (
+	"time"
+
 	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
 )
 
 const KeyManagementDomain dyconfig.Key = "dockyards-pdns.managementDomain"
 
+const (
+	KeyZoneLayout              dyconfig.Key = "dockyards-pdns.zoneLayout"
+	KeyManagementDomains       dyconfig.Key = "dockyards-pdns.managementDomains"
+	KeyManagementDomainMapping dyconfig.Key = "dockyards-pdns.managementDomainMapping"
+)
+
 const (
 	workloadTargetNamespace = "external-dns"
 	secretPDNSAPIKey        = "PDNS_API_KEY"
+	pdnsAPIPort             = "8081"
 )
 
 const (
 	KeyPDNSName      dyconfig.Key = "dockyards-pdns.pdnsName"
 	KeyPDNSNamespace dyconfig.Key = "dockyards-pdns.pdnsNamespace"
 	KeySources       dyconfig.Key = "dockyards-pdns.sources"
+	KeyCAAIssuers    dyconfig.Key = "dockyards-pdns.caaIssuers"
+	KeyCAAIodef      dyconfig.Key = "dockyards-pdns.caaIodef"
+)
+
+const (
+	KeyPDNSBackends       dyconfig.Key = "dockyards-pdns.pdnsBackends"
+	KeyPDNSPlacement      dyconfig.Key = "dockyards-pdns.pdnsPlacement"
+	KeyPDNSBackendMapping dyconfig.Key = "dockyards-pdns.pdnsBackendMapping"
+	KeyPDNSRegionLabel    dyconfig.Key = "dockyards-pdns.pdnsRegionLabel"
+)
+
+const (
+	defaultPDNSBackendName    = "default"
+	pdnsPlacementOrganization = "organization"
+	pdnsPlacementRegion       = "region"
+	pdnsPlacementLeastLoaded  = "least-loaded"
+	defaultPDNSRegionLabel    = "topology.kubernetes.io/region"
+)
+
+const configValidationInterval = time.Minute
+
+const (
+	KeyExternalDNSProvider dyconfig.Key = "dockyards-pdns.externalDNSProvider"
+	KeyAllowDNSUpdateFrom  dyconfig.Key = "dockyards-pdns.allowDNSUpdateFrom"
+)
+
+const (
+	externalDNSProviderPDNS    = "pdns"
+	externalDNSProviderRFC2136 = "rfc2136"
+	defaultAllowDNSUpdateFrom  = "0.0.0.0/0,::/0"
+)
+
+const (
+	KeyACMEChallenges dyconfig.Key = "dockyards-pdns.acmeChallenges"
+	KeyACMEHosts      dyconfig.Key = "dockyards-pdns.acmeHosts"
+	KeyACMEServer     dyconfig.Key = "dockyards-pdns.acmeServer"
+	KeyACMEEmail      dyconfig.Key = "dockyards-pdns.acmeEmail"
+)
+
+const (
+	acmeChallengeLabel           = "_acme-challenge"
+	acmeChallengeZonePrefix      = "acme-challenge."
+	acmeWorkloadTemplateName     = "cert-manager-dns01"
+	acmeWorkloadTargetNamespace  = "cert-manager"
+	defaultACMEHosts             = "@,apps"
+	defaultACMEServer            = "https://acme-v02.api.letsencrypt.org/directory"
+	acmeCertManagerTSIGAlgorithm = "HMACSHA256"
+)
+
+const (
+	KeyDNSSEC           dyconfig.Key = "dockyards-pdns.dnssec"
+	KeyDNSSECAlgorithm  dyconfig.Key = "dockyards-pdns.dnssecAlgorithm"
+	KeyDNSSECKeyPolicy  dyconfig.Key = "dockyards-pdns.dnssecKeyPolicy"
+	KeyDNSSECNSEC3Param dyconfig.Key = "dockyards-pdns.dnssecNSEC3Param"
+)
+
+const (
+	dnssecKeyPolicyCSK       = "csk"
+	dnssecKeyPolicyKSKZSK    = "ksk-zsk"
+	defaultDNSSECAlgorithm   = "ecdsap256sha256"
+	defaultDNSSECKeyPolicy   = dnssecKeyPolicyCSK
+	dsTTL                    = 3600
+	dsDigestTypeSHA256       = "2"
+	dnssecParentZoneKind     = "ClusterZone"
+	dnssecUnsignRequeueDelay = 10 * time.Second
+)
+
+const (
+	KeyZoneKind             dyconfig.Key = "dockyards-pdns.zoneKind"
+	KeySecondaryNameservers dyconfig.Key = "dockyards-pdns.secondaryNameservers"
+	KeySecondaryAddresses   dyconfig.Key = "dockyards-pdns.secondaryAddresses"
+	KeyTransferTSIG         dyconfig.Key = "dockyards-pdns.transferTSIG"
+)
+
+const (
+	zoneKindNative     = "Native"
+	zoneKindMaster     = "Master"
+	transferTSIGPrefix = "axfr."
+)
+
+const (
+	KeyCustomDomainResolver dyconfig.Key = "dockyards-pdns.customDomainResolver"
+)
+
+const (
+	zoneLayoutFlat         = "flat"
+	zoneLayoutHierarchical = "hierarchical"
+	childZoneRequeueDelay  = 30 * time.Second
+)
+
+const (
+	KeyGarbageCollection            dyconfig.Key = "dockyards-pdns.garbageCollection"
+	KeyGarbageCollectionGracePeriod dyconfig.Key = "dockyards-pdns.garbageCollectionGracePeriod"
+	KeyGarbageCollectionInterval    dyconfig.Key = "dockyards-pdns.garbageCollectionInterval"
+)
+
+const (
+	garbageCollectionDisabled      = "disabled"
+	garbageCollectionReport        = "report"
+	garbageCollectionDelete        = "delete"
+	defaultGarbageCollectionGrace  = 24 * time.Hour
+	defaultGarbageCollectionPeriod = time.Hour
+)
+
+const (
+	KeyDriftAudit         dyconfig.Key = "dockyards-pdns.driftAudit"
+	KeyDriftAuditInterval dyconfig.Key = "dockyards-pdns.driftAuditInterval"
+)
+
+const (
+	driftAuditDisabled        = "disabled"
+	driftAuditReport          = "report"
+	driftAuditRepair          = "repair"
+	defaultDriftAuditInterval = time.Hour
+)
+
+const (
+	KeyZoneMigration            dyconfig.Key = "dockyards-pdns.zoneMigration"
+	KeyZoneMigrationCNAMEPeriod dyconfig.Key = "dockyards-pdns.zoneMigrationCNAMEPeriod"
+)
+
+const (
+	planReportInterval = 30 * time.Second
+)
+
+const (
+	KeyPaused dyconfig.Key = "dockyards-pdns.paused"
+)
+
+const (
+	pausedRequeueDelay = time.Minute
+)
+
+const (
+	zoneMigrationRequeueDelay = 30 * time.Second
+)
+
+const (
+	KeyDNSProbe dyconfig.Key = "dockyards-pdns.dnsProbe"
+)
+
+const (
+	dnsProbeDisabled      = "disabled"
+	dnsProbeZone          = "zone"
+	dnsProbeDelegation    = "delegation"
+	dnsProbeRetryInterval = 30 * time.Second
+)
+
+const (
+	KeyZoneExport           dyconfig.Key = "dockyards-pdns.zoneExport"
+	KeyZoneExportInterval   dyconfig.Key = "dockyards-pdns.zoneExportInterval"
+	KeyZoneExportDirectory  dyconfig.Key = "dockyards-pdns.zoneExportDirectory"
+	KeyZoneExportAPIRecords dyconfig.Key = "dockyards-pdns.zoneExportAPIRecords"
+)
+
+const (
+	ZoneExportDisabled        = "disabled"
+	ZoneExportConfigMap       = "configmap"
+	ZoneExportSecret          = "secret"
+	ZoneExportDirectory       = "directory"
+	defaultZoneExportInterval = 24 * time.Hour
+	zoneExportObjectName      = "dockyards-pdns-zones"
+	zoneExportFileSuffix      = ".zone"
+)
+
+const (
+	KeyBackend dyconfig.Key = "dockyards-pdns.backend"
+)
+
+const (
+	KeyReverseZones       dyconfig.Key = "dockyards-pdns.reverseZones"
+	KeyReverseNameservers dyconfig.Key = "dockyards-pdns.reverseNameservers"
+)
+
+const (
+	backendCRD           = "crd"
+	backendAPI           = "api"
+	backendRecordAccount = "dockyards-pdns"
+)
+
+const (
+	customDomainChallengeLabel  = "_dockyards-challenge"
+	customDomainChallengePrefix = "dockyards-domain-verification="
+	customDomainRetryInterval   = time.Minute
+	customDomainQueryTimeout    = 5 * time.Second
+)
+
+const (
+	tsigAlgorithm       = "hmac-sha256"
+	secretTSIGName      = "name"
+	secretTSIGAlgorithm = "algorithm"
+	secretTSIGSecret    = "secret"
 )
 
 const (
@@ -37,4 +238,182 @@ const (
 	soaRetryInterval   = 3600
 	soaExpireTime      = 604800
 	soaNegativeCache   = 3600
+	// soaEditAPI makes PowerDNS increase the YYYYMMDDnn serial when records are changed through the API.
+	soaEditAPI = "DEFAULT"
+)
+
+const (
+	AnnotationRecordTemplate       = "pdns.dockyards.io/record-template"
+	AnnotationCAAIssuers           = "pdns.dockyards.io/caa-issuers"
+	AnnotationCAAIodef             = "pdns.dockyards.io/caa-iodef"
+	AnnotationACMEChallenges       = "pdns.dockyards.io/acme-challenges"
+	AnnotationExternalDNSProvider  = "pdns.dockyards.io/external-dns-provider"
+	AnnotationDNSSEC               = "pdns.dockyards.io/dnssec"
+	AnnotationDNSSECAlgorithm      = "pdns.dockyards.io/dnssec-algorithm"
+	AnnotationDNSSECKeyPolicy      = "pdns.dockyards.io/dnssec-key-policy"
+	AnnotationDNSSECNSEC3Param     = "pdns.dockyards.io/dnssec-nsec3param"
+	AnnotationDNSSECUnsignAfter    = "pdns.dockyards.io/dnssec-unsign-after"
+	AnnotationZoneKind             = "pdns.dockyards.io/zone-kind"
+	AnnotationSecondaryNameservers = "pdns.dockyards.io/secondary-nameservers"
+	AnnotationSecondaryAddresses   = "pdns.dockyards.io/secondary-addresses"
+	AnnotationTransferTSIG         = "pdns.dockyards.io/transfer-tsig"
+	AnnotationManagementDomain     = "pdns.dockyards.io/management-domain"
+	AnnotationZoneLayout           = "pdns.dockyards.io/zone-layout"
+	AnnotationParentZone           = "pdns.dockyards.io/parent-zone"
+	AnnotationCustomDomains        = "pdns.dockyards.io/custom-domains"
+	AnnotationOrphanedSince        = "pdns.dockyards.io/orphaned-since"
+	AnnotationMigrateTo            = "pdns.dockyards.io/migrate-to"
+	AnnotationMigrationCopied      = "pdns.dockyards.io/migration-copied"
+	AnnotationPaused               = "pdns.dockyards.io/paused"
+	AnnotationResyncRequested      = "pdns.dockyards.io/resync-requested"
+	AnnotationPDNSBackend          = "pdns.dockyards.io/pdns-backend"
+	AnnotationAddresses            = "pdns.dockyards.io/addresses"
+	AnnotationZoneTransfers        = "pdns.dockyards.io/zone-transfers"
+	AnnotationDelegatedFrom        = "pdns.dockyards.io/delegated-from"
+	AnnotationSOA                  = "pdns.dockyards.io/soa"
+	LabelRecordTemplate            = "pdns.dockyards.io/record-template"
+	LabelACMEChallenge             = "pdns.dockyards.io/acme-challenge"
+	LabelReverseDNS                = "pdns.dockyards.io/reverse-dns"
+	LabelZoneType                  = "pdns.dockyards.io/zone-type"
+	LabelZoneImport                = "pdns.dockyards.io/zone-import"
+	LabelSecret                    = "pdns.dockyards.io/secret"
+	LabelConfigMap                 = "pdns.dockyards.io/config-map"
+	LabelShard                     = "pdns.dockyards.io/shard"
+)
+
+const (
+	FinalizerChildZones  = "pdns.dockyards.io/child-zones"
+	FinalizerBackendZone = "pdns.dockyards.io/backend-zone"
+)
+
+// Values of LabelSecret, which marks the secrets the manager caches outside the PowerDNS namespace.
+const (
+	secretTypeTSIG       = "tsig"
+	secretTypeZoneExport = "zone-export"
+)
+
+// Values of LabelConfigMap, which marks the ConfigMaps the manager caches outside the dockyards namespace.
+const (
+	configMapTypeRecordTemplate = "record-template"
+	configMapTypeZoneExport     = "zone-export"
+)
+
+const (
+	ZoneTypeACMEChallenge = "acme-challenge"
+	ZoneTypeCustomDomain  = "custom-domain"
+	ZoneTypeOrganization  = "organization"
+	ZoneTypeReverse       = "reverse"
+)
+
+// zoneAnnotations are copied from the cluster, or else its organization, onto the zone.
+var zoneAnnotations = []string{
+	AnnotationRecordTemplate,
+	AnnotationCAAIssuers,
+	AnnotationCAAIodef,
+	AnnotationACMEChallenges,
+	AnnotationExternalDNSProvider,
+	AnnotationDNSSEC,
+	AnnotationDNSSECAlgorithm,
+	AnnotationDNSSECKeyPolicy,
+	AnnotationDNSSECNSEC3Param,
+	AnnotationZoneKind,
+	AnnotationSecondaryNameservers,
+	AnnotationSecondaryAddresses,
+	AnnotationTransferTSIG,
+}
+
+// emptyZoneAnnotations are zone annotations that are copied onto the zone with an empty value, which disables the
+// matching config key for the zone.
+var emptyZoneAnnotations = []string{
+	AnnotationCAAIssuers,
+	AnnotationCAAIodef,
+}
+
+const (
+	ZoneMigratedCondition = "ZoneMigrated"
+
+	ZoneMigrationWaitingReason    = "ZoneMigrationWaiting"
+	ZoneMigrationForwardingReason = "ZoneMigrationForwarding"
+	ZoneMigrationCompletedReason  = "ZoneMigrationCompleted"
+)
+
+const (
+	ReconciliationPausedCondition = "ReconciliationPaused"
+
+	ReconciliationPausedReason = "ReconciliationPaused"
+)
+
+const (
+	DriftDetectedReason = "DriftDetected"
+	DriftRepairedReason = "DriftRepaired"
+)
+
+const (
+	DriftUnknownZone     = "UnknownZone"
+	DriftMissingRecord   = "MissingRecord"
+	DriftRecordMismatch  = "RecordMismatch"
+	DriftUnmanagedRecord = "UnmanagedRecord"
+)
+
+const (
+	OrphanDetectedReason  = "OrphanDetected"
+	OrphanDeletedReason   = "OrphanDeleted"
+	OrphanRecoveredReason = "OrphanRecovered"
+)
+
+const (
+	DNSServingCondition = "DNSServing"
+
+	ZoneServedReason         = "ZoneServed"
+	ZoneNotServedReason      = "ZoneNotServed"
+	StaleSerialReason        = "StaleSerial"
+	NameserverMismatchReason = "NameserverMismatch"
+	DelegationMismatchReason = "DelegationMismatch"
+)
+
+const (
+	CAAPolicyReadyCondition = "CAAPolicyReady"
+
+	CAAPolicyReconciledReason = "CAAPolicyReconciled"
+	InvalidCAAPolicyReason    = "InvalidCAAPolicy"
+)
+
+const (
+	DNSSECReadyCondition = "DNSSECReady"
+
+	DNSSECSignedReason        = "DNSSECSigned"
+	DNSSECUnsigningReason     = "DNSSECUnsigning"
+	InvalidDNSSECPolicyReason = "InvalidDNSSECPolicy"
+)
+
+const (
+	CustomDomainsReadyCondition = "CustomDomainsReady"
+
+	CustomDomainsVerifiedReason           = "CustomDomainsVerified"
+	CustomDomainVerificationPendingReason = "CustomDomainVerificationPending"
+	InvalidCustomDomainReason             = "InvalidCustomDomain"
+)
+
+const (
+	ReverseDNSReadyCondition = "ReverseDNSReady"
+
+	ReverseDNSReconciledReason  = "ReverseDNSReconciled"
+	InvalidReverseAddressReason = "InvalidReverseAddress"
+	UnsupportedBackendReason    = "UnsupportedBackend"
+)
+
+// fieldManager owns the fields dockyards-pdns sets with server-side apply.
+const fieldManager = "dockyards-pdns"
+
+// patchFieldManager owns the fields dockyards-pdns sets with patches, such as finalizers and claims. It differs from
+// the default manager of earlier versions, whose fields are moved to fieldManager, so patched fields are never removed
+// by an apply.
+const patchFieldManager = "dockyards-pdns-patch"
+
+const (
+	KeyForceFieldOwnership dyconfig.Key = "dockyards-pdns.forceFieldOwnership"
+)
+
+const (
+	FieldConflictReason = "FieldConflict"
 )
@@ -0,0 +1,425 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=zones,verbs=delete
+
+// parseCustomDomains parses the comma-separated custom domains of a cluster.
+//
+// Domains overlapping a management domain are rejected since those names are handed out by the controller.
+func parseCustomDomains(value string, managementDomains []string) ([]string, error) {
+	var domains []string
+
+	for _, domain := range strings.Split(value, ",") {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if domain == "" {
+			continue
+		}
+
+		errs := validation.IsDNS1123Subdomain(domain)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("invalid custom domain %s: %s", domain, strings.Join(errs, ", "))
+		}
+
+		if !strings.Contains(domain, ".") {
+			return nil, fmt.Errorf("invalid custom domain %s: top-level domains are not allowed", domain)
+		}
+
+		for _, managementDomain := range managementDomains {
+			if domainsOverlap(domain, managementDomain) {
+				return nil, fmt.Errorf("invalid custom domain %s: overlaps management domain %s", domain, managementDomain)
+			}
+		}
+
+		if !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+
+	slices.Sort(domains)
+
+	return domains, nil
+}
+
+// domainsOverlap reports whether one domain is equal to or a subdomain of the other.
+func domainsOverlap(a, b string) bool {
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+// customDomainChallenge returns the TXT record name and value that prove ownership of a domain for a cluster.
+//
+// The value is derived from the cluster UID so that a challenge cannot be reused by another cluster.
+func customDomainChallenge(cluster *dockyardsv1.Cluster, domain string) (string, string) {
+	sum := sha256.Sum256([]byte(string(cluster.UID) + "/" + domain))
+
+	return customDomainChallengeLabel + "." + domain, customDomainChallengePrefix + hex.EncodeToString(sum[:16])
+}
+
+// exchangeDNS sends a query over UDP and retries over TCP when the answer is truncated.
+func exchangeDNS(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, time.Duration, error) {
+	c := dns.Client{
+		Timeout: customDomainQueryTimeout,
+	}
+
+	resp, rtt, err := c.ExchangeContext(ctx, msg, server)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.Truncated {
+		c.Net = "tcp"
+
+		resp, rtt, err = c.ExchangeContext(ctx, msg, server)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return resp, rtt, nil
+}
+
+// lookupTXT queries a recursive resolver for the TXT records of a name.
+func lookupTXT(ctx context.Context, resolver, name string) ([]string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeTXT)
+
+	resp, _, err := exchangeDNS(ctx, msg, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Rcode == dns.RcodeNameError {
+		return nil, nil
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("query for %s failed with %s", name, dns.RcodeToString[resp.Rcode])
+	}
+
+	var records []string
+
+	for _, rr := range resp.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+
+		records = append(records, strings.Join(txt.Txt, ""))
+	}
+
+	return records, nil
+}
+
+// customDomainResolver returns the address of the recursive resolver used to verify custom domains and delegations.
+//
+// The resolver must be configured, the nameservers of the pod are not trusted to answer for domains on the internet.
+func customDomainResolver(configManager *dyconfig.ConfigManager) (string, error) {
+	resolver, err := requiredConfigValue(configManager, KeyCustomDomainResolver)
+	if err != nil {
+		return "", err
+	}
+
+	_, _, err = net.SplitHostPort(resolver)
+	if err != nil {
+		return net.JoinHostPort(resolver, "53"), nil
+	}
+
+	return resolver, nil
+}
+
+// customDomainConflict returns a reason when a domain overlaps a zone that belongs to another cluster.
+func (r *DockyardsClusterReconciler) customDomainConflict(ctx context.Context, cluster *dockyardsv1.Cluster, domain string) (string, error) {
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList)
+	if err != nil {
+		return "", err
+	}
+
+	for _, zone := range zoneList.Items {
+		if zone.Namespace == cluster.Namespace && zone.Labels[dockyardsv1.LabelClusterName] == cluster.Name {
+			continue
+		}
+
+		if domainsOverlap(domain, zone.Name) {
+			return fmt.Sprintf("domain %s overlaps existing zone %s", domain, zone.Name), nil
+		}
+	}
+
+	return "", nil
+}
+
+// reconcileCustomDomains creates zones for the custom domains of a cluster once their ownership is verified.
+//
+// A domain is verified by a TXT record at `_dockyards-challenge.<domain>`; zones are never created before that, and a
+// zone that exists is considered verified.
+func (r *DockyardsClusterReconciler) reconcileCustomDomains(ctx context.Context, cluster *dockyardsv1.Cluster, clusterZone *pdnsv1.Zone, transferPolicy *ZoneTransferPolicy) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList, client.InNamespace(cluster.Namespace), client.MatchingLabels{
+		dockyardsv1.LabelClusterName: cluster.Name,
+		LabelZoneType:                ZoneTypeCustomDomain,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	allowed, err := managementDomains(r.ConfigManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	domains, err := parseCustomDomains(cluster.Annotations[AnnotationCustomDomains], allowed)
+	if err != nil {
+		logger.Info("ignoring invalid custom domains", "cluster", cluster.Name, "err", err)
+
+		return ctrl.Result{}, r.setCustomDomainsCondition(ctx, cluster, metav1.ConditionFalse, InvalidCustomDomainReason, err.Error())
+	}
+
+	verified := make(map[string]bool)
+	for _, zone := range zoneList.Items {
+		if metav1.IsControlledBy(&zone, clusterZone) {
+			verified[zone.Name] = true
+		}
+	}
+
+	var resolver string
+	var pending []string
+	var conflicts []string
+
+	for _, domain := range domains {
+		if !verified[domain] {
+			conflict, err := r.customDomainConflict(ctx, cluster, domain)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+
+			if conflict != "" {
+				conflicts = append(conflicts, conflict)
+
+				continue
+			}
+
+			if resolver == "" {
+				resolver, err = customDomainResolver(r.ConfigManager)
+				if err != nil {
+					pending = append(pending, fmt.Sprintf("unable to verify %s: %s", domain, err))
+
+					continue
+				}
+			}
+
+			name, value := customDomainChallenge(cluster, domain)
+
+			records, err := lookupTXT(ctx, resolver, name)
+			if err != nil {
+				logger.Info("unable to verify custom domain", "domain", domain, "err", err)
+			}
+
+			if !slices.Contains(records, value) {
+				pending = append(pending, fmt.Sprintf("create TXT record %s with value %q", name, value))
+
+				continue
+			}
+
+			logger.Info("Verified custom domain", "cluster", cluster.Name, "domain", domain)
+		}
+
+		zone := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      domain,
+				Namespace: cluster.Namespace,
+			},
+		}
+
+		claimed, err := r.Shard.claimZone(ctx, r.Client, client.ObjectKeyFromObject(&zone))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		err = r.Get(ctx, client.ObjectKeyFromObject(&zone), &zone)
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+
+		paused, err := isAnnotatedPaused(&zone)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if paused {
+			continue
+		}
+
+		ac := zoneApply(domain, cluster.Namespace, pdnsv1.ZoneSpec{
+			Kind:        transferPolicy.Kind,
+			Nameservers: transferPolicy.ZoneNameservers(clusterZone.Name),
+		})
+
+		ac.WithLabels(map[string]string{
+			dockyardsv1.LabelClusterName: cluster.Name,
+			LabelZoneType:                ZoneTypeCustomDomain,
+		})
+
+		r.Shard.applyLabel(ac.ObjectMetaApplyConfiguration)
+
+		for _, key := range transferZoneAnnotations {
+			value, found := clusterZone.Annotations[key]
+			if found {
+				ac.WithAnnotations(map[string]string{
+					key: value,
+				})
+			}
+		}
+
+		// Custom domain zones are served by the nameservers of the cluster zone.
+		ac.WithAnnotations(map[string]string{
+			AnnotationPDNSBackend: clusterZone.Annotations[AnnotationPDNSBackend],
+		})
+
+		ac.WithOwnerReferences(ownerReferenceApplyConfiguration(zoneOwnerReference(clusterZone)))
+
+		operationResult, err := r.Shard.applyZone(ctx, r.Client, &zone, ac)
+		if errors.Is(err, errClaimLost) {
+			continue
+		}
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Reconciled custom domain Zone", "cluster", cluster.Name, "zone", zone.Name, "operationResult", operationResult)
+	}
+
+	for _, zone := range zoneList.Items {
+		if slices.Contains(domains, zone.Name) || !metav1.IsControlledBy(&zone, clusterZone) || !r.Shard.Owns(&zone) || metav1.HasAnnotation(zone.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) {
+			continue
+		}
+
+		err := r.Delete(ctx, &zone)
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Deleted custom domain Zone", "cluster", cluster.Name, "zone", zone.Name)
+	}
+
+	if len(domains) == 0 {
+		patch := client.MergeFrom(cluster.DeepCopy())
+		if meta.RemoveStatusCondition(&cluster.Status.Conditions, CustomDomainsReadyCondition) {
+			return ctrl.Result{}, r.Status().Patch(ctx, cluster, patch)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	status := metav1.ConditionTrue
+	reason := CustomDomainsVerifiedReason
+	message := "Verified " + strings.Join(domains, ", ")
+
+	switch {
+	case len(conflicts) > 0:
+		status = metav1.ConditionFalse
+		reason = InvalidCustomDomainReason
+		message = strings.Join(append(conflicts, pending...), "; ")
+	case len(pending) > 0:
+		status = metav1.ConditionFalse
+		reason = CustomDomainVerificationPendingReason
+		message = strings.Join(pending, "; ")
+	}
+
+	err = r.setCustomDomainsCondition(ctx, cluster, status, reason, message)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(pending) > 0 {
+		return ctrl.Result{RequeueAfter: customDomainRetryInterval}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// externalDNSDomainFilter returns the cluster zone followed by the verified custom domains of the cluster.
+func (r *ZoneReconciler) externalDNSDomainFilter(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster) ([]string, error) {
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList, client.InNamespace(zone.Namespace), client.MatchingLabels{
+		dockyardsv1.LabelClusterName: cluster.Name,
+		LabelZoneType:                ZoneTypeCustomDomain,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []string{
+		zone.Name,
+	}
+
+	for _, customZone := range zoneList.Items {
+		if !customZone.DeletionTimestamp.IsZero() || !metav1.IsControlledBy(&customZone, zone) {
+			continue
+		}
+
+		domains = append(domains, customZone.Name)
+	}
+
+	slices.Sort(domains[1:])
+
+	return domains, nil
+}
+
+// setCustomDomainsCondition patches the custom domains condition of the cluster status.
+func (r *DockyardsClusterReconciler) setCustomDomainsCondition(ctx context.Context, cluster *dockyardsv1.Cluster, status metav1.ConditionStatus, reason, message string) error {
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	condition := metav1.Condition{
+		Type:               CustomDomainsReadyCondition,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: cluster.Generation,
+	}
+
+	if !meta.SetStatusCondition(&cluster.Status.Conditions, condition) {
+		return nil
+	}
+
+	return r.Status().Patch(ctx, cluster, patch)
+}
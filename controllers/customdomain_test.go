@@ -0,0 +1,206 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseCustomDomains(t *testing.T) {
+	tt := []struct {
+		name     string
+		value    string
+		expected []string
+		invalid  bool
+	}{
+		{
+			name:  "test domains",
+			value: "www.example.com, Shop.Example.com.,www.example.com",
+			expected: []string{
+				"shop.example.com",
+				"www.example.com",
+			},
+		},
+		{
+			name: "test empty",
+		},
+		{
+			name:    "test top-level domain",
+			value:   "com",
+			invalid: true,
+		},
+		{
+			name:    "test management domain",
+			value:   "other-cluster.test.com",
+			invalid: true,
+		},
+		{
+			name:    "test parent of management domain",
+			value:   "test.com",
+			invalid: true,
+		},
+		{
+			name:    "test other management domain",
+			value:   "www.test.net",
+			invalid: true,
+		},
+		{
+			name:    "test invalid domain",
+			value:   "shop_example.com",
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseCustomDomains(tc.value, []string{"test.com", "test.net"})
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected validation error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(actual, tc.expected) {
+				t.Error(cmp.Diff(tc.expected, actual))
+			}
+		})
+	}
+}
+
+func TestCustomDomainVerification(t *testing.T) {
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+			UID:  "a1b2c3",
+		},
+	}
+
+	otherCluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+			UID:  "d4e5f6",
+		},
+	}
+
+	name, value := customDomainChallenge(&cluster, "shop.example.com")
+	if name != "_dockyards-challenge.shop.example.com" {
+		t.Fatalf("unexpected challenge name %s", name)
+	}
+
+	_, otherValue := customDomainChallenge(&otherCluster, "shop.example.com")
+	if otherValue == value {
+		t.Fatal("expected challenges to differ between clusters")
+	}
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc(".", func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+
+		if req.Question[0].Name == dns.Fqdn(name) && req.Question[0].Qtype == dns.TypeTXT {
+			resp.Answer = append(resp.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 300},
+				Txt: []string{value},
+			})
+		} else {
+			resp.Rcode = dns.RcodeNameError
+		}
+
+		_ = w.WriteMsg(resp)
+	})
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	server := dns.Server{
+		PacketConn:        packetConn,
+		Handler:           mux,
+		NotifyStartedFunc: func() { close(started) },
+	}
+
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+
+	<-started
+
+	ctx := context.Background()
+	resolver := packetConn.LocalAddr().String()
+
+	t.Run("test verified", func(t *testing.T) {
+		records, err := lookupTXT(ctx, resolver, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{
+			value,
+		}
+
+		if !cmp.Equal(records, expected) {
+			t.Error(cmp.Diff(expected, records))
+		}
+	})
+
+	t.Run("test resolver", func(t *testing.T) {
+		_, err := customDomainResolver(dyconfig.NewFakeConfigManager(nil))
+		if err == nil {
+			t.Error("expected error without a configured resolver")
+		}
+
+		actual, err := customDomainResolver(dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyCustomDomainResolver): "192.0.2.53",
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != "192.0.2.53:53" {
+			t.Errorf("expected 192.0.2.53:53, got %s", actual)
+		}
+	})
+
+	t.Run("test missing challenge", func(t *testing.T) {
+		records, err := lookupTXT(ctx, resolver, "_dockyards-challenge.other.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 0 {
+			t.Errorf("expected no records, got %v", records)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// delegationRRsetName returns the name of the RRset that delegates a zone from its parent.
+func delegationRRsetName(zone *pdnsv1.Zone) string {
+	return "delegation." + zone.Name
+}
+
+// glueRRsetName returns the name of the RRset that publishes the glue record of a zone in its parent.
+func glueRRsetName(zone *pdnsv1.Zone) string {
+	return "glue." + zone.Name
+}
+
+// delegationRecords returns the fully qualified nameservers of a zone.
+func delegationRecords(zone *pdnsv1.Zone) []string {
+	nameservers := zone.Spec.Nameservers
+	if len(nameservers) == 0 {
+		nameservers = []string{"ns1." + zone.Name}
+	}
+
+	records := make([]string, len(nameservers))
+	for i, nameserver := range nameservers {
+		records[i] = strings.TrimSuffix(nameserver, ".") + "."
+	}
+
+	return records
+}
+
+// reconcileDelegation publishes NS and glue records for a zone in its parent zone.
+//
+// The records are owned by the child zone so they are garbage collected with it. The parent zone is recorded in
+// AnnotationDelegatedFrom before the records are published, so zones that lose or change their parent zone have the
+// delegation removed from the previous parent.
+func (r *ZoneReconciler) reconcileDelegation(ctx context.Context, zone *pdnsv1.Zone, externalIP string) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	parentZone := zone.Annotations[AnnotationParentZone]
+
+	delegatedFrom := zone.Annotations[AnnotationDelegatedFrom]
+	if delegatedFrom != "" && delegatedFrom != parentZone {
+		err := r.deleteDelegation(ctx, zone)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if parentZone == "" {
+		return ctrl.Result{}, nil
+	}
+
+	if zone.Annotations[AnnotationDelegatedFrom] != parentZone {
+		patch := client.MergeFrom(zone.DeepCopy())
+
+		metav1.SetMetaDataAnnotation(&zone.ObjectMeta, AnnotationDelegatedFrom, parentZone)
+
+		err := r.Patch(ctx, zone, patch)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	zoneRef := pdnsv1.ZoneRef{
+		Name: parentZone,
+		Kind: "Zone", // PDNS library does not offer ZoneKind
+	}
+
+	backend, err := r.parentBackend(ctx, zone, zoneRef)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	delegation := BackendRecord{
+		ID: delegationRRsetName(zone),
+		Spec: pdnsv1.RRsetSpec{
+			Type:    "NS",
+			TTL:     uint32(zoneTTL),
+			Name:    zone.Name + ".",
+			Records: delegationRecords(zone),
+			ZoneRef: zoneRef,
+		},
+	}
+
+	operationResult, err := backend.EnsureRecord(ctx, zone, delegation)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Reconciled Zone delegation RRSet", "zone", zone.Name, "parentZone", parentZone, "operationResult", operationResult)
+
+	glue := BackendRecord{
+		ID: glueRRsetName(zone),
+		Spec: pdnsv1.RRsetSpec{
+			Type: "A",
+			TTL:  uint32(zoneTTL),
+			Name: "ns1." + zone.Name + ".",
+			Records: []string{
+				externalIP,
+			},
+			ZoneRef: zoneRef,
+		},
+	}
+
+	operationResult, err = backend.EnsureRecord(ctx, zone, glue)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Reconciled Zone glue RRSet", "zone", zone.Name, "parentZone", parentZone, "operationResult", operationResult)
+
+	return ctrl.Result{}, nil
+}
+
+// deleteDelegation removes the NS and glue records of a zone from the parent zone recorded in
+// AnnotationDelegatedFrom, zones that were never delegated are left alone.
+func (r *ZoneReconciler) deleteDelegation(ctx context.Context, zone *pdnsv1.Zone) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	delegatedFrom := zone.Annotations[AnnotationDelegatedFrom]
+	if delegatedFrom == "" {
+		return nil
+	}
+
+	backend, err := r.parentBackend(ctx, zone, pdnsv1.ZoneRef{Name: delegatedFrom, Kind: "Zone"})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range []string{delegationRRsetName(zone), glueRRsetName(zone)} {
+		record := BackendRecord{
+			ID: id,
+		}
+
+		deleted, err := backend.DeleteRecord(ctx, zone, record)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			logger.Info("Deleted Zone delegation RRSet", "zone", zone.Name, "parentZone", delegatedFrom, "rrset", record.ID)
+		}
+	}
+
+	patch := client.MergeFrom(zone.DeepCopy())
+
+	delete(zone.Annotations, AnnotationDelegatedFrom)
+
+	return r.Patch(ctx, zone, patch)
+}
+
+// reconcileDeleteParentZone holds the deletion of a parent zone until no child zone is delegated from it.
+func (r *ZoneReconciler) reconcileDeleteParentZone(ctx context.Context, zone *pdnsv1.Zone) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	if !controllerutil.ContainsFinalizer(zone, FinalizerChildZones) {
+		return ctrl.Result{}, nil
+	}
+
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList, client.InNamespace(zone.Namespace))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var children []string
+	for _, child := range zoneList.Items {
+		if child.Annotations[AnnotationParentZone] == zone.Name {
+			children = append(children, child.Name)
+		}
+	}
+
+	if len(children) > 0 {
+		logger.Info("Waiting for child zones before deleting zone", "zone", zone.Name, "childZones", children)
+
+		return ctrl.Result{RequeueAfter: childZoneRequeueDelay}, nil
+	}
+
+	patch := client.MergeFrom(zone.DeepCopy())
+
+	controllerutil.RemoveFinalizer(zone, FinalizerChildZones)
+
+	err = r.Patch(ctx, zone, patch)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
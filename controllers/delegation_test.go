@@ -0,0 +1,309 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestDelegationRecords(t *testing.T) {
+	tt := []struct {
+		name        string
+		nameservers []string
+		expected    []string
+	}{
+		{
+			name: "test default nameserver",
+			expected: []string{
+				"ns1.cluster.org.test.com.",
+			},
+		},
+		{
+			name: "test secondaries",
+			nameservers: []string{
+				"ns1.cluster.org.test.com",
+				"ns2.secondary.net.",
+			},
+			expected: []string{
+				"ns1.cluster.org.test.com.",
+				"ns2.secondary.net.",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			zone := pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster.org.test.com",
+				},
+				Spec: pdnsv1.ZoneSpec{
+					Nameservers: tc.nameservers,
+				},
+			}
+
+			actual := delegationRecords(&zone)
+			if !cmp.Equal(actual, tc.expected) {
+				t.Errorf("diff: %s", cmp.Diff(tc.expected, actual))
+			}
+		})
+	}
+}
+
+func TestReconcileDelegation(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = pdnsv1.AddToScheme(scheme)
+
+	parent := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org.test.com",
+			Namespace: "testing",
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster.org.test.com",
+			Namespace: "testing",
+			Annotations: map[string]string{
+				AnnotationParentZone: parent.Name,
+			},
+		},
+	}
+
+	flat := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster.test.com",
+			Namespace: "testing",
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&parent, &zone, &flat).Build()
+
+	backend := NewMemoryBackend()
+
+	r := ZoneReconciler{
+		Client:        c,
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{}),
+		Backend:       backend,
+	}
+
+	t.Run("test delegation", func(t *testing.T) {
+		_, err := r.reconcileDelegation(ctx, &zone, "192.168.0.53")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		delegation, found := backend.Record("testing", delegationRRsetName(&zone))
+		if !found {
+			t.Fatal("expected delegation record")
+		}
+
+		expected := pdnsv1.RRsetSpec{
+			Type:    "NS",
+			TTL:     uint32(zoneTTL),
+			Name:    "cluster.org.test.com.",
+			Records: []string{"ns1.cluster.org.test.com."},
+			ZoneRef: pdnsv1.ZoneRef{Name: parent.Name, Kind: "Zone"},
+		}
+
+		if !cmp.Equal(delegation.Spec, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, delegation.Spec))
+		}
+
+		glue, found := backend.Record("testing", glueRRsetName(&zone))
+		if !found {
+			t.Fatal("expected glue record")
+		}
+
+		expected = pdnsv1.RRsetSpec{
+			Type:    "A",
+			TTL:     uint32(zoneTTL),
+			Name:    "ns1.cluster.org.test.com.",
+			Records: []string{"192.168.0.53"},
+			ZoneRef: pdnsv1.ZoneRef{Name: parent.Name, Kind: "Zone"},
+		}
+
+		if !cmp.Equal(glue.Spec, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, glue.Spec))
+		}
+
+		var actual pdnsv1.Zone
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual.Annotations[AnnotationDelegatedFrom] != parent.Name {
+			t.Errorf("expected delegated from %s, got %s", parent.Name, actual.Annotations[AnnotationDelegatedFrom])
+		}
+	})
+
+	t.Run("test removed parent zone", func(t *testing.T) {
+		delete(zone.Annotations, AnnotationParentZone)
+
+		err := c.Update(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileDelegation(ctx, &zone, "192.168.0.53")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, id := range []string{delegationRRsetName(&zone), glueRRsetName(&zone)} {
+			_, found := backend.Record("testing", id)
+			if found {
+				t.Errorf("expected record %s to be deleted", id)
+			}
+		}
+
+		var actual pdnsv1.Zone
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if metav1.HasAnnotation(actual.ObjectMeta, AnnotationDelegatedFrom) {
+			t.Errorf("expected no delegated from annotation, got %s", actual.Annotations[AnnotationDelegatedFrom])
+		}
+	})
+
+	t.Run("test flat zone", func(t *testing.T) {
+		record := BackendRecord{
+			ID: delegationRRsetName(&flat),
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "NS",
+				Name:    "cluster.test.com.",
+				Records: []string{"ns1.other.net."},
+			},
+		}
+
+		_, err := backend.EnsureRecord(ctx, &flat, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileDelegation(ctx, &flat, "192.168.0.53")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, found := backend.Record("testing", record.ID)
+		if !found {
+			t.Error("expected record of zone that was never delegated to be left alone")
+		}
+	})
+}
+
+func TestReconcileDeleteParentZone(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = pdnsv1.AddToScheme(scheme)
+
+	parent := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org.test.com",
+			Namespace: "testing",
+			Finalizers: []string{
+				FinalizerChildZones,
+			},
+		},
+	}
+
+	child := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster.org.test.com",
+			Namespace: "testing",
+			Annotations: map[string]string{
+				AnnotationParentZone: parent.Name,
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&parent, &child).Build()
+
+	r := ZoneReconciler{
+		Client: c,
+	}
+
+	err := c.Delete(ctx, &parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test child zones", func(t *testing.T) {
+		err := c.Get(ctx, client.ObjectKeyFromObject(&parent), &parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := r.reconcileDeleteParentZone(ctx, &parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.RequeueAfter != childZoneRequeueDelay {
+			t.Errorf("expected requeue after %s, got %s", childZoneRequeueDelay, result.RequeueAfter)
+		}
+
+		err = c.Get(ctx, client.ObjectKeyFromObject(&parent), &parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !controllerutil.ContainsFinalizer(&parent, FinalizerChildZones) {
+			t.Error("expected child zones finalizer")
+		}
+	})
+
+	t.Run("test no child zones", func(t *testing.T) {
+		err := c.Delete(ctx, &child)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := r.reconcileDeleteParentZone(ctx, &parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !result.IsZero() {
+			t.Errorf("expected empty result, got %v", result)
+		}
+
+		err = c.Get(ctx, client.ObjectKeyFromObject(&parent), &parent)
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected parent zone to be deleted, got %v", err)
+		}
+	})
+}
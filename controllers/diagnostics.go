@@ -0,0 +1,284 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/sudoswedenab/dockyards-backend/api/apiutil"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterConditions are the cluster conditions maintained by the reconcilers.
+var clusterConditions = []string{
+	ReconciliationPausedCondition,
+	ZoneMigratedCondition,
+	DNSServingCondition,
+	CAAPolicyReadyCondition,
+	DNSSECReadyCondition,
+	CustomDomainsReadyCondition,
+}
+
+// StatusRRset is an RRset of a cluster zone as seen by the backend.
+type StatusRRset struct {
+	Name       string
+	Type       string
+	TTL        uint32
+	Records    []string
+	SyncStatus string
+}
+
+// StatusWorkload is a Workload created for a cluster, such as ExternalDNS.
+type StatusWorkload struct {
+	Name    string
+	Ready   metav1.ConditionStatus
+	Reason  string
+	Message string
+}
+
+// ClusterStatus is everything the reconcilers know about the DNS of a cluster.
+type ClusterStatus struct {
+	Cluster      string
+	Namespace    string
+	Organization string
+	ZoneName     string
+	ParentZone   string
+
+	// Zone is nil when the zone has not been created yet.
+	Zone       *pdnsv1.Zone
+	SyncStatus string
+	RRsets     []StatusRRset
+
+	// RRsetsError is set when the RRsets could not be read from the backend.
+	RRsetsError string
+
+	Workloads  []StatusWorkload
+	Conditions []metav1.Condition
+
+	// Endpoints are the PowerDNS service addresses, EndpointsError is set when they could not be discovered.
+	Endpoints      *PDNSIPs
+	EndpointsError string
+}
+
+// Diagnostics inspects and repairs the DNS of clusters for the operator subcommands.
+type Diagnostics struct {
+	client.Client
+	*dyconfig.ConfigManager
+}
+
+func (d *Diagnostics) reconciler() *ZoneReconciler {
+	return &ZoneReconciler{
+		Client:        d.Client,
+		ConfigManager: d.ConfigManager,
+	}
+}
+
+// ClusterStatus collects the zone, RRsets, workloads, conditions and PowerDNS endpoints of a cluster.
+func (d *Diagnostics) ClusterStatus(ctx context.Context, namespace, name string) (*ClusterStatus, error) {
+	var cluster dockyardsv1.Cluster
+	err := d.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerOrganization, err := apiutil.GetOwnerOrganization(ctx, d.Client, &cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if ownerOrganization == nil {
+		return nil, fmt.Errorf("cluster %s has no owner organization", cluster.Name)
+	}
+
+	naming, err := resolveClusterZoneNaming(d.ConfigManager, &cluster, ownerOrganization)
+	if err != nil {
+		return nil, err
+	}
+
+	status := ClusterStatus{
+		Cluster:      cluster.Name,
+		Namespace:    cluster.Namespace,
+		Organization: ownerOrganization.Name,
+		ZoneName:     naming.Name,
+		ParentZone:   naming.ParentZone,
+	}
+
+	for _, conditionType := range clusterConditions {
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, conditionType)
+		if condition != nil {
+			status.Conditions = append(status.Conditions, *condition)
+		}
+	}
+
+	var workloadList dockyardsv1.WorkloadList
+	err = d.List(ctx, &workloadList, client.InNamespace(cluster.Namespace), client.MatchingLabels{dockyardsv1.LabelClusterName: cluster.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, workload := range workloadList.Items {
+		statusWorkload := StatusWorkload{
+			Name:  workload.Name,
+			Ready: metav1.ConditionUnknown,
+		}
+
+		condition := meta.FindStatusCondition(workload.Status.Conditions, dockyardsv1.ReadyCondition)
+		if condition != nil {
+			statusWorkload.Ready = condition.Status
+			statusWorkload.Reason = condition.Reason
+			statusWorkload.Message = condition.Message
+		}
+
+		status.Workloads = append(status.Workloads, statusWorkload)
+	}
+
+	var zone pdnsv1.Zone
+	err = d.Get(ctx, client.ObjectKey{Name: naming.Name, Namespace: cluster.Namespace}, &zone)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+
+	found := err == nil
+
+	// A zone that has not been created yet has the endpoints of the default PowerDNS backend.
+	ips, err := d.reconciler().getPDNSIPs(ctx, &zone)
+	if err != nil {
+		status.EndpointsError = err.Error()
+	} else {
+		status.Endpoints = ips
+	}
+
+	if !found {
+		return &status, nil
+	}
+
+	status.Zone = &zone
+
+	if zone.Status.SyncStatus != nil {
+		status.SyncStatus = *zone.Status.SyncStatus
+	}
+
+	status.RRsets, err = d.zoneRRsets(ctx, &zone)
+	if err != nil {
+		status.RRsetsError = err.Error()
+	}
+
+	return &status, nil
+}
+
+// zoneRRsets returns the RRset resources of a zone, or the RRsets served by PowerDNS when the backend does not use
+// resources, with the RRsets the api backend owns marked as owned.
+func (d *Diagnostics) zoneRRsets(ctx context.Context, zone *pdnsv1.Zone) ([]StatusRRset, error) {
+	backend, err := parseBackend(d.ConfigManager)
+	if err != nil {
+		return nil, err
+	}
+
+	var rrsets []StatusRRset
+
+	if backend == backendCRD {
+		var rrsetList pdnsv1.RRsetList
+		err := d.List(ctx, &rrsetList, client.InNamespace(zone.Namespace))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rrset := range rrsetList.Items {
+			if rrset.Spec.ZoneRef.Name != zone.Name {
+				continue
+			}
+
+			statusRRset := StatusRRset{
+				Name:    rrsetFQDN(rrset.Spec.Name, zone.Name),
+				Type:    rrset.Spec.Type,
+				TTL:     rrset.Spec.TTL,
+				Records: rrset.Spec.Records,
+			}
+
+			if rrset.Status.SyncStatus != nil {
+				statusRRset.SyncStatus = *rrset.Status.SyncStatus
+			}
+
+			rrsets = append(rrsets, statusRRset)
+		}
+	} else {
+		r := d.reconciler()
+
+		ips, err := r.getPDNSIPs(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		pdnsClient, err := r.getPDNSClient(ctx, ips)
+		if err != nil {
+			return nil, err
+		}
+
+		served, err := pdnsClient.GetZone(ctx, zone.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rrset := range served.RRsets {
+			syncStatus := "Served"
+			if recordOwner(rrset) != nil {
+				syncStatus = "Owned"
+			}
+
+			rrsets = append(rrsets, StatusRRset{
+				Name:       rrset.Name,
+				Type:       rrset.Type,
+				TTL:        rrset.TTL,
+				Records:    recordContents(rrset),
+				SyncStatus: syncStatus,
+			})
+		}
+	}
+
+	slices.SortFunc(rrsets, func(a, b StatusRRset) int {
+		return strings.Compare(rrsetKey(a.Name, a.Type), rrsetKey(b.Name, b.Type))
+	})
+
+	return rrsets, nil
+}
+
+// Resync requests reconciliation of a cluster and its zone by annotating the cluster with the current time.
+func (d *Diagnostics) Resync(ctx context.Context, namespace, name string, now time.Time) error {
+	var cluster dockyardsv1.Cluster
+	err := d.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &cluster)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	metav1.SetMetaDataAnnotation(&cluster.ObjectMeta, AnnotationResyncRequested, now.UTC().Format(time.RFC3339Nano))
+
+	return d.Patch(ctx, &cluster, patch)
+}
+
+// Doctor validates the config keys and the objects they refer to, see checkConfig.
+func (d *Diagnostics) Doctor(ctx context.Context) []ConfigCheck {
+	return checkConfig(ctx, d.Client, d.ConfigManager)
+}
@@ -0,0 +1,321 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func diagnosticsTestObjects() []client.Object {
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org",
+			UID:  "org-uid",
+		},
+	}
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testing",
+			UID:       "cluster-uid",
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: dockyardsv1.GroupVersion.String(),
+					Kind:       dockyardsv1.OrganizationKind,
+					Name:       organization.Name,
+					UID:        organization.UID,
+				},
+			},
+		},
+		Status: dockyardsv1.ClusterStatus{
+			Conditions: []metav1.Condition{
+				{
+					Type:   DNSServingCondition,
+					Status: metav1.ConditionTrue,
+					Reason: "Serving",
+				},
+				{
+					Type:   dockyardsv1.ReadyCondition,
+					Status: metav1.ConditionTrue,
+				},
+			},
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+			UID:       "zone-uid",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: "test",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: dockyardsv1.GroupVersion.String(),
+					Kind:       dockyardsv1.ClusterKind,
+					Name:       cluster.Name,
+					UID:        cluster.UID,
+					Controller: ptr.To(true),
+				},
+			},
+		},
+		Status: pdnsv1.ZoneStatus{
+			SyncStatus: ptr.To("Succeeded"),
+		},
+	}
+
+	rrset := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ns1.org-test.test.com",
+			Namespace: "testing",
+		},
+		Spec: pdnsv1.RRsetSpec{
+			Type:    "A",
+			TTL:     zoneTTL,
+			Name:    "ns1",
+			Records: []string{"192.0.2.1"},
+			ZoneRef: pdnsv1.ZoneRef{Name: zone.Name, Kind: "Zone"},
+		},
+		Status: pdnsv1.RRsetStatus{
+			SyncStatus: ptr.To("Succeeded"),
+		},
+	}
+
+	orphanedZone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-gone.test.com",
+			Namespace: "testing",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: "gone",
+			},
+		},
+	}
+
+	workload := dockyardsv1.Workload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-external-dns",
+			Namespace: "testing",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: "test",
+			},
+		},
+		Status: dockyardsv1.WorkloadStatus{
+			Conditions: []metav1.Condition{
+				{
+					Type:   dockyardsv1.ReadyCondition,
+					Status: metav1.ConditionFalse,
+					Reason: "Progressing",
+				},
+			},
+		},
+	}
+
+	dnsService := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pdns-dns",
+			Namespace: "pdns",
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.1"}},
+			},
+		},
+	}
+
+	apiService := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pdns-api",
+			Namespace: "pdns",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIPs: []string{"10.0.0.1"},
+		},
+	}
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pdns",
+			Namespace: "pdns",
+		},
+		Data: map[string][]byte{
+			secretPDNSAPIKey: []byte("secret"),
+		},
+	}
+
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pdns"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "dockyards-public"}},
+	}
+
+	return []client.Object{&organization, &cluster, &zone, &rrset, &orphanedZone, &workload, &dnsService, &apiService, &secret, &namespaces[0], &namespaces[1]}
+}
+
+func TestDiagnostics(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(diagnosticsTestObjects()...).Build()
+
+	configManager := dyconfig.NewFakeConfigManager(map[string]string{
+		string(KeyManagementDomain):         "test.com",
+		string(KeyPDNSName):                 "pdns",
+		string(KeyPDNSNamespace):            "pdns",
+		string(KeySources):                  "ingress,service",
+		string(dyconfig.KeyPublicNamespace): "dockyards-public",
+		string(KeyGarbageCollection):        "purge",
+	})
+
+	d := Diagnostics{
+		Client:        c,
+		ConfigManager: configManager,
+	}
+
+	t.Run("test cluster status", func(t *testing.T) {
+		actual, err := d.ClusterStatus(ctx, "testing", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual.ZoneName != "org-test.test.com" || actual.SyncStatus != "Succeeded" || actual.Organization != "org" {
+			t.Errorf("unexpected zone %s, sync status %s, organization %s", actual.ZoneName, actual.SyncStatus, actual.Organization)
+		}
+
+		expectedEndpoints := &PDNSIPs{
+			DNSIP:  "192.0.2.1",
+			APIIPs: []string{"10.0.0.1"},
+			PDNSBackend: &PDNSBackend{
+				Name:          defaultPDNSBackendName,
+				PDNSName:      "pdns",
+				PDNSNamespace: "pdns",
+				Default:       true,
+			},
+		}
+		if !cmp.Equal(actual.Endpoints, expectedEndpoints) {
+			t.Errorf("diff: %s", cmp.Diff(expectedEndpoints, actual.Endpoints))
+		}
+
+		expectedRRsets := []StatusRRset{
+			{
+				Name:       "ns1.org-test.test.com.",
+				Type:       "A",
+				TTL:        zoneTTL,
+				Records:    []string{"192.0.2.1"},
+				SyncStatus: "Succeeded",
+			},
+		}
+
+		if !cmp.Equal(actual.RRsets, expectedRRsets) {
+			t.Errorf("diff: %s", cmp.Diff(expectedRRsets, actual.RRsets))
+		}
+
+		expectedWorkloads := []StatusWorkload{
+			{
+				Name:   "test-external-dns",
+				Ready:  metav1.ConditionFalse,
+				Reason: "Progressing",
+			},
+		}
+
+		if !cmp.Equal(actual.Workloads, expectedWorkloads) {
+			t.Errorf("diff: %s", cmp.Diff(expectedWorkloads, actual.Workloads))
+		}
+
+		if len(actual.Conditions) != 1 || actual.Conditions[0].Type != DNSServingCondition {
+			t.Errorf("expected only the DNSServing condition, got %v", actual.Conditions)
+		}
+	})
+
+	t.Run("test resync", func(t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		err := d.Resync(ctx, "testing", "test", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cluster dockyardsv1.Cluster
+		err = c.Get(ctx, client.ObjectKey{Name: "test", Namespace: "testing"}, &cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "2025-01-01T00:00:00Z"
+		if cluster.Annotations[AnnotationResyncRequested] != expected {
+			t.Errorf("expected %s, got %s", expected, cluster.Annotations[AnnotationResyncRequested])
+		}
+	})
+
+	t.Run("test doctor", func(t *testing.T) {
+		failed := make(map[string]bool)
+		for _, check := range d.Doctor(ctx) {
+			if check.Err != nil {
+				failed[check.Name] = true
+			}
+		}
+
+		expected := map[string]bool{
+			"garbage-collection":             true,
+			"workload-template-external-dns": true,
+		}
+
+		if !cmp.Equal(failed, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, failed))
+		}
+	})
+
+	t.Run("test orphans", func(t *testing.T) {
+		g := GarbageCollector{
+			Client:        c,
+			ConfigManager: configManager,
+		}
+
+		actual, err := g.Orphans(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []Orphan{
+			{
+				Kind:      "Zone",
+				Namespace: "testing",
+				Name:      "org-gone.test.com",
+				Reason:    "cluster gone not found",
+			},
+		}
+
+		if !cmp.Equal(actual, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual))
+		}
+	})
+}
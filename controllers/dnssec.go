@@ -0,0 +1,492 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=zones,verbs=patch
+
+// dnssecAlgorithms are the signing algorithms accepted by the PowerDNS cryptokeys API.
+var dnssecAlgorithms = []string{
+	"rsasha256",
+	"rsasha512",
+	"ecdsap256sha256",
+	"ecdsap384sha384",
+	"ed25519",
+	"ed448",
+}
+
+// DNSSECPolicy describes how a zone is signed.
+type DNSSECPolicy struct {
+	Algorithm  string
+	KeyPolicy  string
+	NSEC3Param string
+}
+
+// Validate checks the algorithm, key policy and NSEC3 parameters.
+func (p *DNSSECPolicy) Validate() error {
+	if !slices.Contains(dnssecAlgorithms, p.Algorithm) {
+		return fmt.Errorf("unsupported DNSSEC algorithm %s", p.Algorithm)
+	}
+
+	switch p.KeyPolicy {
+	case dnssecKeyPolicyCSK, dnssecKeyPolicyKSKZSK:
+	default:
+		return fmt.Errorf("unsupported DNSSEC key policy %s", p.KeyPolicy)
+	}
+
+	if p.NSEC3Param == "" {
+		return nil
+	}
+
+	fields := strings.Fields(p.NSEC3Param)
+	if len(fields) != 4 {
+		return fmt.Errorf("invalid NSEC3 parameters %s: expected hash algorithm, flags, iterations and salt", p.NSEC3Param)
+	}
+
+	if fields[0] != "1" {
+		return fmt.Errorf("invalid NSEC3 hash algorithm %s", fields[0])
+	}
+
+	if fields[1] != "0" && fields[1] != "1" {
+		return fmt.Errorf("invalid NSEC3 flags %s", fields[1])
+	}
+
+	_, err := strconv.ParseUint(fields[2], 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid NSEC3 iterations %s: %w", fields[2], err)
+	}
+
+	if fields[3] != "-" {
+		salt, err := hex.DecodeString(fields[3])
+		if err != nil || len(salt) > 255 {
+			return fmt.Errorf("invalid NSEC3 salt %s", fields[3])
+		}
+	}
+
+	return nil
+}
+
+// KeyTypes returns the key types required by the key policy.
+func (p *DNSSECPolicy) KeyTypes() []string {
+	if p.KeyPolicy == dnssecKeyPolicyKSKZSK {
+		return []string{pdnsapi.KeyTypeKSK, pdnsapi.KeyTypeZSK}
+	}
+
+	return []string{pdnsapi.KeyTypeCSK}
+}
+
+// isDNSSECEnabled reports whether the zone should be signed, the annotation overrides the config key.
+func (r *ZoneReconciler) isDNSSECEnabled(zone *pdnsv1.Zone) (bool, error) {
+	value, found := zone.Annotations[AnnotationDNSSEC]
+	if !found {
+		value = r.GetValueOrDefault(KeyDNSSEC, "false")
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %s for DNSSEC: %w", value, err)
+	}
+
+	return enabled, nil
+}
+
+// dnssecPolicy returns the signing policy of the zone, annotations override the config keys.
+func (r *ZoneReconciler) dnssecPolicy(zone *pdnsv1.Zone) DNSSECPolicy {
+	algorithm, found := zone.Annotations[AnnotationDNSSECAlgorithm]
+	if !found {
+		algorithm = r.GetValueOrDefault(KeyDNSSECAlgorithm, defaultDNSSECAlgorithm)
+	}
+
+	keyPolicy, found := zone.Annotations[AnnotationDNSSECKeyPolicy]
+	if !found {
+		keyPolicy = r.GetValueOrDefault(KeyDNSSECKeyPolicy, defaultDNSSECKeyPolicy)
+	}
+
+	nsec3Param, found := zone.Annotations[AnnotationDNSSECNSEC3Param]
+	if !found {
+		nsec3Param = r.GetValueOrDefault(KeyDNSSECNSEC3Param, "")
+	}
+
+	return DNSSECPolicy{
+		Algorithm:  strings.ToLower(strings.TrimSpace(algorithm)),
+		KeyPolicy:  strings.ToLower(strings.TrimSpace(keyPolicy)),
+		NSEC3Param: strings.Join(strings.Fields(nsec3Param), " "),
+	}
+}
+
+// dsRRsetName returns the name of the RRset that publishes the DS records of a zone in its parent.
+func dsRRsetName(zone *pdnsv1.Zone) string {
+	return "ds." + zone.Name
+}
+
+// dnssecParentZoneRef returns a reference to the zone that delegates to the zone, either the organization zone or the
+// management domain zone.
+func dnssecParentZoneRef(zone *pdnsv1.Zone) (pdnsv1.ZoneRef, error) {
+	parentZone, found := zone.Annotations[AnnotationParentZone]
+	if found {
+		return pdnsv1.ZoneRef{Name: parentZone, Kind: "Zone"}, nil
+	}
+
+	parent, found := zone.Annotations[AnnotationManagementDomain]
+	if !found {
+		_, parent, found = strings.Cut(zone.Name, ".")
+	}
+
+	if !found || parent == "" {
+		return pdnsv1.ZoneRef{}, fmt.Errorf("zone %s has no parent zone", zone.Name)
+	}
+
+	zoneRef := pdnsv1.ZoneRef{
+		Name: parent,
+		Kind: dnssecParentZoneKind,
+	}
+
+	return zoneRef, nil
+}
+
+// dsRecords returns the SHA-256 DS records of the active key signing keys.
+func dsRecords(cryptokeys []pdnsapi.Cryptokey) []string {
+	var records []string
+
+	for _, cryptokey := range cryptokeys {
+		if !cryptokey.Active || cryptokey.KeyType == pdnsapi.KeyTypeZSK {
+			continue
+		}
+
+		for _, ds := range cryptokey.DS {
+			fields := strings.Fields(ds)
+			if len(fields) == 4 && fields[2] == dsDigestTypeSHA256 {
+				records = append(records, ds)
+			}
+		}
+	}
+
+	slices.Sort(records)
+
+	return records
+}
+
+// formatCryptokeys summarizes the keys of a zone for status messages.
+func formatCryptokeys(cryptokeys []pdnsapi.Cryptokey) string {
+	var keys []string
+
+	for _, cryptokey := range cryptokeys {
+		state := "inactive"
+		if cryptokey.Active {
+			state = "active"
+		}
+
+		keys = append(keys, fmt.Sprintf("%s %d %s %s", cryptokey.KeyType, cryptokey.ID, cryptokey.Algorithm, state))
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+// reconcileDNSSEC signs the zone according to its policy and publishes the DS records in the parent zone.
+func (r *ZoneReconciler) reconcileDNSSEC(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, ips *PDNSIPs) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	enabled, err := r.isDNSSECEnabled(zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !enabled {
+		return r.deleteDNSSEC(ctx, zone, cluster, ips)
+	}
+
+	policy := r.dnssecPolicy(zone)
+
+	err = policy.Validate()
+	if err != nil {
+		logger.Info("ignoring invalid DNSSEC policy", "zone", zone.Name, "err", err)
+
+		return ctrl.Result{}, r.setDNSSECCondition(ctx, cluster, metav1.ConditionFalse, InvalidDNSSECPolicyReason, err.Error())
+	}
+
+	_, found := zone.Annotations[AnnotationDNSSECUnsignAfter]
+	if found {
+		patch := client.MergeFrom(zone.DeepCopy())
+		delete(zone.Annotations, AnnotationDNSSECUnsignAfter)
+
+		err := r.Patch(ctx, zone, patch)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	parentZoneRef, err := dnssecParentZoneRef(zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cryptokeys, err := r.reconcileCryptokeys(ctx, pdnsClient, zone, &policy)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	pdnsZone, err := pdnsClient.GetZone(ctx, zone.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if pdnsZone.NSEC3Param != policy.NSEC3Param {
+		err := pdnsClient.SetNSEC3Param(ctx, zone.Name, policy.NSEC3Param)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		err = pdnsClient.RectifyZone(ctx, zone.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Updated zone NSEC3 parameters", "zone", zone.Name, "nsec3param", policy.NSEC3Param)
+	}
+
+	records := dsRecords(cryptokeys)
+	if len(records) == 0 {
+		return ctrl.Result{}, fmt.Errorf("no DS records for zone %s", zone.Name)
+	}
+
+	backend, err := r.parentBackend(ctx, zone, parentZoneRef)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	dsset := BackendRecord{
+		ID: dsRRsetName(zone),
+		Spec: pdnsv1.RRsetSpec{
+			Type:    "DS",
+			TTL:     uint32(dsTTL),
+			Name:    zone.Name + ".",
+			Records: records,
+			ZoneRef: parentZoneRef,
+		},
+	}
+
+	operationResult, err := backend.EnsureRecord(ctx, zone, dsset)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Reconciled Zone DS RRSet", "zone", zone.Name, "parent", parentZoneRef.Name, "operationResult", operationResult)
+
+	message := fmt.Sprintf("Signed with %s, DS published in %s", formatCryptokeys(cryptokeys), parentZoneRef.Name)
+
+	return ctrl.Result{}, r.setDNSSECCondition(ctx, cluster, metav1.ConditionTrue, DNSSECSignedReason, message)
+}
+
+// reconcileCryptokeys creates the active keys required by the policy and returns all keys of the zone.
+//
+// Keys that do not match the policy are left in place, algorithm and key rollovers are not automated.
+func (r *ZoneReconciler) reconcileCryptokeys(ctx context.Context, pdnsClient *pdnsapi.Client, zone *pdnsv1.Zone, policy *DNSSECPolicy) ([]pdnsapi.Cryptokey, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	cryptokeys, err := pdnsClient.ListCryptokeys(ctx, zone.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	created := false
+
+	for _, keyType := range policy.KeyTypes() {
+		found := slices.ContainsFunc(cryptokeys, func(cryptokey pdnsapi.Cryptokey) bool {
+			return cryptokey.Active && cryptokey.KeyType == keyType && cryptokey.Algorithm == policy.Algorithm
+		})
+		if found {
+			continue
+		}
+
+		cryptokey := pdnsapi.Cryptokey{
+			KeyType:   keyType,
+			Active:    true,
+			Algorithm: policy.Algorithm,
+		}
+
+		newKey, err := pdnsClient.CreateCryptokey(ctx, zone.Name, &cryptokey)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Info("Created zone cryptokey", "zone", zone.Name, "keyType", keyType, "id", newKey.ID)
+
+		created = true
+	}
+
+	if !created {
+		return cryptokeys, nil
+	}
+
+	return pdnsClient.ListCryptokeys(ctx, zone.Name)
+}
+
+// deleteDNSSEC unsigns a zone without breaking validation for resolvers.
+//
+// The DS RRset is removed from the parent first, the zone stays signed until cached DS records have expired and
+// only then are the keys removed. The deadline is kept in an annotation on the zone.
+func (r *ZoneReconciler) deleteDNSSEC(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, ips *PDNSIPs) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	unsignAfter, unsigning := zone.Annotations[AnnotationDNSSECUnsignAfter]
+	if !unsigning && meta.FindStatusCondition(cluster.Status.Conditions, DNSSECReadyCondition) == nil {
+		return ctrl.Result{}, nil
+	}
+
+	parentZoneRef, _ := dnssecParentZoneRef(zone)
+
+	backend, err := r.parentBackend(ctx, zone, parentZoneRef)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The DS RRset is found by its id since the parent zone may have changed, the zone is unsigned once it is gone.
+	deleted, err := backend.DeleteRecord(ctx, zone, BackendRecord{ID: dsRRsetName(zone)})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if deleted {
+		logger.Info("Deleted Zone DS RRSet", "zone", zone.Name)
+
+		err = r.setDNSSECCondition(ctx, cluster, metav1.ConditionFalse, DNSSECUnsigningReason, "Removing DS records from parent zone")
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: dnssecUnsignRequeueDelay}, nil
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cryptokeys, err := pdnsClient.ListCryptokeys(ctx, zone.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(cryptokeys) > 0 && !unsigning {
+		deadline := time.Now().Add(dsTTL * time.Second).UTC()
+
+		patch := client.MergeFrom(zone.DeepCopy())
+		metav1.SetMetaDataAnnotation(&zone.ObjectMeta, AnnotationDNSSECUnsignAfter, deadline.Format(time.RFC3339))
+
+		err := r.Patch(ctx, zone, patch)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		message := fmt.Sprintf("Waiting until %s for cached DS records to expire", deadline.Format(time.RFC3339))
+
+		err = r.setDNSSECCondition(ctx, cluster, metav1.ConditionFalse, DNSSECUnsigningReason, message)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: time.Until(deadline)}, nil
+	}
+
+	if len(cryptokeys) > 0 {
+		deadline, err := time.Parse(time.RFC3339, unsignAfter)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("invalid value %s for annotation %s: %w", unsignAfter, AnnotationDNSSECUnsignAfter, err)
+		}
+
+		if time.Now().Before(deadline) {
+			return ctrl.Result{RequeueAfter: time.Until(deadline)}, nil
+		}
+
+		for _, cryptokey := range cryptokeys {
+			err := pdnsClient.DeleteCryptokey(ctx, zone.Name, cryptokey.ID)
+			if pdnsapi.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		pdnsZone, err := pdnsClient.GetZone(ctx, zone.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if pdnsZone.NSEC3Param != "" {
+			err := pdnsClient.SetNSEC3Param(ctx, zone.Name, "")
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		logger.Info("Unsigned zone", "zone", zone.Name)
+	}
+
+	if unsigning {
+		patch := client.MergeFrom(zone.DeepCopy())
+		delete(zone.Annotations, AnnotationDNSSECUnsignAfter)
+
+		err := r.Patch(ctx, zone, patch)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	patch := client.MergeFrom(cluster.DeepCopy())
+	if meta.RemoveStatusCondition(&cluster.Status.Conditions, DNSSECReadyCondition) {
+		return ctrl.Result{}, r.Status().Patch(ctx, cluster, patch)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// setDNSSECCondition patches the DNSSEC condition of the cluster status.
+func (r *ZoneReconciler) setDNSSECCondition(ctx context.Context, cluster *dockyardsv1.Cluster, status metav1.ConditionStatus, reason, message string) error {
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	condition := metav1.Condition{
+		Type:               DNSSECReadyCondition,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: cluster.Generation,
+	}
+
+	if !meta.SetStatusCondition(&cluster.Status.Conditions, condition) {
+		return nil
+	}
+
+	return r.Status().Patch(ctx, cluster, patch)
+}
@@ -0,0 +1,230 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDNSSECPolicy(t *testing.T) {
+	tt := []struct {
+		name        string
+		config      map[string]string
+		annotations map[string]string
+		expected    DNSSECPolicy
+		invalid     bool
+	}{
+		{
+			name: "test defaults",
+			expected: DNSSECPolicy{
+				Algorithm: "ecdsap256sha256",
+				KeyPolicy: "csk",
+			},
+		},
+		{
+			name: "test config",
+			config: map[string]string{
+				string(KeyDNSSECAlgorithm):  "ED25519",
+				string(KeyDNSSECKeyPolicy):  "ksk-zsk",
+				string(KeyDNSSECNSEC3Param): "1  0 0 -",
+			},
+			expected: DNSSECPolicy{
+				Algorithm:  "ed25519",
+				KeyPolicy:  "ksk-zsk",
+				NSEC3Param: "1 0 0 -",
+			},
+		},
+		{
+			name: "test annotation overrides config",
+			config: map[string]string{
+				string(KeyDNSSECNSEC3Param): "1 0 0 -",
+			},
+			annotations: map[string]string{
+				AnnotationDNSSECAlgorithm:  "rsasha256",
+				AnnotationDNSSECNSEC3Param: "",
+			},
+			expected: DNSSECPolicy{
+				Algorithm: "rsasha256",
+				KeyPolicy: "csk",
+			},
+		},
+		{
+			name: "test nsec3 salt",
+			annotations: map[string]string{
+				AnnotationDNSSECNSEC3Param: "1 1 10 ab12",
+			},
+			expected: DNSSECPolicy{
+				Algorithm:  "ecdsap256sha256",
+				KeyPolicy:  "csk",
+				NSEC3Param: "1 1 10 ab12",
+			},
+		},
+		{
+			name: "test unsupported algorithm",
+			annotations: map[string]string{
+				AnnotationDNSSECAlgorithm: "rsasha1",
+			},
+			invalid: true,
+		},
+		{
+			name: "test unsupported key policy",
+			annotations: map[string]string{
+				AnnotationDNSSECKeyPolicy: "zsk",
+			},
+			invalid: true,
+		},
+		{
+			name: "test invalid nsec3 hash algorithm",
+			annotations: map[string]string{
+				AnnotationDNSSECNSEC3Param: "2 0 0 -",
+			},
+			invalid: true,
+		},
+		{
+			name: "test invalid nsec3 salt",
+			annotations: map[string]string{
+				AnnotationDNSSECNSEC3Param: "1 0 0 salt",
+			},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := ZoneReconciler{
+				ConfigManager: dyconfig.NewFakeConfigManager(tc.config),
+			}
+
+			zone := pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "org-cluster.test.com",
+					Annotations: tc.annotations,
+				},
+			}
+
+			policy := r.dnssecPolicy(&zone)
+
+			err := policy.Validate()
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected validation error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(policy, tc.expected) {
+				t.Error(cmp.Diff(tc.expected, policy))
+			}
+		})
+	}
+}
+
+func TestDSRecords(t *testing.T) {
+	cryptokeys := []pdnsapi.Cryptokey{
+		{
+			ID:      2,
+			KeyType: pdnsapi.KeyTypeKSK,
+			Active:  true,
+			DS: []string{
+				"12345 13 1 abcd",
+				"12345 13 2 ef01",
+			},
+		},
+		{
+			ID:      3,
+			KeyType: pdnsapi.KeyTypeZSK,
+			Active:  true,
+			DS: []string{
+				"23456 13 2 2345",
+			},
+		},
+		{
+			ID:      4,
+			KeyType: pdnsapi.KeyTypeCSK,
+			Active:  false,
+			DS: []string{
+				"34567 13 2 6789",
+			},
+		},
+	}
+
+	expected := []string{
+		"12345 13 2 ef01",
+	}
+
+	actual := dsRecords(cryptokeys)
+	if !cmp.Equal(actual, expected) {
+		t.Error(cmp.Diff(expected, actual))
+	}
+}
+
+func TestDNSSECParentZoneRef(t *testing.T) {
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org-cluster.test.com",
+		},
+	}
+
+	expected := pdnsv1.ZoneRef{
+		Name: "test.com",
+		Kind: "ClusterZone",
+	}
+
+	actual, err := dnssecParentZoneRef(&zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(actual, expected) {
+		t.Error(cmp.Diff(expected, actual))
+	}
+}
+
+func TestDNSSECParentZoneRefOrganization(t *testing.T) {
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster.org.test.com",
+			Annotations: map[string]string{
+				AnnotationManagementDomain: "test.com",
+				AnnotationParentZone:       "org.test.com",
+			},
+		},
+	}
+
+	expected := pdnsv1.ZoneRef{
+		Name: "org.test.com",
+		Kind: "Zone",
+	}
+
+	actual, err := dnssecParentZoneRef(&zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(actual, expected) {
+		t.Error(cmp.Diff(expected, actual))
+	}
+}
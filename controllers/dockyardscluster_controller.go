@@ -16,17 +16,21 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
 	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
 	"github.com/sudoswedenab/dockyards-backend/api/apiutil"
 	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
 	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
 // +kubebuilder:rbac:groups=dockyards.io,resources=clusters/status,verbs=patch
@@ -40,6 +44,9 @@ import (
 type DockyardsClusterReconciler struct {
 	client.Client
 	*dyconfig.ConfigManager
+
+	// Shard limits the reconciler to the clusters and zones of the instance, see Shard.
+	Shard *Shard
 }
 
 // Reconcile ensures a DNS zone exists for an owned cluster that is not being deleted.
@@ -48,6 +55,9 @@ func (r *DockyardsClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	var cluster dockyardsv1.Cluster
 	err := r.Get(ctx, req.NamespacedName, &cluster)
+	if apierrors.IsNotFound(err) {
+		reportPaused(dockyardsv1.ClusterKind, req.NamespacedName, false)
+	}
 	if err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
@@ -67,6 +77,12 @@ func (r *DockyardsClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, nil
 	}
 
+	if !r.Shard.MatchesCluster(&cluster, ownerOrganization) {
+		return ctrl.Result{}, r.Shard.releaseZones(ctx, r.Client, r.ConfigManager, cluster.Namespace, client.MatchingLabels{
+			dockyardsv1.LabelClusterName: cluster.Name,
+		})
+	}
+
 	return r.reconcileDNSZone(ctx, &cluster, ownerOrganization)
 }
 
@@ -74,19 +90,62 @@ func (r *DockyardsClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 func (r *DockyardsClusterReconciler) reconcileDNSZone(ctx context.Context, cluster *dockyardsv1.Cluster, ownerOrganization *dockyardsv1.Organization) (ctrl.Result, error) {
 	logger := ctrl.LoggerFrom(ctx)
 
-	managementDomain, found := r.GetValueForKey(KeyManagementDomain)
-	if !found {
-		return ctrl.Result{}, fmt.Errorf("config key `%s` not found", KeyManagementDomain)
+	if ownerOrganization == nil {
+		return ctrl.Result{}, fmt.Errorf("cluster %s has no owner organization", cluster.Name)
 	}
-	if managementDomain == "" {
-		return ctrl.Result{}, fmt.Errorf("no value for config key `%s`", KeyManagementDomain)
+
+	naming, err := resolveClusterZoneNaming(r.ConfigManager, cluster, ownerOrganization)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	if ownerOrganization == nil {
-		return ctrl.Result{}, fmt.Errorf("cluster %s has no owner organization", cluster.Name)
+	zoneName := naming.Name
+
+	// The pause is checked before the zone is claimed, so that a paused zone is never relabelled for this shard.
+	var current pdnsv1.Zone
+	err = r.Get(ctx, client.ObjectKey{Name: zoneName, Namespace: cluster.Namespace}, &current)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+
+	reason, err := pauseReason(r.ConfigManager, cluster, &current)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	reportPaused(dockyardsv1.ClusterKind, client.ObjectKeyFromObject(cluster), reason != "")
+
+	err = setPausedCondition(ctx, r.Client, cluster, reason)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	zoneName := ownerOrganization.Name + "-" + cluster.GetName() + "." + managementDomain
+	if reason != "" {
+		logger.Info("Skipping paused cluster", "cluster", cluster.Name, "reason", reason)
+
+		return ctrl.Result{RequeueAfter: pausedRequeueDelay}, nil
+	}
+
+	claimed, err := r.Shard.claimZone(ctx, r.Client, client.ObjectKey{Name: zoneName, Namespace: cluster.Namespace})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !claimed {
+		return ctrl.Result{}, nil
+	}
+
+	pdnsBackend, err := recordedPDNSBackend(ctx, r.Client, r.ConfigManager, &current, ownerOrganization, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	annotations := zoneAnnotationValues(cluster, ownerOrganization)
+
+	transferPolicy, err := parseZoneTransferPolicy(annotations, r.ConfigManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
 	zone := pdnsv1.Zone{
 		ObjectMeta: metav1.ObjectMeta{
@@ -95,37 +154,96 @@ func (r *DockyardsClusterReconciler) reconcileDNSZone(ctx context.Context, clust
 		},
 	}
 
-	operationResult, err := controllerutil.CreateOrPatch(ctx, r.Client, &zone, func() error {
-		zone.Labels = map[string]string{
-			dockyardsv1.LabelClusterName: cluster.Name,
-		}
-		zone.OwnerReferences = []metav1.OwnerReference{
-			{
-				APIVersion:         dockyardsv1.GroupVersion.String(),
-				Kind:               dockyardsv1.ClusterKind,
-				Name:               cluster.Name,
-				UID:                cluster.UID,
-				Controller:         ptr.To(true),
-				BlockOwnerDeletion: ptr.To(true),
-			},
-		}
+	ac := zoneApply(zoneName, cluster.Namespace, pdnsv1.ZoneSpec{
+		Kind:        transferPolicy.Kind,
+		Nameservers: transferPolicy.ZoneNameservers(zoneName),
+	})
 
-		zone.Spec = pdnsv1.ZoneSpec{
-			Kind: "Native",
-			Nameservers: []string{
-				"ns1." + zoneName,
-			},
-		}
+	ac.WithLabels(map[string]string{
+		dockyardsv1.LabelClusterName: cluster.Name,
+	})
 
-		return nil
+	r.Shard.applyLabel(ac.ObjectMetaApplyConfiguration)
+
+	// Annotations that are no longer set on the cluster or organization are removed since they are no longer applied.
+	ac.WithAnnotations(annotations)
+	ac.WithAnnotations(map[string]string{
+		AnnotationManagementDomain: naming.ManagementDomain,
+		AnnotationPDNSBackend:      pdnsBackend,
 	})
+
+	if naming.ParentZone != "" {
+		ac.WithAnnotations(map[string]string{
+			AnnotationParentZone: naming.ParentZone,
+		})
+	}
+
+	ac.WithOwnerReferences(ownerReferenceApplyConfiguration(metav1.OwnerReference{
+		APIVersion:         dockyardsv1.GroupVersion.String(),
+		Kind:               dockyardsv1.ClusterKind,
+		Name:               cluster.Name,
+		UID:                cluster.UID,
+		Controller:         ptr.To(true),
+		BlockOwnerDeletion: ptr.To(true),
+	}))
+
+	operationResult, err := r.Shard.applyZone(ctx, r.Client, &zone, ac)
+	if errors.Is(err, errClaimLost) {
+		return ctrl.Result{}, nil
+	}
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// The current zone of the cluster is never migrated, even if a migration away from it was started earlier.
+	migrating := metav1.HasAnnotation(zone.ObjectMeta, AnnotationMigrateTo) || metav1.HasAnnotation(zone.ObjectMeta, AnnotationMigrationCopied)
+	if migrating && !metav1.HasAnnotation(zone.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) {
+		patch := client.MergeFrom(zone.DeepCopy())
+
+		delete(zone.Annotations, AnnotationMigrateTo)
+		delete(zone.Annotations, AnnotationMigrationCopied)
+
+		err := r.Patch(ctx, &zone, patch)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	logger.Info("Reconciled DNS Zone", "cluster", cluster.Name, "operationResult", operationResult)
 
-	return ctrl.Result{}, nil
+	err = r.reconcileZoneMigration(ctx, cluster, &zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return r.reconcileCustomDomains(ctx, cluster, &zone, transferPolicy)
+}
+
+// clustersForOrganization enqueues the clusters in the namespace of an organization.
+func (r *DockyardsClusterReconciler) clustersForOrganization(ctx context.Context, obj client.Object) []ctrl.Request {
+	organization, ok := obj.(*dockyardsv1.Organization)
+	if !ok {
+		return nil
+	}
+
+	if organization.Spec.NamespaceRef == nil {
+		return nil
+	}
+
+	var clusterList dockyardsv1.ClusterList
+	err := r.List(ctx, &clusterList, client.InNamespace(organization.Spec.NamespaceRef.Name))
+	if err != nil {
+		return nil
+	}
+
+	requests := make([]ctrl.Request, len(clusterList.Items))
+	for i, cluster := range clusterList.Items {
+		requests[i] = ctrl.Request{
+			NamespacedName: client.ObjectKeyFromObject(&cluster),
+		}
+	}
+
+	return requests
 }
 
 // SetupWithManager configures the controller runtime to manage cluster and zone resources.
@@ -137,7 +255,8 @@ func (r *DockyardsClusterReconciler) SetupWithManager(manager ctrl.Manager) erro
 
 	err := ctrl.NewControllerManagedBy(manager).
 		For(&dockyardsv1.Cluster{}).
-		Owns(&pdnsv1.Zone{}).
+		Owns(&pdnsv1.Zone{}, builder.WithPredicates(r.Shard.ClaimablePredicate())).
+		Watches(&dockyardsv1.Organization{}, handler.EnqueueRequestsFromMapFunc(r.clustersForOrganization)).
 		Complete(r)
 	if err != nil {
 		return err
@@ -145,3 +264,26 @@ func (r *DockyardsClusterReconciler) SetupWithManager(manager ctrl.Manager) erro
 
 	return nil
 }
+
+// zoneAnnotationValues returns the zone annotations of the cluster, or else its organization.
+//
+// An empty value falls back to the organization, except for the annotations in emptyZoneAnnotations where an empty
+// value is copied so that it overrides the config key.
+func zoneAnnotationValues(cluster *dockyardsv1.Cluster, organization *dockyardsv1.Organization) map[string]string {
+	annotations := make(map[string]string)
+
+	for _, key := range zoneAnnotations {
+		allowEmpty := slices.Contains(emptyZoneAnnotations, key)
+
+		value, found := cluster.Annotations[key]
+		if value == "" && (!found || !allowEmpty) {
+			value, found = organization.Annotations[key]
+		}
+
+		if value != "" || found && allowEmpty {
+			annotations[key] = value
+		}
+	}
+
+	return annotations
+}
@@ -141,7 +141,7 @@ func TestClusterReconciler(t *testing.T) {
 	}
 
 	t.Run("test cluster reconciliation", func(t *testing.T) {
-		r := DockyardsClusterReconciler{c, dockyardsConfigManager}
+		r := DockyardsClusterReconciler{Client: c, ConfigManager: dockyardsConfigManager}
 		_, err = r.reconcileDNSZone(ctx, &cluster, &organization)
 		if err != nil {
 			t.Fatal(err)
@@ -196,7 +196,7 @@ func TestClusterReconciler(t *testing.T) {
 		}
 
 		externalIP := "1.2.3.4"
-		z := ZoneReconciler{c, dockyardsConfigManager}
+		z := ZoneReconciler{Client: c, ConfigManager: dockyardsConfigManager}
 		_, err = z.reconcileRRsets(ctx, &zone, externalIP)
 		if err != nil {
 			t.Fatal(err)
@@ -349,7 +349,16 @@ func TestClusterReconciler(t *testing.T) {
 		internalIPs := []string{
 			"5.6.7.8",
 		}
-		_, err = z.reconcileExternalDNS(ctx, &zone, &cluster, internalIPs)
+		ips := PDNSIPs{
+			APIIPs: internalIPs,
+			PDNSBackend: &PDNSBackend{
+				Name:          defaultPDNSBackendName,
+				PDNSName:      secretName,
+				PDNSNamespace: pdnsNamespace,
+				Default:       true,
+			},
+		}
+		_, err = z.reconcileExternalDNS(ctx, &zone, &cluster, &ips)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -419,3 +428,82 @@ func TestClusterReconciler(t *testing.T) {
 		}
 	})
 }
+
+func TestZoneAnnotationValues(t *testing.T) {
+	tt := []struct {
+		name                    string
+		clusterAnnotations      map[string]string
+		organizationAnnotations map[string]string
+		expected                map[string]string
+	}{
+		{
+			name: "test cluster overrides organization",
+			clusterAnnotations: map[string]string{
+				AnnotationZoneKind: "Master",
+			},
+			organizationAnnotations: map[string]string{
+				AnnotationZoneKind:   "Native",
+				AnnotationCAAIssuers: "letsencrypt.org",
+			},
+			expected: map[string]string{
+				AnnotationZoneKind:   "Master",
+				AnnotationCAAIssuers: "letsencrypt.org",
+			},
+		},
+		{
+			name: "test empty value falls back to organization",
+			clusterAnnotations: map[string]string{
+				AnnotationZoneKind: "",
+			},
+			organizationAnnotations: map[string]string{
+				AnnotationZoneKind: "Master",
+			},
+			expected: map[string]string{
+				AnnotationZoneKind: "Master",
+			},
+		},
+		{
+			name: "test empty caa issuers",
+			clusterAnnotations: map[string]string{
+				AnnotationCAAIssuers: "",
+				AnnotationZoneKind:   "",
+			},
+			organizationAnnotations: map[string]string{
+				AnnotationCAAIssuers: "letsencrypt.org",
+			},
+			expected: map[string]string{
+				AnnotationCAAIssuers: "",
+			},
+		},
+		{
+			name: "test empty caa iodef on organization",
+			organizationAnnotations: map[string]string{
+				AnnotationCAAIodef: "",
+			},
+			expected: map[string]string{
+				AnnotationCAAIodef: "",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := dockyardsv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.clusterAnnotations,
+				},
+			}
+
+			organization := dockyardsv1.Organization{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.organizationAnnotations,
+				},
+			}
+
+			actual := zoneAnnotationValues(&cluster, &organization)
+			if !cmp.Equal(actual, tc.expected) {
+				t.Error(cmp.Diff(tc.expected, actual))
+			}
+		})
+	}
+}
@@ -0,0 +1,549 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/prometheus/client_golang/prometheus"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=zones;rrsets,verbs=get;list
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=clusterzones,verbs=get;list;watch
+
+// driftFindings counts the differences found by the last drift audit per zone and kind.
+var driftFindings = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "dockyards_pdns_drift",
+		Help: "Differences between PowerDNS and the desired state found by the last drift audit.",
+	},
+	[]string{"namespace", "zone", "kind"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(driftFindings)
+}
+
+// DriftAuditPolicy describes how differences between PowerDNS and the desired state are handled.
+type DriftAuditPolicy struct {
+	Mode     string
+	Interval time.Duration
+}
+
+// parseDriftAuditPolicy reads the drift audit mode and interval from the config keys.
+func parseDriftAuditPolicy(configManager *dyconfig.ConfigManager) (*DriftAuditPolicy, error) {
+	policy := DriftAuditPolicy{
+		Mode:     configManager.GetValueOrDefault(KeyDriftAudit, driftAuditDisabled),
+		Interval: defaultDriftAuditInterval,
+	}
+
+	switch policy.Mode {
+	case driftAuditDisabled, driftAuditReport, driftAuditRepair:
+	default:
+		return nil, fmt.Errorf("invalid value for config key `%s`: unsupported mode %s", KeyDriftAudit, policy.Mode)
+	}
+
+	// The desired state is read from RRset resources, which the api backend does not create.
+	backend, err := parseBackend(configManager)
+	if err == nil && backend == backendAPI && policy.Mode != driftAuditDisabled {
+		return nil, fmt.Errorf("invalid value for config key `%s`: not supported by the %s backend", KeyDriftAudit, backendAPI)
+	}
+
+	value, found := configManager.GetValueForKey(KeyDriftAuditInterval)
+	if found {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %s", KeyDriftAuditInterval, value)
+		}
+
+		policy.Interval = interval
+	}
+
+	return &policy, nil
+}
+
+// Drift is a difference between an RRset served by PowerDNS and the desired state of its zone.
+type Drift struct {
+	Kind    string
+	Name    string
+	Type    string
+	Message string
+
+	// Repair is the change that restores the desired state.
+	Repair *pdnsapi.RRset
+}
+
+// externalDNSOwnedNames returns the names that ExternalDNS claims through its TXT ownership records.
+//
+// Both the TXT records themselves and the names they claim are returned, including the names claimed with the
+// `<type>-` prefix of newer ExternalDNS versions.
+func externalDNSOwnedNames(served []pdnsapi.RRset) map[string]bool {
+	owned := make(map[string]bool)
+
+	for _, rrset := range served {
+		if rrset.Type != "TXT" {
+			continue
+		}
+
+		claimed := slices.ContainsFunc(rrset.Records, func(record pdnsapi.Record) bool {
+			return strings.Contains(record.Content, "heritage=external-dns")
+		})
+		if !claimed {
+			continue
+		}
+
+		owned[rrset.Name] = true
+
+		label, rest, _ := strings.Cut(rrset.Name, ".")
+
+		prefix, name, found := strings.Cut(label, "-")
+		if found && dnsRecordTypes[strings.ToUpper(prefix)] {
+			if name == "" {
+				owned[rest] = true
+			} else {
+				owned[name+"."+rest] = true
+			}
+		}
+	}
+
+	return owned
+}
+
+// dnsRecordTypes are the record types ExternalDNS uses as prefix of its ownership records.
+var dnsRecordTypes = map[string]bool{
+	"A":     true,
+	"AAAA":  true,
+	"CNAME": true,
+	"MX":    true,
+	"NS":    true,
+	"SRV":   true,
+	"TXT":   true,
+	"CAA":   true,
+}
+
+// desiredRRset returns the RRset described by an RRset resource as the PowerDNS API would return it.
+func desiredRRset(zoneName string, spec pdnsv1.RRsetSpec) pdnsapi.RRset {
+	rrset := pdnsapi.RRset{
+		Name:    rrsetFQDN(spec.Name, zoneName),
+		Type:    spec.Type,
+		TTL:     spec.TTL,
+		Records: make([]pdnsapi.Record, len(spec.Records)),
+	}
+
+	for i, content := range spec.Records {
+		rrset.Records[i] = pdnsapi.Record{Content: content}
+	}
+
+	return rrset
+}
+
+// recordContents returns the sorted contents of the enabled records of an RRset.
+func recordContents(rrset pdnsapi.RRset) []string {
+	var contents []string
+
+	for _, record := range rrset.Records {
+		if !record.Disabled {
+			contents = append(contents, strings.TrimSpace(record.Content))
+		}
+	}
+
+	slices.Sort(contents)
+
+	return contents
+}
+
+// auditZone compares the RRsets PowerDNS serves for a zone with its nameservers and RRset resources.
+//
+// SOA records are skipped since PowerDNS maintains the serial, RRset resources that skip remediation are not
+// compared and names claimed by ExternalDNS are never unmanaged.
+func auditZone(served *pdnsapi.Zone, zone *pdnsv1.Zone, rrsets []pdnsv1.RRset) []Drift {
+	desired := make(map[string]pdnsapi.RRset)
+	known := make(map[string]bool)
+
+	if len(zone.Spec.Nameservers) > 0 {
+		nameservers := pdnsapi.RRset{
+			Name: zone.Name + ".",
+			Type: "NS",
+		}
+
+		for _, nameserver := range zone.Spec.Nameservers {
+			nameservers.Records = append(nameservers.Records, pdnsapi.Record{Content: pdnsapi.CanonicalName(nameserver)})
+		}
+
+		desired[rrsetKey(nameservers.Name, nameservers.Type)] = nameservers
+	}
+
+	for _, rrset := range rrsets {
+		if rrset.Spec.ZoneRef.Name != zone.Name || rrset.Spec.Type == "SOA" {
+			continue
+		}
+
+		expected := desiredRRset(zone.Name, rrset.Spec)
+		key := rrsetKey(expected.Name, expected.Type)
+
+		known[key] = true
+
+		if metav1.HasAnnotation(rrset.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) {
+			continue
+		}
+
+		desired[key] = expected
+	}
+
+	actual := make(map[string]pdnsapi.RRset)
+	for _, rrset := range served.RRsets {
+		actual[rrsetKey(rrset.Name, rrset.Type)] = rrset
+	}
+
+	var drifts []Drift
+
+	for key, expected := range desired {
+		repair := expected
+		repair.ChangeType = pdnsapi.ChangeTypeReplace
+
+		rrset, found := actual[key]
+		if repair.TTL == 0 {
+			repair.TTL = zoneTTL
+			if found {
+				repair.TTL = rrset.TTL
+			}
+		}
+
+		if !found {
+			drifts = append(drifts, Drift{
+				Kind:    DriftMissingRecord,
+				Name:    expected.Name,
+				Type:    expected.Type,
+				Message: "not served",
+				Repair:  &repair,
+			})
+
+			continue
+		}
+
+		expectedContents := recordContents(expected)
+		actualContents := recordContents(rrset)
+
+		if !slices.Equal(expectedContents, actualContents) {
+			drifts = append(drifts, Drift{
+				Kind:    DriftRecordMismatch,
+				Name:    expected.Name,
+				Type:    expected.Type,
+				Message: fmt.Sprintf("serving %q, expected %q", strings.Join(actualContents, ","), strings.Join(expectedContents, ",")),
+				Repair:  &repair,
+			})
+
+			continue
+		}
+
+		if expected.TTL != 0 && rrset.TTL != expected.TTL {
+			drifts = append(drifts, Drift{
+				Kind:    DriftRecordMismatch,
+				Name:    expected.Name,
+				Type:    expected.Type,
+				Message: fmt.Sprintf("serving ttl %d, expected %d", rrset.TTL, expected.TTL),
+				Repair:  &repair,
+			})
+		}
+	}
+
+	owned := externalDNSOwnedNames(served.RRsets)
+
+	for key, rrset := range actual {
+		if rrset.Type == "SOA" || known[key] || owned[rrset.Name] {
+			continue
+		}
+
+		_, found := desired[key]
+		if found {
+			continue
+		}
+
+		drifts = append(drifts, Drift{
+			Kind:    DriftUnmanagedRecord,
+			Name:    rrset.Name,
+			Type:    rrset.Type,
+			Message: "not managed by an RRset or claimed by ExternalDNS",
+			Repair: &pdnsapi.RRset{
+				Name:       rrset.Name,
+				Type:       rrset.Type,
+				ChangeType: pdnsapi.ChangeTypeDelete,
+				Records:    []pdnsapi.Record{},
+			},
+		})
+	}
+
+	slices.SortFunc(drifts, func(a, b Drift) int {
+		return strings.Compare(rrsetKey(a.Name, a.Type), rrsetKey(b.Name, b.Type))
+	})
+
+	return drifts
+}
+
+// DriftAuditor periodically compares the zones and RRsets served by PowerDNS with the desired state.
+type DriftAuditor struct {
+	client.Client
+	*dyconfig.ConfigManager
+	Recorder record.EventRecorder
+
+	// Plan records repairs instead of sending them when set, see NewPlanClient.
+	Plan *Plan
+
+	// Shard limits the audit to the zones claimed by the instance, see Shard.
+	Shard *Shard
+}
+
+// Start runs drift audits until the context is cancelled.
+func (a *DriftAuditor) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("drift-auditor")
+	ctx = ctrl.LoggerInto(ctx, logger)
+
+	for {
+		interval := defaultDriftAuditInterval
+
+		policy, err := parseDriftAuditPolicy(a.ConfigManager)
+		if err != nil {
+			logger.Error(err, "error parsing drift audit policy")
+		}
+
+		if policy != nil {
+			interval = policy.Interval
+
+			if policy.Mode != driftAuditDisabled {
+				err := a.run(ctx, policy)
+				if err != nil {
+					logger.Error(err, "error auditing powerdns")
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// NeedLeaderElection makes sure only the leader audits PowerDNS.
+func (a *DriftAuditor) NeedLeaderElection() bool {
+	return true
+}
+
+// run connects to the API of every PowerDNS backend with the credentials of the zone reconciler and audits it.
+func (a *DriftAuditor) run(ctx context.Context, policy *DriftAuditPolicy) error {
+	backend, err := parseBackend(a.ConfigManager)
+	if err != nil {
+		return err
+	}
+
+	// The desired state is read from RRset resources, which only the CRD backend writes.
+	if backend != backendCRD {
+		ctrl.LoggerFrom(ctx).Info("Skipping drift audit for backend", "backend", backend)
+
+		return nil
+	}
+
+	z := ZoneReconciler{
+		Client:        a.Client,
+		ConfigManager: a.ConfigManager,
+		Plan:          a.Plan,
+	}
+
+	pdnsBackends, err := ParsePDNSBackends(a.ConfigManager)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for i := range pdnsBackends {
+		pdnsBackend := &pdnsBackends[i]
+
+		ips, err := z.getPDNSBackendIPs(ctx, pdnsBackend)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error auditing backend %s: %w", pdnsBackend.Name, err))
+
+			continue
+		}
+
+		pdnsClient, err := z.getPDNSClient(ctx, ips)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error auditing backend %s: %w", pdnsBackend.Name, err))
+
+			continue
+		}
+
+		err = a.audit(ctx, pdnsBackend, pdnsClient, policy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error auditing backend %s: %w", pdnsBackend.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// audit runs a single drift audit of the zones placed on the PowerDNS backend, reports the differences and repairs
+// them in repair mode. A nil backend audits every zone.
+//
+// Unknown zones are only reported in the metric and the log, they are never deleted.
+func (a *DriftAuditor) audit(ctx context.Context, pdnsBackend *PDNSBackend, pdnsClient *pdnsapi.Client, policy *DriftAuditPolicy) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var zoneList pdnsv1.ZoneList
+	err := a.List(ctx, &zoneList)
+	if err != nil {
+		return err
+	}
+
+	var clusterZoneList pdnsv1.ClusterZoneList
+	err = a.List(ctx, &clusterZoneList)
+	if err != nil {
+		return err
+	}
+
+	var rrsetList pdnsv1.RRsetList
+	err = a.List(ctx, &rrsetList)
+	if err != nil {
+		return err
+	}
+
+	served, err := pdnsClient.ListZones(ctx)
+	if err != nil {
+		return err
+	}
+
+	driftFindings.Reset()
+
+	known := make(map[string]bool)
+	for _, zone := range zoneList.Items {
+		if pdnsBackend.Serves(&zone) {
+			known[pdnsapi.CanonicalName(zone.Name)] = true
+		}
+	}
+	for _, zone := range clusterZoneList.Items {
+		known[pdnsapi.CanonicalName(zone.Name)] = true
+	}
+
+	for _, zone := range served {
+		if known[pdnsapi.CanonicalName(zone.Name)] {
+			continue
+		}
+
+		driftFindings.WithLabelValues("", zone.Name, DriftUnknownZone).Set(1)
+
+		logger.Info("Found unknown zone in PowerDNS", "zone", zone.Name)
+	}
+
+	rrsetsByNamespace := make(map[string][]pdnsv1.RRset)
+	for _, rrset := range rrsetList.Items {
+		rrsetsByNamespace[rrset.Namespace] = append(rrsetsByNamespace[rrset.Namespace], rrset)
+	}
+
+	for i := range zoneList.Items {
+		zone := &zoneList.Items[i]
+
+		if !zone.DeletionTimestamp.IsZero() || !a.Shard.Owns(zone) || !pdnsBackend.Serves(zone) || !zone.IsInExpectedStatus(1, "Succeeded") {
+			continue
+		}
+
+		if zone.Labels[LabelZoneType] == ZoneTypeACMEChallenge || zone.Annotations[AnnotationMigrateTo] != "" {
+			continue
+		}
+
+		err := a.auditZone(ctx, pdnsClient, zone, rrsetsByNamespace[zone.Namespace], policy)
+		if err != nil {
+			logger.Error(err, "error auditing zone", "zone", zone.Name, "namespace", zone.Namespace)
+		}
+	}
+
+	return nil
+}
+
+// auditZone reports the differences of a single zone and repairs them in repair mode, unless the zone is paused or
+// skips remediation.
+func (a *DriftAuditor) auditZone(ctx context.Context, pdnsClient *pdnsapi.Client, zone *pdnsv1.Zone, rrsets []pdnsv1.RRset, policy *DriftAuditPolicy) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	served, err := pdnsClient.GetZone(ctx, zone.Name)
+	if pdnsapi.IsNotFound(err) {
+		a.Recorder.Event(zone, corev1.EventTypeWarning, DriftDetectedReason, "zone not served by PowerDNS")
+		driftFindings.WithLabelValues(zone.Namespace, zone.Name, DriftMissingRecord).Inc()
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	drifts := auditZone(served, zone, rrsets)
+	if len(drifts) == 0 {
+		return nil
+	}
+
+	var repairs []pdnsapi.RRset
+
+	for _, drift := range drifts {
+		driftFindings.WithLabelValues(zone.Namespace, zone.Name, drift.Kind).Inc()
+
+		a.Recorder.Eventf(zone, corev1.EventTypeWarning, DriftDetectedReason, "%s %s %s: %s", drift.Kind, drift.Name, drift.Type, drift.Message)
+
+		logger.Info("Found drift in PowerDNS", "zone", zone.Name, "kind", drift.Kind, "name", drift.Name, "type", drift.Type, "message", drift.Message)
+
+		repairs = append(repairs, *drift.Repair)
+	}
+
+	if policy.Mode != driftAuditRepair || metav1.HasAnnotation(zone.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) {
+		return nil
+	}
+
+	reason, err := pauseReason(a.ConfigManager, zone)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		logger.Info("Skipping repair of paused zone", "zone", zone.Name, "reason", reason)
+
+		return nil
+	}
+
+	err = pdnsClient.PatchRRsets(ctx, zone.Name, repairs)
+	if err != nil {
+		return err
+	}
+
+	a.Recorder.Eventf(zone, corev1.EventTypeNormal, DriftRepairedReason, "repaired %d RRsets", len(repairs))
+
+	logger.Info("Repaired drift in PowerDNS", "zone", zone.Name, "rrsets", len(repairs))
+
+	return nil
+}
@@ -0,0 +1,353 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"github.com/sudoswedenab/dockyards-pdns/test/fakepdns"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDriftAuditPolicy(t *testing.T) {
+	tt := []struct {
+		name    string
+		config  map[string]string
+		mode    string
+		invalid bool
+	}{
+		{
+			name: "test defaults",
+			mode: driftAuditDisabled,
+		},
+		{
+			name: "test repair",
+			config: map[string]string{
+				string(KeyDriftAudit):         "repair",
+				string(KeyDriftAuditInterval): "10m",
+			},
+			mode: driftAuditRepair,
+		},
+		{
+			name: "test invalid mode",
+			config: map[string]string{
+				string(KeyDriftAudit): "fix",
+			},
+			invalid: true,
+		},
+		{
+			name: "test api backend",
+			config: map[string]string{
+				string(KeyDriftAudit): "report",
+				string(KeyBackend):    "api",
+			},
+			invalid: true,
+		},
+		{
+			name: "test disabled with api backend",
+			config: map[string]string{
+				string(KeyBackend): "api",
+			},
+			mode: driftAuditDisabled,
+		},
+		{
+			name: "test invalid interval",
+			config: map[string]string{
+				string(KeyDriftAuditInterval): "hourly",
+			},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := parseDriftAuditPolicy(dyconfig.NewFakeConfigManager(tc.config))
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if policy.Mode != tc.mode {
+				t.Errorf("expected mode %s, got %s", tc.mode, policy.Mode)
+			}
+		})
+	}
+}
+
+// driftTestZone returns a succeeded cluster zone and its RRset resources.
+func driftTestZone() (pdnsv1.Zone, []pdnsv1.RRset) {
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "org-test.test.com",
+			Namespace:  "testing",
+			Generation: 1,
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: "test",
+			},
+		},
+		Spec: pdnsv1.ZoneSpec{
+			Kind: zoneKindNative,
+			Nameservers: []string{
+				"ns1.org-test.test.com",
+			},
+		},
+		Status: pdnsv1.ZoneStatus{
+			SyncStatus:         ptr.To("Succeeded"),
+			ObservedGeneration: ptr.To(int64(1)),
+		},
+	}
+
+	rrsets := []pdnsv1.RRset{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ns1.org-test.test.com",
+				Namespace: "testing",
+			},
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "A",
+				TTL:     300,
+				Name:    "ns1",
+				Records: []string{"192.0.2.53"},
+				ZoneRef: pdnsv1.ZoneRef{Name: "org-test.test.com", Kind: "Zone"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "caa.org-test.test.com",
+				Namespace: "testing",
+			},
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "CAA",
+				TTL:     300,
+				Name:    "org-test.test.com.",
+				Records: []string{`0 issue "letsencrypt.org"`},
+				ZoneRef: pdnsv1.ZoneRef{Name: "org-test.test.com", Kind: "Zone"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "www.org-test.test.com",
+				Namespace: "testing",
+				Annotations: map[string]string{
+					dockyardsv1.AnnotationSkipRemediation: "true",
+				},
+			},
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "A",
+				TTL:     300,
+				Name:    "www",
+				Records: []string{"192.0.2.80"},
+				ZoneRef: pdnsv1.ZoneRef{Name: "org-test.test.com", Kind: "Zone"},
+			},
+		},
+	}
+
+	return zone, rrsets
+}
+
+// driftTestRRsets are the RRsets served for the test zone.
+var driftTestRRsets = []pdnsapi.RRset{
+	{
+		Name:    "org-test.test.com.",
+		Type:    "SOA",
+		TTL:     3600,
+		Records: []pdnsapi.Record{{Content: "ns1.org-test.test.com. hostmaster.org-test.test.com. 2025010101 10800 3600 604800 3600"}},
+	},
+	{
+		Name:    "org-test.test.com.",
+		Type:    "NS",
+		TTL:     300,
+		Records: []pdnsapi.Record{{Content: "ns1.org-test.test.com."}},
+	},
+	{
+		Name:    "ns1.org-test.test.com.",
+		Type:    "A",
+		TTL:     300,
+		Records: []pdnsapi.Record{{Content: "192.0.2.99"}},
+	},
+	{
+		Name:    "www.org-test.test.com.",
+		Type:    "A",
+		TTL:     300,
+		Records: []pdnsapi.Record{{Content: "192.0.2.81"}},
+	},
+	{
+		Name:    "app.org-test.test.com.",
+		Type:    "A",
+		TTL:     300,
+		Records: []pdnsapi.Record{{Content: "192.0.2.10"}},
+	},
+	{
+		Name:    "a-app.org-test.test.com.",
+		Type:    "TXT",
+		TTL:     300,
+		Records: []pdnsapi.Record{{Content: `"heritage=external-dns,external-dns/owner=default,external-dns/resource=ingress/default/app"`}},
+	},
+	{
+		Name:    "manual.org-test.test.com.",
+		Type:    "TXT",
+		TTL:     300,
+		Records: []pdnsapi.Record{{Content: `"edited by hand"`}},
+	},
+}
+
+func TestAuditZone(t *testing.T) {
+	zone, rrsets := driftTestZone()
+
+	drifts := auditZone(&pdnsapi.Zone{RRsets: driftTestRRsets}, &zone, rrsets)
+
+	expected := []Drift{
+		{
+			Kind:    DriftUnmanagedRecord,
+			Name:    "manual.org-test.test.com.",
+			Type:    "TXT",
+			Message: "not managed by an RRset or claimed by ExternalDNS",
+			Repair: &pdnsapi.RRset{
+				Name:       "manual.org-test.test.com.",
+				Type:       "TXT",
+				ChangeType: pdnsapi.ChangeTypeDelete,
+				Records:    []pdnsapi.Record{},
+			},
+		},
+		{
+			Kind:    DriftRecordMismatch,
+			Name:    "ns1.org-test.test.com.",
+			Type:    "A",
+			Message: `serving "192.0.2.99", expected "192.0.2.53"`,
+			Repair: &pdnsapi.RRset{
+				Name:       "ns1.org-test.test.com.",
+				Type:       "A",
+				TTL:        300,
+				ChangeType: pdnsapi.ChangeTypeReplace,
+				Records:    []pdnsapi.Record{{Content: "192.0.2.53"}},
+			},
+		},
+		{
+			Kind:    DriftMissingRecord,
+			Name:    "org-test.test.com.",
+			Type:    "CAA",
+			Message: "not served",
+			Repair: &pdnsapi.RRset{
+				Name:       "org-test.test.com.",
+				Type:       "CAA",
+				TTL:        300,
+				ChangeType: pdnsapi.ChangeTypeReplace,
+				Records:    []pdnsapi.Record{{Content: `0 issue "letsencrypt.org"`}},
+			},
+		},
+	}
+
+	if !cmp.Equal(drifts, expected) {
+		t.Error(cmp.Diff(expected, drifts))
+	}
+}
+
+func TestDriftAuditor(t *testing.T) {
+	ctx := context.Background()
+
+	server := fakepdns.NewServer("test-api-key")
+	t.Cleanup(server.Close)
+
+	pdnsClient := pdnsapi.NewClient(server.URL, "test-api-key")
+
+	server.AddZone("org-test.test.com")
+	server.AddZone("unknown.test.com")
+
+	rrsets := make([]pdnsapi.RRset, len(driftTestRRsets))
+	for i, rrset := range driftTestRRsets {
+		rrset.ChangeType = pdnsapi.ChangeTypeReplace
+		rrsets[i] = rrset
+	}
+
+	err := pdnsClient.PatchRRsets(ctx, "org-test.test.com", rrsets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+
+	_ = pdnsv1.AddToScheme(scheme)
+
+	zone, zoneRRsets := driftTestZone()
+
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zone)
+	for i := range zoneRRsets {
+		builder = builder.WithObjects(&zoneRRsets[i])
+	}
+
+	recorder := record.NewFakeRecorder(10)
+
+	a := DriftAuditor{
+		Client:        builder.Build(),
+		ConfigManager: dyconfig.NewFakeConfigManager(nil),
+		Recorder:      recorder,
+	}
+
+	t.Run("test report", func(t *testing.T) {
+		err := a.audit(ctx, nil, pdnsClient, &DriftAuditPolicy{Mode: driftAuditReport})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(recorder.Events) != 3 {
+			t.Errorf("expected 3 events, got %d", len(recorder.Events))
+		}
+
+		for len(recorder.Events) > 0 {
+			<-recorder.Events
+		}
+
+		served, _ := server.Zone("org-test.test.com.")
+		if !cmp.Equal(served.RRsets, driftTestRRsets) {
+			t.Error(cmp.Diff(driftTestRRsets, served.RRsets))
+		}
+	})
+
+	t.Run("test repair", func(t *testing.T) {
+		err := a.audit(ctx, nil, pdnsClient, &DriftAuditPolicy{Mode: driftAuditRepair})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		served, _ := server.Zone("org-test.test.com.")
+
+		drifts := auditZone(&served, &zone, zoneRRsets)
+		if len(drifts) != 0 {
+			t.Errorf("expected no drift after repair, got %v", drifts)
+		}
+
+		_, found := server.Zone("unknown.test.com.")
+		if !found {
+			t.Error("expected unknown zone to be kept")
+		}
+	})
+}
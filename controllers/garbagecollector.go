@@ -0,0 +1,514 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/sudoswedenab/dockyards-backend/api/apiutil"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=zones,verbs=get;list;patch;delete
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=rrsets,verbs=get;list;patch;delete
+
+// GarbageCollectionPolicy describes how orphaned zones and RRsets are handled.
+type GarbageCollectionPolicy struct {
+	Mode        string
+	GracePeriod time.Duration
+	Interval    time.Duration
+}
+
+// parseGarbageCollectionPolicy reads the garbage collection mode, grace period and interval from the config keys.
+func parseGarbageCollectionPolicy(configManager *dyconfig.ConfigManager) (*GarbageCollectionPolicy, error) {
+	policy := GarbageCollectionPolicy{
+		Mode:        configManager.GetValueOrDefault(KeyGarbageCollection, garbageCollectionDisabled),
+		GracePeriod: defaultGarbageCollectionGrace,
+		Interval:    defaultGarbageCollectionPeriod,
+	}
+
+	switch policy.Mode {
+	case garbageCollectionDisabled, garbageCollectionReport, garbageCollectionDelete:
+	default:
+		return nil, fmt.Errorf("invalid value for config key `%s`: unsupported mode %s", KeyGarbageCollection, policy.Mode)
+	}
+
+	// Orphans are found through RRset resources, which the api backend does not create.
+	backend, err := parseBackend(configManager)
+	if err == nil && backend == backendAPI && policy.Mode != garbageCollectionDisabled {
+		return nil, fmt.Errorf("invalid value for config key `%s`: not supported by the %s backend", KeyGarbageCollection, backendAPI)
+	}
+
+	value, found := configManager.GetValueForKey(KeyGarbageCollectionGracePeriod)
+	if found {
+		gracePeriod, err := time.ParseDuration(value)
+		if err != nil || gracePeriod < 0 {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %s", KeyGarbageCollectionGracePeriod, value)
+		}
+
+		policy.GracePeriod = gracePeriod
+	}
+
+	value, found = configManager.GetValueForKey(KeyGarbageCollectionInterval)
+	if found {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %s", KeyGarbageCollectionInterval, value)
+		}
+
+		policy.Interval = interval
+	}
+
+	return &policy, nil
+}
+
+// zoneOrphanReason returns why a zone labelled with a cluster is orphaned, or an empty string if it is not.
+//
+// A nil cluster means the cluster no longer exists, the expected name is only compared for cluster zones.
+func zoneOrphanReason(zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, expectedName string) string {
+	if cluster == nil {
+		return fmt.Sprintf("cluster %s not found", zone.Labels[dockyardsv1.LabelClusterName])
+	}
+
+	if zone.Labels[LabelZoneType] != "" {
+		if metav1.GetControllerOf(zone) == nil {
+			return "zone has no controller"
+		}
+
+		return ""
+	}
+
+	if !metav1.IsControlledBy(zone, cluster) {
+		return fmt.Sprintf("zone is not controlled by cluster %s", cluster.Name)
+	}
+
+	if zone.Name != expectedName && zone.Annotations[AnnotationMigrateTo] != expectedName {
+		return fmt.Sprintf("cluster %s expects zone %s", cluster.Name, expectedName)
+	}
+
+	return ""
+}
+
+// rrsetOrphanReason returns why an RRset labelled with a cluster is orphaned, or an empty string if it is not.
+//
+// A nil cluster or owner zone means the object no longer exists.
+func rrsetOrphanReason(rrset *pdnsv1.RRset, cluster *dockyardsv1.Cluster, ownerZone *pdnsv1.Zone) string {
+	if cluster == nil {
+		return fmt.Sprintf("cluster %s not found", rrset.Labels[dockyardsv1.LabelClusterName])
+	}
+
+	owner := metav1.GetControllerOf(rrset)
+	if owner == nil || owner.Kind != "Zone" {
+		return "rrset has no owner zone"
+	}
+
+	if ownerZone == nil || ownerZone.UID != owner.UID {
+		return fmt.Sprintf("zone %s not found", owner.Name)
+	}
+
+	return ""
+}
+
+// GarbageCollector periodically reports or deletes zones and RRsets left behind by clusters.
+type GarbageCollector struct {
+	client.Client
+	*dyconfig.ConfigManager
+	Recorder record.EventRecorder
+
+	// Shard limits garbage collection to the zones claimed by the instance and their RRsets, see Shard.
+	Shard *Shard
+}
+
+// Start runs garbage collection passes until the context is cancelled.
+func (g *GarbageCollector) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("garbage-collector")
+	ctx = ctrl.LoggerInto(ctx, logger)
+
+	for {
+		interval := defaultGarbageCollectionPeriod
+
+		policy, err := parseGarbageCollectionPolicy(g.ConfigManager)
+		if err != nil {
+			logger.Error(err, "error parsing garbage collection policy")
+		}
+
+		if policy != nil {
+			interval = policy.Interval
+
+			paused, err := isGloballyPaused(g.ConfigManager)
+			if err != nil {
+				logger.Error(err, "error checking pause")
+			}
+
+			if policy.Mode != garbageCollectionDisabled && err == nil && !paused {
+				err := g.collect(ctx, policy, time.Now())
+				if err != nil {
+					logger.Error(err, "error collecting orphaned zones")
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// NeedLeaderElection makes sure only the leader collects garbage.
+func (g *GarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// collect runs a single garbage collection pass over zones and RRsets labelled with a cluster.
+func (g *GarbageCollector) collect(ctx context.Context, policy *GarbageCollectionPolicy, now time.Time) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var zoneList pdnsv1.ZoneList
+	err := g.List(ctx, &zoneList, client.HasLabels{dockyardsv1.LabelClusterName})
+	if err != nil {
+		return err
+	}
+
+	for i := range zoneList.Items {
+		zone := &zoneList.Items[i]
+
+		if !zone.DeletionTimestamp.IsZero() || !g.Shard.Owns(zone) || g.isExempt(ctx, zone) {
+			continue
+		}
+
+		reason, err := g.zoneOrphanReason(ctx, zone)
+		if err != nil {
+			logger.Error(err, "error checking zone", "zone", zone.Name, "namespace", zone.Namespace)
+
+			continue
+		}
+
+		err = g.handleOrphan(ctx, "Zone", zone, reason, policy, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	var rrsetList pdnsv1.RRsetList
+	err = g.List(ctx, &rrsetList, client.HasLabels{dockyardsv1.LabelClusterName})
+	if err != nil {
+		return err
+	}
+
+	for i := range rrsetList.Items {
+		rrset := &rrsetList.Items[i]
+
+		if !rrset.DeletionTimestamp.IsZero() || !g.ownsRRset(ctx, rrset) || g.isExempt(ctx, rrset) {
+			continue
+		}
+
+		reason, err := g.rrsetOrphanReason(ctx, rrset)
+		if err != nil {
+			logger.Error(err, "error checking rrset", "rrset", rrset.Name, "namespace", rrset.Namespace)
+
+			continue
+		}
+
+		err = g.handleOrphan(ctx, "RRset", rrset, reason, policy, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Orphan is a zone or RRset left behind by a cluster.
+type Orphan struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Reason    string
+
+	// OrphanedSince is when the garbage collector first found the object orphaned, empty if it has not yet.
+	OrphanedSince string
+}
+
+// Orphans returns the zones and RRsets the garbage collector considers orphaned, without marking or deleting them.
+func (g *GarbageCollector) Orphans(ctx context.Context) ([]Orphan, error) {
+	var orphans []Orphan
+
+	var zoneList pdnsv1.ZoneList
+	err := g.List(ctx, &zoneList, client.HasLabels{dockyardsv1.LabelClusterName})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range zoneList.Items {
+		zone := &zoneList.Items[i]
+
+		if !zone.DeletionTimestamp.IsZero() || !g.Shard.Owns(zone) || g.isExempt(ctx, zone) {
+			continue
+		}
+
+		reason, err := g.zoneOrphanReason(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		if reason != "" {
+			orphans = append(orphans, Orphan{
+				Kind:          "Zone",
+				Namespace:     zone.Namespace,
+				Name:          zone.Name,
+				Reason:        reason,
+				OrphanedSince: zone.Annotations[AnnotationOrphanedSince],
+			})
+		}
+	}
+
+	var rrsetList pdnsv1.RRsetList
+	err = g.List(ctx, &rrsetList, client.HasLabels{dockyardsv1.LabelClusterName})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rrsetList.Items {
+		rrset := &rrsetList.Items[i]
+
+		if !rrset.DeletionTimestamp.IsZero() || !g.ownsRRset(ctx, rrset) || g.isExempt(ctx, rrset) {
+			continue
+		}
+
+		reason, err := g.rrsetOrphanReason(ctx, rrset)
+		if err != nil {
+			return nil, err
+		}
+
+		if reason != "" {
+			orphans = append(orphans, Orphan{
+				Kind:          "RRset",
+				Namespace:     rrset.Namespace,
+				Name:          rrset.Name,
+				Reason:        reason,
+				OrphanedSince: rrset.Annotations[AnnotationOrphanedSince],
+			})
+		}
+	}
+
+	return orphans, nil
+}
+
+// isExempt reports whether an object skips remediation or is paused, directly or through its cluster.
+//
+// Objects whose pause cannot be determined are exempt as well.
+func (g *GarbageCollector) isExempt(ctx context.Context, obj client.Object) bool {
+	logger := ctrl.LoggerFrom(ctx)
+
+	_, skip := obj.GetAnnotations()[dockyardsv1.AnnotationSkipRemediation]
+	if skip {
+		return true
+	}
+
+	objs := []client.Object{obj}
+
+	cluster, err := g.getCluster(ctx, obj)
+	if err != nil {
+		logger.Error(err, "error getting cluster", "name", obj.GetName(), "namespace", obj.GetNamespace())
+
+		return true
+	}
+
+	if cluster != nil {
+		objs = append(objs, cluster)
+	}
+
+	reason, err := pauseReason(g.ConfigManager, objs...)
+	if err != nil {
+		logger.Error(err, "error checking pause", "name", obj.GetName(), "namespace", obj.GetNamespace())
+
+		return true
+	}
+
+	return reason != ""
+}
+
+// getCluster returns the cluster named by the labels of an object, or nil if it does not exist.
+func (g *GarbageCollector) getCluster(ctx context.Context, obj client.Object) (*dockyardsv1.Cluster, error) {
+	var cluster dockyardsv1.Cluster
+	err := g.Get(ctx, client.ObjectKey{Name: obj.GetLabels()[dockyardsv1.LabelClusterName], Namespace: obj.GetNamespace()}, &cluster)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, nil
+	}
+
+	return &cluster, nil
+}
+
+// zoneOrphanReason looks up the cluster of a zone and the zone name it expects.
+//
+// Errors resolving the expected name, such as invalid configuration, never make a zone an orphan.
+func (g *GarbageCollector) zoneOrphanReason(ctx context.Context, zone *pdnsv1.Zone) (string, error) {
+	cluster, err := g.getCluster(ctx, zone)
+	if err != nil {
+		return "", err
+	}
+
+	if cluster == nil || zone.Labels[LabelZoneType] != "" {
+		return zoneOrphanReason(zone, cluster, ""), nil
+	}
+
+	if !cluster.DeletionTimestamp.IsZero() {
+		return "", nil
+	}
+
+	ownerOrganization, err := apiutil.GetOwnerOrganization(ctx, g.Client, cluster)
+	if err != nil {
+		return "", err
+	}
+
+	if ownerOrganization == nil {
+		return fmt.Sprintf("cluster %s has no owner organization", cluster.Name), nil
+	}
+
+	naming, err := resolveClusterZoneNaming(g.ConfigManager, cluster, ownerOrganization)
+	if err != nil {
+		return "", err
+	}
+
+	return zoneOrphanReason(zone, cluster, naming.Name), nil
+}
+
+// rrsetOrphanReason looks up the cluster and owner zone of an RRset.
+func (g *GarbageCollector) rrsetOrphanReason(ctx context.Context, rrset *pdnsv1.RRset) (string, error) {
+	cluster, err := g.getCluster(ctx, rrset)
+	if err != nil {
+		return "", err
+	}
+
+	var ownerZone *pdnsv1.Zone
+
+	owner := metav1.GetControllerOf(rrset)
+	if owner != nil && owner.Kind == "Zone" {
+		var zone pdnsv1.Zone
+		err := g.Get(ctx, client.ObjectKey{Name: owner.Name, Namespace: rrset.Namespace}, &zone)
+		if client.IgnoreNotFound(err) != nil {
+			return "", err
+		}
+		if err == nil {
+			ownerZone = &zone
+		}
+	}
+
+	return rrsetOrphanReason(rrset, cluster, ownerZone), nil
+}
+
+// ownsRRset reports whether the zone of the RRset is claimed by the shard, RRsets of missing zones are collected by
+// every shard.
+func (g *GarbageCollector) ownsRRset(ctx context.Context, rrset *pdnsv1.RRset) bool {
+	var zone pdnsv1.Zone
+	err := g.Get(ctx, client.ObjectKey{Name: rrset.Spec.ZoneRef.Name, Namespace: rrset.Namespace}, &zone)
+	if err != nil {
+		return apierrors.IsNotFound(err)
+	}
+
+	return g.Shard.Owns(&zone)
+}
+
+// handleOrphan records when an object was first found orphaned and deletes it once the grace period has passed.
+//
+// Every decision is reported as an Event on the object, objects that are no longer orphaned lose their mark.
+func (g *GarbageCollector) handleOrphan(ctx context.Context, kind string, obj client.Object, reason string, policy *GarbageCollectionPolicy, now time.Time) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	orphanedSince, marked := obj.GetAnnotations()[AnnotationOrphanedSince]
+
+	if reason == "" {
+		if !marked {
+			return nil
+		}
+
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+		annotations := obj.GetAnnotations()
+		delete(annotations, AnnotationOrphanedSince)
+		obj.SetAnnotations(annotations)
+
+		err := g.Patch(ctx, obj, patch)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		g.Recorder.Event(obj, corev1.EventTypeNormal, OrphanRecoveredReason, "no longer orphaned")
+
+		return nil
+	}
+
+	since, err := time.Parse(time.RFC3339, orphanedSince)
+	if !marked || err != nil {
+		since = now
+
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[AnnotationOrphanedSince] = since.UTC().Format(time.RFC3339)
+		obj.SetAnnotations(annotations)
+
+		err := g.Patch(ctx, obj, patch)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+	}
+
+	deleteAfter := since.Add(policy.GracePeriod)
+
+	if policy.Mode == garbageCollectionReport {
+		g.Recorder.Eventf(obj, corev1.EventTypeWarning, OrphanDetectedReason, "orphaned since %s: %s", since.UTC().Format(time.RFC3339), reason)
+
+		logger.Info("Reported orphan", "kind", kind, "name", obj.GetName(), "namespace", obj.GetNamespace(), "reason", reason)
+
+		return nil
+	}
+
+	if now.Before(deleteAfter) {
+		g.Recorder.Eventf(obj, corev1.EventTypeWarning, OrphanDetectedReason, "deleting after %s: %s", deleteAfter.UTC().Format(time.RFC3339), reason)
+
+		return nil
+	}
+
+	err = g.Delete(ctx, obj)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	g.Recorder.Eventf(obj, corev1.EventTypeNormal, OrphanDeletedReason, "deleted: %s", reason)
+
+	logger.Info("Deleted orphan", "kind", kind, "name", obj.GetName(), "namespace", obj.GetNamespace(), "reason", reason)
+
+	return nil
+}
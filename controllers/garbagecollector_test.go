@@ -0,0 +1,305 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestGarbageCollectionPolicy(t *testing.T) {
+	tt := []struct {
+		name     string
+		config   map[string]string
+		expected *GarbageCollectionPolicy
+		invalid  bool
+	}{
+		{
+			name: "test defaults",
+			expected: &GarbageCollectionPolicy{
+				Mode:        garbageCollectionDisabled,
+				GracePeriod: 24 * time.Hour,
+				Interval:    time.Hour,
+			},
+		},
+		{
+			name: "test delete",
+			config: map[string]string{
+				string(KeyGarbageCollection):            "delete",
+				string(KeyGarbageCollectionGracePeriod): "72h",
+				string(KeyGarbageCollectionInterval):    "15m",
+			},
+			expected: &GarbageCollectionPolicy{
+				Mode:        garbageCollectionDelete,
+				GracePeriod: 72 * time.Hour,
+				Interval:    15 * time.Minute,
+			},
+		},
+		{
+			name: "test invalid mode",
+			config: map[string]string{
+				string(KeyGarbageCollection): "purge",
+			},
+			invalid: true,
+		},
+		{
+			name: "test invalid grace period",
+			config: map[string]string{
+				string(KeyGarbageCollection):            "report",
+				string(KeyGarbageCollectionGracePeriod): "1 day",
+			},
+			invalid: true,
+		},
+		{
+			name: "test api backend",
+			config: map[string]string{
+				string(KeyGarbageCollection): "report",
+				string(KeyBackend):           "api",
+			},
+			invalid: true,
+		},
+		{
+			name: "test zero interval",
+			config: map[string]string{
+				string(KeyGarbageCollectionInterval): "0s",
+			},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseGarbageCollectionPolicy(dyconfig.NewFakeConfigManager(tc.config))
+			if tc.invalid {
+				if err == nil {
+					t.Fatalf("expected error, got %v", actual)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(actual, tc.expected) {
+				t.Errorf("diff: %s", cmp.Diff(tc.expected, actual))
+			}
+		})
+	}
+}
+
+func TestZoneOrphanReason(t *testing.T) {
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster",
+			UID:  "cluster-uid",
+		},
+	}
+
+	clusterOwner := metav1.OwnerReference{
+		APIVersion: dockyardsv1.GroupVersion.String(),
+		Kind:       dockyardsv1.ClusterKind,
+		Name:       cluster.Name,
+		UID:        cluster.UID,
+		Controller: ptr.To(true),
+	}
+
+	tt := []struct {
+		name     string
+		zone     pdnsv1.Zone
+		cluster  *dockyardsv1.Cluster
+		expected string
+	}{
+		{
+			name: "test cluster zone",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "org-cluster.test.com",
+					Labels:          map[string]string{dockyardsv1.LabelClusterName: "cluster"},
+					OwnerReferences: []metav1.OwnerReference{clusterOwner},
+				},
+			},
+			cluster: &cluster,
+		},
+		{
+			name: "test missing cluster",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "org-cluster.test.com",
+					Labels:          map[string]string{dockyardsv1.LabelClusterName: "cluster"},
+					OwnerReferences: []metav1.OwnerReference{clusterOwner},
+				},
+			},
+			expected: "cluster cluster not found",
+		},
+		{
+			name: "test removed owner reference",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "org-cluster.test.com",
+					Labels: map[string]string{dockyardsv1.LabelClusterName: "cluster"},
+				},
+			},
+			cluster:  &cluster,
+			expected: "zone is not controlled by cluster cluster",
+		},
+		{
+			name: "test renamed zone",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "org-cluster.old.com",
+					Labels:          map[string]string{dockyardsv1.LabelClusterName: "cluster"},
+					OwnerReferences: []metav1.OwnerReference{clusterOwner},
+				},
+			},
+			cluster:  &cluster,
+			expected: "cluster cluster expects zone org-cluster.test.com",
+		},
+		{
+			name: "test migrating zone",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "org-cluster.old.com",
+					Labels:          map[string]string{dockyardsv1.LabelClusterName: "cluster"},
+					Annotations:     map[string]string{AnnotationMigrateTo: "org-cluster.test.com"},
+					OwnerReferences: []metav1.OwnerReference{clusterOwner},
+				},
+			},
+			cluster: &cluster,
+		},
+		{
+			name: "test custom domain zone",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "example.net",
+					Labels: map[string]string{
+						dockyardsv1.LabelClusterName: "cluster",
+						LabelZoneType:                ZoneTypeCustomDomain,
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: pdnsv1.GroupVersion.String(),
+							Kind:       "Zone",
+							Name:       "org-cluster.test.com",
+							Controller: ptr.To(true),
+						},
+					},
+				},
+			},
+			cluster: &cluster,
+		},
+		{
+			name: "test custom domain zone without controller",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "example.net",
+					Labels: map[string]string{
+						dockyardsv1.LabelClusterName: "cluster",
+						LabelZoneType:                ZoneTypeCustomDomain,
+					},
+				},
+			},
+			cluster:  &cluster,
+			expected: "zone has no controller",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := zoneOrphanReason(&tc.zone, tc.cluster, "org-cluster.test.com")
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRRsetOrphanReason(t *testing.T) {
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster",
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org-cluster.test.com",
+			UID:  "zone-uid",
+		},
+	}
+
+	tt := []struct {
+		name      string
+		owners    []metav1.OwnerReference
+		cluster   *dockyardsv1.Cluster
+		ownerZone *pdnsv1.Zone
+		expected  string
+	}{
+		{
+			name: "test owned rrset",
+			owners: []metav1.OwnerReference{
+				zoneOwnerReference(&zone),
+			},
+			cluster:   &cluster,
+			ownerZone: &zone,
+		},
+		{
+			name: "test missing cluster",
+			owners: []metav1.OwnerReference{
+				zoneOwnerReference(&zone),
+			},
+			ownerZone: &zone,
+			expected:  "cluster cluster not found",
+		},
+		{
+			name:     "test no owner",
+			cluster:  &cluster,
+			expected: "rrset has no owner zone",
+		},
+		{
+			name: "test missing zone",
+			owners: []metav1.OwnerReference{
+				zoneOwnerReference(&zone),
+			},
+			cluster:  &cluster,
+			expected: "zone org-cluster.test.com not found",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rrset := pdnsv1.RRset{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "soa.org-cluster.test.com",
+					Labels:          map[string]string{dockyardsv1.LabelClusterName: "cluster"},
+					OwnerReferences: tc.owners,
+				},
+			}
+
+			actual := rrsetOrphanReason(&rrset, tc.cluster, tc.ownerZone)
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
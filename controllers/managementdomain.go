@@ -0,0 +1,206 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// normalizeDomain lower-cases a domain and strips surrounding whitespace and the trailing dot.
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
+
+// managementDomains returns the allowlist of management domains, which defaults to the single management domain.
+func managementDomains(configManager *dyconfig.ConfigManager) ([]string, error) {
+	value := configManager.GetValueOrDefault(KeyManagementDomains, "")
+	if value == "" {
+		managementDomain := normalizeDomain(configManager.GetValueOrDefault(KeyManagementDomain, ""))
+		if managementDomain == "" {
+			return nil, nil
+		}
+
+		errs := validation.IsDNS1123Subdomain(managementDomain)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %s", KeyManagementDomain, strings.Join(errs, ", "))
+		}
+
+		return []string{managementDomain}, nil
+	}
+
+	var domains []string
+
+	for _, domain := range strings.Split(value, ",") {
+		domain = normalizeDomain(domain)
+		if domain == "" {
+			continue
+		}
+
+		errs := validation.IsDNS1123Subdomain(domain)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %s", KeyManagementDomains, strings.Join(errs, ", "))
+		}
+
+		domains = append(domains, domain)
+	}
+
+	return domains, nil
+}
+
+// parseManagementDomainMapping parses a comma-separated list of `<organization>=<domain>` pairs.
+func parseManagementDomainMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		organization, domain, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid management domain mapping %s: expected <organization>=<domain>", pair)
+		}
+
+		mapping[strings.TrimSpace(organization)] = normalizeDomain(domain)
+	}
+
+	return mapping, nil
+}
+
+// resolveManagementDomain returns the management domain of a cluster zone.
+//
+// The cluster annotation takes precedence over the organization annotation, the config mapping and finally the
+// management domain config key. The result must be part of the allowlist.
+func resolveManagementDomain(configManager *dyconfig.ConfigManager, cluster *dockyardsv1.Cluster, ownerOrganization *dockyardsv1.Organization) (string, error) {
+	managementDomain := normalizeDomain(cluster.Annotations[AnnotationManagementDomain])
+	if managementDomain == "" {
+		return resolveOrganizationManagementDomain(configManager, ownerOrganization)
+	}
+
+	return managementDomain, validateManagementDomain(configManager, managementDomain)
+}
+
+// resolveOrganizationManagementDomain returns the management domain of an organization from its annotation, the
+// config mapping or the management domain config key.
+func resolveOrganizationManagementDomain(configManager *dyconfig.ConfigManager, organization *dockyardsv1.Organization) (string, error) {
+	mapping, err := parseManagementDomainMapping(configManager.GetValueOrDefault(KeyManagementDomainMapping, ""))
+	if err != nil {
+		return "", fmt.Errorf("invalid value for config key `%s`: %w", KeyManagementDomainMapping, err)
+	}
+
+	managementDomain := normalizeDomain(organization.Annotations[AnnotationManagementDomain])
+	if managementDomain == "" {
+		managementDomain = mapping[organization.Name]
+	}
+
+	if managementDomain == "" {
+		value, err := requiredConfigValue(configManager, KeyManagementDomain)
+		if err != nil {
+			return "", err
+		}
+
+		managementDomain = normalizeDomain(value)
+	}
+
+	return managementDomain, validateManagementDomain(configManager, managementDomain)
+}
+
+// validateManagementDomain checks a management domain against the allowlist.
+func validateManagementDomain(configManager *dyconfig.ConfigManager, managementDomain string) error {
+	allowed, err := managementDomains(configManager)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(allowed, managementDomain) {
+		return fmt.Errorf("management domain %s is not allowed", managementDomain)
+	}
+
+	return nil
+}
+
+// zoneLayout returns the zone layout of an organization, the annotation overrides the config key.
+//
+// In the flat layout cluster zones are named `<org>-<cluster>.<domain>`, in the hierarchical layout they are named
+// `<cluster>.<org>.<domain>` and delegated from an organization zone.
+func zoneLayout(configManager *dyconfig.ConfigManager, organization *dockyardsv1.Organization) (string, error) {
+	layout, found := organization.Annotations[AnnotationZoneLayout]
+	if !found {
+		layout = configManager.GetValueOrDefault(KeyZoneLayout, zoneLayoutFlat)
+	}
+
+	switch layout {
+	case zoneLayoutFlat, zoneLayoutHierarchical:
+		return layout, nil
+	default:
+		return "", fmt.Errorf("unsupported zone layout %s", layout)
+	}
+}
+
+// organizationZoneName returns the name of the organization zone in the hierarchical layout.
+func organizationZoneName(organization *dockyardsv1.Organization, managementDomain string) string {
+	return organization.Name + "." + managementDomain
+}
+
+// clusterZoneNaming is the name of a cluster zone with the domain and parent zone it was derived from.
+type clusterZoneNaming struct {
+	Name             string
+	ManagementDomain string
+	ParentZone       string
+}
+
+// resolveClusterZoneNaming returns the expected zone name of a cluster in the zone layout of its organization.
+func resolveClusterZoneNaming(configManager *dyconfig.ConfigManager, cluster *dockyardsv1.Cluster, ownerOrganization *dockyardsv1.Organization) (*clusterZoneNaming, error) {
+	layout, err := zoneLayout(configManager, ownerOrganization)
+	if err != nil {
+		return nil, err
+	}
+
+	if layout == zoneLayoutHierarchical {
+		managementDomain, err := resolveOrganizationManagementDomain(configManager, ownerOrganization)
+		if err != nil {
+			return nil, err
+		}
+
+		parentZone := organizationZoneName(ownerOrganization, managementDomain)
+
+		naming := clusterZoneNaming{
+			Name:             cluster.Name + "." + parentZone,
+			ManagementDomain: managementDomain,
+			ParentZone:       parentZone,
+		}
+
+		return &naming, nil
+	}
+
+	managementDomain, err := resolveManagementDomain(configManager, cluster, ownerOrganization)
+	if err != nil {
+		return nil, err
+	}
+
+	naming := clusterZoneNaming{
+		Name:             ownerOrganization.Name + "-" + cluster.Name + "." + managementDomain,
+		ManagementDomain: managementDomain,
+	}
+
+	return &naming, nil
+}
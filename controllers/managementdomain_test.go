@@ -0,0 +1,222 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"testing"
+
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveManagementDomain(t *testing.T) {
+	tt := []struct {
+		name                    string
+		config                  map[string]string
+		clusterAnnotations      map[string]string
+		organizationAnnotations map[string]string
+		expected                string
+		invalid                 bool
+	}{
+		{
+			name: "test management domain",
+			config: map[string]string{
+				string(KeyManagementDomain): "test.com",
+			},
+			expected: "test.com",
+		},
+		{
+			name: "test invalid management domain",
+			config: map[string]string{
+				string(KeyManagementDomain): "test_domain.com",
+			},
+			invalid: true,
+		},
+		{
+			name: "test missing management domain",
+			config: map[string]string{
+				string(KeyManagementDomains): "test.com",
+			},
+			invalid: true,
+		},
+		{
+			name: "test mapping",
+			config: map[string]string{
+				string(KeyManagementDomain):        "test.com",
+				string(KeyManagementDomains):       "test.com,brand.net",
+				string(KeyManagementDomainMapping): "other=test.com, org=Brand.net.",
+			},
+			expected: "brand.net",
+		},
+		{
+			name: "test organization annotation",
+			config: map[string]string{
+				string(KeyManagementDomain):        "test.com",
+				string(KeyManagementDomains):       "test.com,brand.net,region.org",
+				string(KeyManagementDomainMapping): "org=brand.net",
+			},
+			organizationAnnotations: map[string]string{
+				AnnotationManagementDomain: "region.org",
+			},
+			expected: "region.org",
+		},
+		{
+			name: "test cluster annotation",
+			config: map[string]string{
+				string(KeyManagementDomain):  "test.com",
+				string(KeyManagementDomains): "test.com,brand.net,region.org",
+			},
+			clusterAnnotations: map[string]string{
+				AnnotationManagementDomain: "brand.net",
+			},
+			organizationAnnotations: map[string]string{
+				AnnotationManagementDomain: "region.org",
+			},
+			expected: "brand.net",
+		},
+		{
+			name: "test domain not in allowlist",
+			config: map[string]string{
+				string(KeyManagementDomain):  "test.com",
+				string(KeyManagementDomains): "test.com,brand.net",
+			},
+			clusterAnnotations: map[string]string{
+				AnnotationManagementDomain: "victim.com",
+			},
+			invalid: true,
+		},
+		{
+			name: "test default allowlist",
+			config: map[string]string{
+				string(KeyManagementDomain): "test.com",
+			},
+			organizationAnnotations: map[string]string{
+				AnnotationManagementDomain: "brand.net",
+			},
+			invalid: true,
+		},
+		{
+			name: "test invalid mapping",
+			config: map[string]string{
+				string(KeyManagementDomain):        "test.com",
+				string(KeyManagementDomainMapping): "org:brand.net",
+			},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := dockyardsv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster",
+					Annotations: tc.clusterAnnotations,
+				},
+			}
+
+			organization := dockyardsv1.Organization{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "org",
+					Annotations: tc.organizationAnnotations,
+				},
+			}
+
+			actual, err := resolveManagementDomain(dyconfig.NewFakeConfigManager(tc.config), &cluster, &organization)
+			if tc.invalid {
+				if err == nil {
+					t.Fatalf("expected error, got %s", actual)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestZoneLayout(t *testing.T) {
+	tt := []struct {
+		name                    string
+		config                  map[string]string
+		organizationAnnotations map[string]string
+		expected                string
+		invalid                 bool
+	}{
+		{
+			name:     "test default",
+			expected: zoneLayoutFlat,
+		},
+		{
+			name: "test config",
+			config: map[string]string{
+				string(KeyZoneLayout): "hierarchical",
+			},
+			expected: zoneLayoutHierarchical,
+		},
+		{
+			name: "test organization annotation",
+			config: map[string]string{
+				string(KeyZoneLayout): "hierarchical",
+			},
+			organizationAnnotations: map[string]string{
+				AnnotationZoneLayout: "flat",
+			},
+			expected: zoneLayoutFlat,
+		},
+		{
+			name: "test invalid layout",
+			organizationAnnotations: map[string]string{
+				AnnotationZoneLayout: "nested",
+			},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			organization := dockyardsv1.Organization{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "org",
+					Annotations: tc.organizationAnnotations,
+				},
+			}
+
+			actual, err := zoneLayout(dyconfig.NewFakeConfigManager(tc.config), &organization)
+			if tc.invalid {
+				if err == nil {
+					t.Fatalf("expected error, got %s", actual)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,506 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ZoneMigrationPolicy describes how cluster zones are moved to a new name.
+type ZoneMigrationPolicy struct {
+	Enabled     bool
+	CNAMEPeriod time.Duration
+}
+
+// parseZoneMigrationPolicy reads the zone migration config keys.
+func parseZoneMigrationPolicy(configManager *dyconfig.ConfigManager) (*ZoneMigrationPolicy, error) {
+	value := configManager.GetValueOrDefault(KeyZoneMigration, "false")
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for config key `%s`: %w", KeyZoneMigration, err)
+	}
+
+	policy := ZoneMigrationPolicy{
+		Enabled: enabled,
+	}
+
+	value, found := configManager.GetValueForKey(KeyZoneMigrationCNAMEPeriod)
+	if found {
+		period, err := time.ParseDuration(value)
+		if err != nil || period < 0 {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %s", KeyZoneMigrationCNAMEPeriod, value)
+		}
+
+		policy.CNAMEPeriod = period
+	}
+
+	return &policy, nil
+}
+
+// rrsetFQDN returns the fully qualified name of an RRset name relative to a zone.
+func rrsetFQDN(name, zoneName string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+
+	if name == "" || name == "@" {
+		return zoneName + "."
+	}
+
+	return name + "." + zoneName + "."
+}
+
+// renameDomain moves a fully qualified name from one zone to another, names outside the zone are returned as is.
+func renameDomain(name, oldZone, newZone string) string {
+	if name == oldZone+"." {
+		return newZone + "."
+	}
+
+	prefix, found := strings.CutSuffix(name, "."+oldZone+".")
+	if !found {
+		return name
+	}
+
+	return prefix + "." + newZone + "."
+}
+
+// rrsetKey identifies an RRset by name and type.
+func rrsetKey(name, rrsetType string) string {
+	return name + "/" + rrsetType
+}
+
+// migrateRRsets returns the RRsets of the old zone to replace in the new zone.
+//
+// SOA and apex NS records, RRsets managed by dockyards-pdns and RRsets that already exist in the new zone are
+// skipped. Names and targets inside the old zone are moved to the new zone.
+func migrateRRsets(source []pdnsapi.RRset, managed, existing map[string]bool, oldZone, newZone string) []pdnsapi.RRset {
+	var rrsets []pdnsapi.RRset
+
+	for _, rrset := range source {
+		if rrset.Type == "SOA" || (rrset.Type == "NS" && rrset.Name == oldZone+".") {
+			continue
+		}
+
+		if managed[rrsetKey(rrset.Name, rrset.Type)] {
+			continue
+		}
+
+		name := renameDomain(rrset.Name, oldZone, newZone)
+		if existing[rrsetKey(name, rrset.Type)] {
+			continue
+		}
+
+		records := make([]pdnsapi.Record, len(rrset.Records))
+		for i, record := range rrset.Records {
+			records[i] = record
+
+			if rrset.Type == "TXT" {
+				continue
+			}
+
+			fields := strings.Fields(record.Content)
+			for j, field := range fields {
+				fields[j] = renameDomain(field, oldZone, newZone)
+			}
+
+			records[i].Content = strings.Join(fields, " ")
+		}
+
+		rrsets = append(rrsets, pdnsapi.RRset{
+			Name:       name,
+			Type:       rrset.Type,
+			TTL:        rrset.TTL,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    records,
+		})
+	}
+
+	return rrsets
+}
+
+// forwardingRRsets returns the changes that replace the migrated names of the old zone with CNAMEs to the new zone.
+//
+// The apex and names that hold records managed by dockyards-pdns keep their records, a CNAME cannot coexist with
+// them.
+func forwardingRRsets(source []pdnsapi.RRset, managed map[string]bool, oldZone, newZone string) []pdnsapi.RRset {
+	excluded := map[string]bool{
+		oldZone + ".": true,
+	}
+
+	for _, rrset := range source {
+		if managed[rrsetKey(rrset.Name, rrset.Type)] {
+			excluded[rrset.Name] = true
+		}
+	}
+
+	var names []string
+	ttls := make(map[string]uint32)
+
+	var rrsets []pdnsapi.RRset
+
+	for _, rrset := range source {
+		if excluded[rrset.Name] || renameDomain(rrset.Name, oldZone, newZone) == rrset.Name {
+			continue
+		}
+
+		// PowerDNS rejects a change that touches the same RRset twice, the CNAME replacement covers it.
+		if rrset.Type != "CNAME" {
+			rrsets = append(rrsets, pdnsapi.RRset{
+				Name:       rrset.Name,
+				Type:       rrset.Type,
+				ChangeType: pdnsapi.ChangeTypeDelete,
+				Records:    []pdnsapi.Record{},
+			})
+		}
+
+		_, found := ttls[rrset.Name]
+		if !found {
+			names = append(names, rrset.Name)
+			ttls[rrset.Name] = rrset.TTL
+		}
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		rrsets = append(rrsets, pdnsapi.RRset{
+			Name:       name,
+			Type:       "CNAME",
+			TTL:        ttls[name],
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records: []pdnsapi.Record{
+				{Content: renameDomain(name, oldZone, newZone)},
+			},
+		})
+	}
+
+	return rrsets
+}
+
+// reconcileZoneMigration marks the previous zones of a cluster for migration to its current zone.
+//
+// Without migration the previous zones are left to garbage collection.
+func (r *DockyardsClusterReconciler) reconcileZoneMigration(ctx context.Context, cluster *dockyardsv1.Cluster, clusterZone *pdnsv1.Zone) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	policy, err := parseZoneMigrationPolicy(r.ConfigManager)
+	if err != nil {
+		return err
+	}
+
+	if !policy.Enabled {
+		return nil
+	}
+
+	var zoneList pdnsv1.ZoneList
+	err = r.List(ctx, &zoneList, client.InNamespace(cluster.Namespace), client.MatchingLabels{dockyardsv1.LabelClusterName: cluster.Name})
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zoneList.Items {
+		if zone.Name == clusterZone.Name || zone.Labels[LabelZoneType] != "" {
+			continue
+		}
+
+		if !zone.DeletionTimestamp.IsZero() || !metav1.IsControlledBy(&zone, cluster) || !r.Shard.Owns(&zone) {
+			continue
+		}
+
+		if zone.Annotations[AnnotationMigrateTo] == clusterZone.Name {
+			continue
+		}
+
+		patch := client.MergeFrom(zone.DeepCopy())
+
+		metav1.SetMetaDataAnnotation(&zone.ObjectMeta, AnnotationMigrateTo, clusterZone.Name)
+		delete(zone.Annotations, AnnotationMigrationCopied)
+
+		err := r.Patch(ctx, &zone, patch)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Started DNS Zone migration", "cluster", cluster.Name, "zone", zone.Name, "migrateTo", clusterZone.Name)
+	}
+
+	return nil
+}
+
+// pendingZoneMigration reports whether records still have to be copied into the zone from a previous zone.
+func (r *ZoneReconciler) pendingZoneMigration(ctx context.Context, zone *pdnsv1.Zone) (bool, error) {
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList, client.InNamespace(zone.Namespace), client.HasLabels{dockyardsv1.LabelClusterName})
+	if err != nil {
+		return false, err
+	}
+
+	for _, previous := range zoneList.Items {
+		if previous.Annotations[AnnotationMigrateTo] != zone.Name {
+			continue
+		}
+
+		_, copied := previous.Annotations[AnnotationMigrationCopied]
+		if !copied && previous.DeletionTimestamp.IsZero() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// managedRRsetKeys returns the RRsets of a zone that are managed by dockyards-pdns, through RRset resources or, with
+// the api backend, by the owner comments of the served RRsets.
+func (r *ZoneReconciler) managedRRsetKeys(ctx context.Context, zone *pdnsv1.Zone, served []pdnsapi.RRset) (map[string]bool, error) {
+	backend, err := parseBackend(r.ConfigManager)
+	if err != nil {
+		return nil, err
+	}
+
+	managed := make(map[string]bool)
+
+	if backend == backendAPI {
+		for _, rrset := range served {
+			if recordOwner(rrset) != nil {
+				managed[rrsetKey(rrset.Name, rrset.Type)] = true
+			}
+		}
+
+		return managed, nil
+	}
+
+	var rrsetList pdnsv1.RRsetList
+	err = r.List(ctx, &rrsetList, client.InNamespace(zone.Namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rrset := range rrsetList.Items {
+		if rrset.Spec.ZoneRef.Name != zone.Name {
+			continue
+		}
+
+		managed[rrsetKey(rrsetFQDN(rrset.Spec.Name, zone.Name), rrset.Spec.Type)] = true
+	}
+
+	return managed, nil
+}
+
+// reconcileZoneMigration moves the records of a previous cluster zone into its replacement and retires it.
+//
+// Records are copied once the new zone has succeeded, which lets the ExternalDNS workload switch its domain filter.
+// Migrated names of the old zone are then optionally answered with CNAMEs to the new zone until the old zone is
+// deleted.
+func (r *ZoneReconciler) reconcileZoneMigration(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	policy, err := parseZoneMigrationPolicy(r.ConfigManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	newZone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      zone.Annotations[AnnotationMigrateTo],
+			Namespace: zone.Namespace,
+		},
+	}
+
+	err = r.Get(ctx, client.ObjectKeyFromObject(&newZone), &newZone)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+
+	ready := false
+	if err == nil {
+		backend, err := r.backend(ctx, &newZone)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		status, err := backend.ZoneStatus(ctx, &newZone)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		ready = status.Ready
+	}
+
+	if !ready {
+		message := fmt.Sprintf("waiting for zone %s", newZone.Name)
+
+		err := r.setZoneMigrationCondition(ctx, cluster, metav1.ConditionFalse, ZoneMigrationWaitingReason, message)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: zoneMigrationRequeueDelay}, nil
+	}
+
+	copied, err := time.Parse(time.RFC3339, zone.Annotations[AnnotationMigrationCopied])
+	if err != nil {
+		copied, err = r.copyZoneRecords(ctx, zone, &newZone, policy)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	retireAfter := copied.Add(policy.CNAMEPeriod)
+
+	now := time.Now()
+	if now.Before(retireAfter) {
+		message := fmt.Sprintf("records copied to %s, forwarding until %s", newZone.Name, retireAfter.UTC().Format(time.RFC3339))
+
+		err := r.setZoneMigrationCondition(ctx, cluster, metav1.ConditionFalse, ZoneMigrationForwardingReason, message)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: retireAfter.Sub(now)}, nil
+	}
+
+	deleted, err := deleteRemediable(ctx, r.Client, zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if deleted {
+		logger.Info("Retired migrated DNS Zone", "zone", zone.Name, "migrateTo", newZone.Name)
+	}
+
+	message := fmt.Sprintf("migrated from %s to %s", zone.Name, newZone.Name)
+
+	err = r.setZoneMigrationCondition(ctx, cluster, metav1.ConditionTrue, ZoneMigrationCompletedReason, message)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// copyZoneRecords copies the records of the old zone into the new zone through the PowerDNS API and records when
+// that happened on the old zone.
+//
+// The zones may be placed on different PowerDNS backends.
+func (r *ZoneReconciler) copyZoneRecords(ctx context.Context, zone, newZone *pdnsv1.Zone, policy *ZoneMigrationPolicy) (time.Time, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	ips, err := r.getPDNSIPs(ctx, zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	newIPs, err := r.getPDNSIPs(ctx, newZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	newPDNSClient, err := r.getPDNSClient(ctx, newIPs)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	source, err := pdnsClient.GetZone(ctx, zone.Name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	target, err := newPDNSClient.GetZone(ctx, newZone.Name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	managed, err := r.managedRRsetKeys(ctx, zone, source.RRsets)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	existing := make(map[string]bool)
+	for _, rrset := range target.RRsets {
+		existing[rrsetKey(rrset.Name, rrset.Type)] = true
+	}
+
+	rrsets := migrateRRsets(source.RRsets, managed, existing, zone.Name, newZone.Name)
+	if len(rrsets) > 0 {
+		err := newPDNSClient.PatchRRsets(ctx, newZone.Name, rrsets)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if policy.CNAMEPeriod > 0 {
+		forwarding := forwardingRRsets(source.RRsets, managed, zone.Name, newZone.Name)
+		if len(forwarding) > 0 {
+			err := pdnsClient.PatchRRsets(ctx, zone.Name, forwarding)
+			if err != nil {
+				return time.Time{}, err
+			}
+		}
+	}
+
+	copied := time.Now()
+
+	patch := client.MergeFrom(zone.DeepCopy())
+
+	metav1.SetMetaDataAnnotation(&zone.ObjectMeta, AnnotationMigrationCopied, copied.UTC().Format(time.RFC3339))
+
+	err = r.Patch(ctx, zone, patch)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	logger.Info("Copied DNS Zone records", "zone", zone.Name, "migrateTo", newZone.Name, "rrsets", len(rrsets))
+
+	return copied, nil
+}
+
+// setZoneMigrationCondition patches the zone migration condition of the cluster status.
+func (r *ZoneReconciler) setZoneMigrationCondition(ctx context.Context, cluster *dockyardsv1.Cluster, status metav1.ConditionStatus, reason, message string) error {
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	condition := metav1.Condition{
+		Type:               ZoneMigratedCondition,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: cluster.Generation,
+	}
+
+	if !meta.SetStatusCondition(&cluster.Status.Conditions, condition) {
+		return nil
+	}
+
+	return r.Status().Patch(ctx, cluster, patch)
+}
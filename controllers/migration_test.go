@@ -0,0 +1,284 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRenameDomain(t *testing.T) {
+	tt := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "test apex",
+			input:    "org-cluster.old.com.",
+			expected: "org-cluster.new.com.",
+		},
+		{
+			name:     "test subdomain",
+			input:    "www.apps.org-cluster.old.com.",
+			expected: "www.apps.org-cluster.new.com.",
+		},
+		{
+			name:     "test outside zone",
+			input:    "example.net.",
+			expected: "example.net.",
+		},
+		{
+			name:     "test suffix without label boundary",
+			input:    "otherorg-cluster.old.com.",
+			expected: "otherorg-cluster.old.com.",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := renameDomain(tc.input, "org-cluster.old.com", "org-cluster.new.com")
+			if actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMigrateRRsets(t *testing.T) {
+	source := []pdnsapi.RRset{
+		{
+			Name:    "org-cluster.old.com.",
+			Type:    "SOA",
+			TTL:     3600,
+			Records: []pdnsapi.Record{{Content: "ns1.org-cluster.old.com. hostmaster.org-cluster.old.com. 2025010101 10800 3600 604800 3600"}},
+		},
+		{
+			Name:    "org-cluster.old.com.",
+			Type:    "NS",
+			TTL:     3600,
+			Records: []pdnsapi.Record{{Content: "ns1.org-cluster.old.com."}},
+		},
+		{
+			Name:    "ns1.org-cluster.old.com.",
+			Type:    "A",
+			TTL:     3600,
+			Records: []pdnsapi.Record{{Content: "192.0.2.53"}},
+		},
+		{
+			Name:    "www.org-cluster.old.com.",
+			Type:    "A",
+			TTL:     300,
+			Records: []pdnsapi.Record{{Content: "192.0.2.1"}},
+		},
+		{
+			Name:    "www.org-cluster.old.com.",
+			Type:    "TXT",
+			TTL:     300,
+			Records: []pdnsapi.Record{{Content: "\"heritage=external-dns,external-dns/owner=org-cluster.old.com.\""}},
+		},
+		{
+			Name:    "api.org-cluster.old.com.",
+			Type:    "CNAME",
+			TTL:     300,
+			Records: []pdnsapi.Record{{Content: "www.org-cluster.old.com."}},
+		},
+		{
+			Name:    "mail.org-cluster.old.com.",
+			Type:    "A",
+			TTL:     300,
+			Records: []pdnsapi.Record{{Content: "192.0.2.25"}},
+		},
+	}
+
+	managed := map[string]bool{
+		rrsetKey("ns1.org-cluster.old.com.", "A"): true,
+	}
+
+	existing := map[string]bool{
+		rrsetKey("mail.org-cluster.new.com.", "A"): true,
+	}
+
+	expected := []pdnsapi.RRset{
+		{
+			Name:       "www.org-cluster.new.com.",
+			Type:       "A",
+			TTL:        300,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    []pdnsapi.Record{{Content: "192.0.2.1"}},
+		},
+		{
+			Name:       "www.org-cluster.new.com.",
+			Type:       "TXT",
+			TTL:        300,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    []pdnsapi.Record{{Content: "\"heritage=external-dns,external-dns/owner=org-cluster.old.com.\""}},
+		},
+		{
+			Name:       "api.org-cluster.new.com.",
+			Type:       "CNAME",
+			TTL:        300,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    []pdnsapi.Record{{Content: "www.org-cluster.new.com."}},
+		},
+	}
+
+	actual := migrateRRsets(source, managed, existing, "org-cluster.old.com", "org-cluster.new.com")
+	if !cmp.Equal(actual, expected) {
+		t.Errorf("diff: %s", cmp.Diff(expected, actual))
+	}
+
+	forwarding := forwardingRRsets(source, managed, "org-cluster.old.com", "org-cluster.new.com")
+
+	expectedForwarding := []pdnsapi.RRset{
+		{
+			Name:       "www.org-cluster.old.com.",
+			Type:       "A",
+			ChangeType: pdnsapi.ChangeTypeDelete,
+			Records:    []pdnsapi.Record{},
+		},
+		{
+			Name:       "www.org-cluster.old.com.",
+			Type:       "TXT",
+			ChangeType: pdnsapi.ChangeTypeDelete,
+			Records:    []pdnsapi.Record{},
+		},
+		{
+			Name:       "mail.org-cluster.old.com.",
+			Type:       "A",
+			ChangeType: pdnsapi.ChangeTypeDelete,
+			Records:    []pdnsapi.Record{},
+		},
+		{
+			Name:       "api.org-cluster.old.com.",
+			Type:       "CNAME",
+			TTL:        300,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    []pdnsapi.Record{{Content: "api.org-cluster.new.com."}},
+		},
+		{
+			Name:       "mail.org-cluster.old.com.",
+			Type:       "CNAME",
+			TTL:        300,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    []pdnsapi.Record{{Content: "mail.org-cluster.new.com."}},
+		},
+		{
+			Name:       "www.org-cluster.old.com.",
+			Type:       "CNAME",
+			TTL:        300,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    []pdnsapi.Record{{Content: "www.org-cluster.new.com."}},
+		},
+	}
+
+	if !cmp.Equal(forwarding, expectedForwarding) {
+		t.Errorf("diff: %s", cmp.Diff(expectedForwarding, forwarding))
+	}
+}
+
+func TestManagedRRsetKeys(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = pdnsv1.AddToScheme(scheme)
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-cluster.old.com",
+			Namespace: "testing",
+		},
+	}
+
+	rrset := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ns1.org-cluster.old.com",
+			Namespace: "testing",
+		},
+		Spec: pdnsv1.RRsetSpec{
+			Type:    "A",
+			Name:    "ns1",
+			Records: []string{"192.0.2.1"},
+			ZoneRef: pdnsv1.ZoneRef{Name: zone.Name, Kind: "Zone"},
+		},
+	}
+
+	served := []pdnsapi.RRset{
+		{
+			Name:     "www.org-cluster.old.com.",
+			Type:     "A",
+			Records:  []pdnsapi.Record{{Content: "192.0.2.2"}},
+			Comments: []pdnsapi.Comment{{Account: backendRecordAccount, Content: `{"id":"www","namespace":"testing","zone":"org-cluster.old.com"}`}},
+		},
+		{
+			Name:    "app.org-cluster.old.com.",
+			Type:    "A",
+			Records: []pdnsapi.Record{{Content: "192.0.2.3"}},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&rrset).Build()
+
+	t.Run("test crd backend", func(t *testing.T) {
+		r := ZoneReconciler{
+			Client:        c,
+			ConfigManager: dyconfig.NewFakeConfigManager(nil),
+		}
+
+		actual, err := r.managedRRsetKeys(ctx, &zone, served)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]bool{
+			"ns1.org-cluster.old.com./A": true,
+		}
+
+		if !cmp.Equal(actual, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual))
+		}
+	})
+
+	t.Run("test api backend", func(t *testing.T) {
+		r := ZoneReconciler{
+			Client: c,
+			ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+				string(KeyBackend): backendAPI,
+			}),
+		}
+
+		actual, err := r.managedRRsetKeys(ctx, &zone, served)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]bool{
+			"www.org-cluster.old.com./A": true,
+		}
+
+		if !cmp.Equal(actual, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual))
+		}
+	})
+}
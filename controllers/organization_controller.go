@@ -0,0 +1,254 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=dockyards.io,resources=organizations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=dockyards.io,resources=organizations/finalizers,verbs=update
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=zones,verbs=create;get;list;watch;patch;delete
+
+// OrganizationReconciler orchestrates the parent zones of organizations using the hierarchical zone layout.
+type OrganizationReconciler struct {
+	client.Client
+	*dyconfig.ConfigManager
+
+	// Shard limits the reconciler to the organizations and zones of the instance, see Shard.
+	Shard *Shard
+}
+
+// Reconcile ensures an organization zone exists in the hierarchical zone layout and removes it otherwise.
+func (r *OrganizationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var organization dockyardsv1.Organization
+	err := r.Get(ctx, req.NamespacedName, &organization)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !organization.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if organization.Spec.NamespaceRef == nil {
+		logger.Info("ignoring organization without namespace reference")
+
+		return ctrl.Result{}, nil
+	}
+
+	if !r.Shard.MatchesOrganization(&organization) {
+		return ctrl.Result{}, r.Shard.releaseZones(ctx, r.Client, r.ConfigManager, organization.Spec.NamespaceRef.Name, client.MatchingLabels{
+			dockyardsv1.LabelOrganizationName: organization.Name,
+			LabelZoneType:                     ZoneTypeOrganization,
+		})
+	}
+
+	paused, err := isGloballyPaused(r.ConfigManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if paused {
+		logger.Info("Skipping paused organization", "organization", organization.Name)
+
+		return ctrl.Result{RequeueAfter: pausedRequeueDelay}, nil
+	}
+
+	layout, err := zoneLayout(r.ConfigManager, &organization)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var zoneName string
+
+	if layout == zoneLayoutHierarchical {
+		zoneName, err = r.reconcileOrganizationZone(ctx, &organization)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, r.deleteStaleOrganizationZones(ctx, &organization, zoneName)
+}
+
+// reconcileOrganizationZone creates or patches the zone that cluster zones of the organization are delegated from.
+func (r *OrganizationReconciler) reconcileOrganizationZone(ctx context.Context, organization *dockyardsv1.Organization) (string, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	managementDomain, err := resolveOrganizationManagementDomain(r.ConfigManager, organization)
+	if err != nil {
+		return "", err
+	}
+
+	zoneName := organizationZoneName(organization, managementDomain)
+
+	transferPolicy, err := parseZoneTransferPolicy(organization.Annotations, r.ConfigManager)
+	if err != nil {
+		return "", err
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      zoneName,
+			Namespace: organization.Spec.NamespaceRef.Name,
+		},
+	}
+
+	err = r.Get(ctx, client.ObjectKeyFromObject(&zone), &zone)
+	if client.IgnoreNotFound(err) != nil {
+		return "", err
+	}
+
+	paused, err := isAnnotatedPaused(&zone)
+	if err != nil {
+		return "", err
+	}
+
+	if paused {
+		logger.Info("Skipping paused organization DNS Zone", "organization", organization.Name, "zone", zone.Name)
+
+		return zoneName, nil
+	}
+
+	claimed, err := r.Shard.claimZone(ctx, r.Client, client.ObjectKeyFromObject(&zone))
+	if err != nil || !claimed {
+		return zoneName, err
+	}
+
+	pdnsBackend, err := recordedPDNSBackend(ctx, r.Client, r.ConfigManager, &zone, organization, nil)
+	if err != nil {
+		return "", err
+	}
+
+	ac := zoneApply(zoneName, zone.Namespace, pdnsv1.ZoneSpec{
+		Kind:        transferPolicy.Kind,
+		Nameservers: transferPolicy.ZoneNameservers(zoneName),
+	})
+
+	ac.WithLabels(map[string]string{
+		dockyardsv1.LabelOrganizationName: organization.Name,
+		LabelZoneType:                     ZoneTypeOrganization,
+	})
+
+	r.Shard.applyLabel(ac.ObjectMetaApplyConfiguration)
+
+	for _, key := range transferZoneAnnotations {
+		value, found := organization.Annotations[key]
+		if found {
+			ac.WithAnnotations(map[string]string{
+				key: value,
+			})
+		}
+	}
+
+	ac.WithAnnotations(map[string]string{
+		AnnotationManagementDomain: managementDomain,
+		AnnotationPDNSBackend:      pdnsBackend,
+	})
+
+	ac.WithFinalizers(FinalizerChildZones)
+
+	ac.WithOwnerReferences(ownerReferenceApplyConfiguration(metav1.OwnerReference{
+		APIVersion:         dockyardsv1.GroupVersion.String(),
+		Kind:               dockyardsv1.OrganizationKind,
+		Name:               organization.Name,
+		UID:                organization.UID,
+		Controller:         ptr.To(true),
+		BlockOwnerDeletion: ptr.To(true),
+	}))
+
+	operationResult, err := r.Shard.applyZone(ctx, r.Client, &zone, ac)
+	if errors.Is(err, errClaimLost) {
+		return zoneName, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("Reconciled organization DNS Zone", "organization", organization.Name, "zone", zoneName, "operationResult", operationResult)
+
+	return zoneName, nil
+}
+
+// deleteStaleOrganizationZones deletes the organization zones other than the current one.
+//
+// Deletion is held by the zone controller until no cluster zone is delegated from the organization zone.
+func (r *OrganizationReconciler) deleteStaleOrganizationZones(ctx context.Context, organization *dockyardsv1.Organization, zoneName string) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList, client.InNamespace(organization.Spec.NamespaceRef.Name), client.MatchingLabels{
+		dockyardsv1.LabelOrganizationName: organization.Name,
+		LabelZoneType:                     ZoneTypeOrganization,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zoneList.Items {
+		if zone.Name == zoneName || !zone.DeletionTimestamp.IsZero() || !metav1.IsControlledBy(&zone, organization) || !r.Shard.Owns(&zone) || metav1.HasAnnotation(zone.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) {
+			continue
+		}
+
+		paused, err := isAnnotatedPaused(&zone)
+		if err != nil {
+			return err
+		}
+
+		if paused {
+			continue
+		}
+
+		err = r.Delete(ctx, &zone)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		logger.Info("Deleted stale organization DNS Zone", "organization", organization.Name, "zone", zone.Name)
+	}
+
+	return nil
+}
+
+// SetupWithManager configures the controller runtime to manage organization zones.
+func (r *OrganizationReconciler) SetupWithManager(manager ctrl.Manager) error {
+	scheme := manager.GetScheme()
+
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	err := ctrl.NewControllerManagedBy(manager).
+		For(&dockyardsv1.Organization{}).
+		Owns(&pdnsv1.Zone{}, builder.WithPredicates(r.Shard.ClaimablePredicate())).
+		Complete(r)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestOrganizationReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org",
+			UID:  "ae0b6a66-2c4c-4a8a-8a3e-9e1c7f0c1d2b",
+		},
+		Spec: dockyardsv1.OrganizationSpec{
+			NamespaceRef: &corev1.LocalObjectReference{
+				Name: "testing",
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&organization).Build()
+
+	r := OrganizationReconciler{
+		Client: c,
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyManagementDomain): "test.com",
+			string(KeyZoneLayout):       zoneLayoutHierarchical,
+			string(KeyPDNSName):         "pdns",
+			string(KeyPDNSNamespace):    "pdns",
+		}),
+	}
+
+	req := ctrl.Request{
+		NamespacedName: client.ObjectKeyFromObject(&organization),
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org.test.com",
+			Namespace: "testing",
+		},
+	}
+
+	t.Run("test hierarchical layout", func(t *testing.T) {
+		_, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if zone.Labels[LabelZoneType] != ZoneTypeOrganization {
+			t.Errorf("expected zone type %s, got %s", ZoneTypeOrganization, zone.Labels[LabelZoneType])
+		}
+
+		if !controllerutil.ContainsFinalizer(&zone, FinalizerChildZones) {
+			t.Error("expected child zones finalizer")
+		}
+
+		if !metav1.IsControlledBy(&zone, &organization) {
+			t.Error("expected zone to be controlled by organization")
+		}
+
+		if zone.Annotations[AnnotationManagementDomain] != "test.com" {
+			t.Errorf("expected management domain test.com, got %s", zone.Annotations[AnnotationManagementDomain])
+		}
+	})
+
+	t.Run("test flat layout", func(t *testing.T) {
+		metav1.SetMetaDataAnnotation(&organization.ObjectMeta, AnnotationZoneLayout, zoneLayoutFlat)
+
+		err := c.Update(ctx, &organization)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if zone.DeletionTimestamp.IsZero() {
+			t.Error("expected stale organization zone to be deleted")
+		}
+	})
+}
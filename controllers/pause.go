@@ -0,0 +1,188 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/prometheus/client_golang/prometheus"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// pausedObjects lists the objects whose reconciliation is skipped because of a pause.
+var pausedObjects = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "dockyards_pdns_paused",
+		Help: "Objects whose reconciliation is paused, the global pause is reported with kind Config.",
+	},
+	[]string{"kind", "namespace", "name"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(pausedObjects)
+}
+
+// reportPaused adds or removes an object from the paused metric.
+func reportPaused(kind string, key client.ObjectKey, paused bool) {
+	if paused {
+		pausedObjects.WithLabelValues(kind, key.Namespace, key.Name).Set(1)
+
+		return
+	}
+
+	pausedObjects.DeleteLabelValues(kind, key.Namespace, key.Name)
+}
+
+// isGloballyPaused reports whether the config key pauses all reconciliation and updates the paused metric.
+func isGloballyPaused(configManager *dyconfig.ConfigManager) (bool, error) {
+	value := configManager.GetValueOrDefault(KeyPaused, "false")
+
+	paused, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for config key `%s`: %w", KeyPaused, err)
+	}
+
+	reportPaused("Config", client.ObjectKey{Name: string(KeyPaused)}, paused)
+
+	return paused, nil
+}
+
+// isAnnotatedPaused reports whether the pause annotation of an object is set to true.
+func isAnnotatedPaused(obj client.Object) (bool, error) {
+	value, found := obj.GetAnnotations()[AnnotationPaused]
+	if !found {
+		return false, nil
+	}
+
+	paused, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %s for pause: %w", value, err)
+	}
+
+	return paused, nil
+}
+
+// pauseKind returns the name of an object kind as used in pause reasons.
+func pauseKind(obj client.Object) string {
+	switch obj.(type) {
+	case *dockyardsv1.Cluster:
+		return "cluster"
+	case *pdnsv1.Zone:
+		return "zone"
+	default:
+		return "object"
+	}
+}
+
+// pauseReason returns why reconciliation of the objects is paused, or an empty string when it is not.
+//
+// The config key pauses every object, the annotation pauses the object it is set on.
+func pauseReason(configManager *dyconfig.ConfigManager, objs ...client.Object) (string, error) {
+	paused, err := isGloballyPaused(configManager)
+	if err != nil {
+		return "", err
+	}
+
+	if paused {
+		return fmt.Sprintf("paused by config key `%s`", KeyPaused), nil
+	}
+
+	for _, obj := range objs {
+		paused, err := isAnnotatedPaused(obj)
+		if err != nil {
+			return "", err
+		}
+
+		if paused {
+			return fmt.Sprintf("paused by annotation on %s %s", pauseKind(obj), obj.GetName()), nil
+		}
+	}
+
+	return "", nil
+}
+
+// zonePauseReason returns why reconciliation of a zone is paused, the zones of a paused cluster are paused as well.
+func (r *ZoneReconciler) zonePauseReason(ctx context.Context, zone *pdnsv1.Zone) (string, error) {
+	objs := []client.Object{zone}
+
+	clusterName := zone.Labels[dockyardsv1.LabelClusterName]
+	if clusterName != "" {
+		var cluster dockyardsv1.Cluster
+		err := r.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: zone.Namespace}, &cluster)
+		if client.IgnoreNotFound(err) != nil {
+			return "", err
+		}
+		if err == nil {
+			objs = append(objs, &cluster)
+		}
+	}
+
+	return pauseReason(r.ConfigManager, objs...)
+}
+
+// setPausedCondition reports a pause on the cluster status, an empty reason removes the condition.
+func setPausedCondition(ctx context.Context, c client.Client, cluster *dockyardsv1.Cluster, reason string) error {
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	if reason == "" {
+		if !meta.RemoveStatusCondition(&cluster.Status.Conditions, ReconciliationPausedCondition) {
+			return nil
+		}
+
+		return c.Status().Patch(ctx, cluster, patch)
+	}
+
+	condition := metav1.Condition{
+		Type:               ReconciliationPausedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReconciliationPausedReason,
+		Message:            reason,
+		ObservedGeneration: cluster.Generation,
+	}
+
+	if !meta.SetStatusCondition(&cluster.Status.Conditions, condition) {
+		return nil
+	}
+
+	return c.Status().Patch(ctx, cluster, patch)
+}
+
+// deleteRemediable deletes an object unless it is annotated to skip remediation and reports whether it was deleted.
+func deleteRemediable(ctx context.Context, c client.Client, obj client.Object) (bool, error) {
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	_, skip := obj.GetAnnotations()[dockyardsv1.AnnotationSkipRemediation]
+	if skip {
+		return false, nil
+	}
+
+	err = c.Delete(ctx, obj)
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	return true, nil
+}
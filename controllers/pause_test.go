@@ -0,0 +1,244 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPauseReason(t *testing.T) {
+	tt := []struct {
+		name     string
+		config   map[string]string
+		cluster  dockyardsv1.Cluster
+		zone     pdnsv1.Zone
+		expected string
+		invalid  bool
+	}{
+		{
+			name: "test not paused",
+		},
+		{
+			name: "test global pause",
+			config: map[string]string{
+				string(KeyPaused): "true",
+			},
+			expected: "paused by config key `dockyards-pdns.paused`",
+		},
+		{
+			name: "test cluster annotation",
+			cluster: dockyardsv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						AnnotationPaused: "true",
+					},
+				},
+			},
+			expected: "paused by annotation on cluster test",
+		},
+		{
+			name: "test zone annotation",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "org-test.test.com",
+					Annotations: map[string]string{
+						AnnotationPaused: "true",
+					},
+				},
+			},
+			expected: "paused by annotation on zone org-test.test.com",
+		},
+		{
+			name: "test annotation set to false",
+			cluster: dockyardsv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						AnnotationPaused: "false",
+					},
+				},
+			},
+		},
+		{
+			name: "test invalid config key",
+			config: map[string]string{
+				string(KeyPaused): "sometimes",
+			},
+			invalid: true,
+		},
+		{
+			name: "test invalid annotation",
+			zone: pdnsv1.Zone{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						AnnotationPaused: "sometimes",
+					},
+				},
+			},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			configManager := dyconfig.NewFakeConfigManager(tc.config)
+
+			actual, err := pauseReason(configManager, &tc.cluster, &tc.zone)
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDeleteRemediable(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = pdnsv1.AddToScheme(scheme)
+
+	remediable := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "caa.org-test.test.com",
+			Namespace: "testing",
+		},
+	}
+
+	skipped := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ns1.org-test.test.com",
+			Namespace: "testing",
+			Annotations: map[string]string{
+				dockyardsv1.AnnotationSkipRemediation: "true",
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&remediable, &skipped).Build()
+
+	deleted, err := deleteRemediable(ctx, c, &pdnsv1.RRset{ObjectMeta: remediable.ObjectMeta})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !deleted {
+		t.Error("expected rrset to be deleted")
+	}
+
+	err = c.Get(ctx, client.ObjectKeyFromObject(&remediable), &pdnsv1.RRset{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected rrset to be deleted, got %v", err)
+	}
+
+	deleted, err = deleteRemediable(ctx, c, &pdnsv1.RRset{ObjectMeta: metav1.ObjectMeta{Name: skipped.Name, Namespace: skipped.Namespace}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted {
+		t.Error("expected rrset skipping remediation to be kept")
+	}
+
+	deleted, err = deleteRemediable(ctx, c, &pdnsv1.RRset{ObjectMeta: remediable.ObjectMeta})
+	if err != nil || deleted {
+		t.Errorf("expected missing rrset to be ignored, got %t %v", deleted, err)
+	}
+}
+
+func TestReconcileDNSZonePausedUnclaimed(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org",
+		},
+	}
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testing",
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+			Annotations: map[string]string{
+				AnnotationPaused: "true",
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&cluster, &zone).WithStatusSubresource(&cluster).Build()
+
+	r := DockyardsClusterReconciler{
+		Client: c,
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyManagementDomain): "test.com",
+		}),
+		Shard: &Shard{
+			Name: "eu",
+		},
+	}
+
+	result, err := r.reconcileDNSZone(ctx, &cluster, &organization)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.RequeueAfter != pausedRequeueDelay {
+		t.Errorf("expected requeue after %s, got %s", pausedRequeueDelay, result.RequeueAfter)
+	}
+
+	err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metav1.HasLabel(zone.ObjectMeta, LabelShard) {
+		t.Errorf("expected paused zone not to be claimed, got shard %s", zone.Labels[LabelShard])
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PDNSBackend is a PowerDNS installation that serves zones, reached through the `<PDNSName>-dns` and `<PDNSName>-api`
+// services and authenticated with the `<PDNSName>` secret in PDNSNamespace.
+type PDNSBackend struct {
+	Name          string
+	PDNSName      string
+	PDNSNamespace string
+
+	// Default serves the zones that do not record a backend, which were created before backends were recorded.
+	Default bool
+}
+
+// Serves reports whether the zone is placed on the backend, a nil backend serves every zone.
+func (b *PDNSBackend) Serves(zone *pdnsv1.Zone) bool {
+	if b == nil {
+		return true
+	}
+
+	name := zone.Annotations[AnnotationPDNSBackend]
+	if name == "" {
+		return b.Default
+	}
+
+	return name == b.Name
+}
+
+// parsePDNSBackendList parses a comma separated list of `<name>=<namespace>/<pdnsName>` backends.
+func parsePDNSBackendList(value string) ([]PDNSBackend, error) {
+	var backends []PDNSBackend
+
+	names := make(map[string]bool)
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, location, found := strings.Cut(item, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid PowerDNS backend %s: expected <name>=<namespace>/<pdnsName>", item)
+		}
+
+		namespace, pdnsName, found := strings.Cut(location, "/")
+		if !found || namespace == "" || pdnsName == "" {
+			return nil, fmt.Errorf("invalid PowerDNS backend %s: expected <name>=<namespace>/<pdnsName>", item)
+		}
+
+		name = strings.TrimSpace(name)
+
+		errs := validation.IsDNS1123Label(name)
+		if len(errs) != 0 {
+			return nil, fmt.Errorf("invalid PowerDNS backend name %s: %s", name, strings.Join(errs, ", "))
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("duplicate PowerDNS backend %s", name)
+		}
+
+		names[name] = true
+
+		backends = append(backends, PDNSBackend{
+			Name:          name,
+			PDNSName:      strings.TrimSpace(pdnsName),
+			PDNSNamespace: strings.TrimSpace(namespace),
+			Default:       len(backends) == 0,
+		})
+	}
+
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("no PowerDNS backends")
+	}
+
+	return backends, nil
+}
+
+// ParsePDNSBackends returns the PowerDNS backends, the first one is the default backend.
+//
+// Without the backends config key the `pdnsName` and `pdnsNamespace` config keys are the only, default, backend.
+func ParsePDNSBackends(configManager *dyconfig.ConfigManager) ([]PDNSBackend, error) {
+	value := configManager.GetValueOrDefault(KeyPDNSBackends, "")
+	if value != "" {
+		backends, err := parsePDNSBackendList(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %w", KeyPDNSBackends, err)
+		}
+
+		return backends, nil
+	}
+
+	pdnsName, err := requiredConfigValue(configManager, KeyPDNSName)
+	if err != nil {
+		return nil, err
+	}
+
+	pdnsNamespace, err := requiredConfigValue(configManager, KeyPDNSNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := PDNSBackend{
+		Name:          defaultPDNSBackendName,
+		PDNSName:      pdnsName,
+		PDNSNamespace: pdnsNamespace,
+		Default:       true,
+	}
+
+	return []PDNSBackend{backend}, nil
+}
+
+// findPDNSBackend returns the backend with the name, the default backend for an empty name.
+func findPDNSBackend(backends []PDNSBackend, name string) (*PDNSBackend, error) {
+	for i := range backends {
+		if backends[i].Name == name || name == "" && backends[i].Default {
+			return &backends[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown PowerDNS backend %s", name)
+}
+
+// pdnsBackendForZone returns the backend the zone is placed on.
+func pdnsBackendForZone(configManager *dyconfig.ConfigManager, zone *pdnsv1.Zone) (*PDNSBackend, error) {
+	backends, err := ParsePDNSBackends(configManager)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := findPDNSBackend(backends, zone.Annotations[AnnotationPDNSBackend])
+	if err != nil {
+		return nil, fmt.Errorf("zone %s: %w", zone.Name, err)
+	}
+
+	return backend, nil
+}
+
+// PDNSPlacement decides which backend a new zone is placed on.
+type PDNSPlacement struct {
+	Policy string
+
+	// Mapping places the zones of an organization on a backend with the organization policy.
+	Mapping map[string]string
+
+	// RegionLabel is the cluster label that names the backend with the region policy.
+	RegionLabel string
+}
+
+// parsePDNSPlacement reads the placement policy from the config keys and checks that it refers to known backends.
+func parsePDNSPlacement(configManager *dyconfig.ConfigManager, backends []PDNSBackend) (*PDNSPlacement, error) {
+	placement := PDNSPlacement{
+		Policy:      configManager.GetValueOrDefault(KeyPDNSPlacement, pdnsPlacementOrganization),
+		RegionLabel: configManager.GetValueOrDefault(KeyPDNSRegionLabel, defaultPDNSRegionLabel),
+	}
+
+	switch placement.Policy {
+	case pdnsPlacementOrganization, pdnsPlacementRegion, pdnsPlacementLeastLoaded:
+	default:
+		return nil, fmt.Errorf("invalid value for config key `%s`: unsupported placement %s", KeyPDNSPlacement, placement.Policy)
+	}
+
+	mapping := make(map[string]string)
+
+	for _, pair := range strings.Split(configManager.GetValueOrDefault(KeyPDNSBackendMapping, ""), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		organization, name, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid value for config key `%s`: invalid mapping %s: expected <organization>=<backend>", KeyPDNSBackendMapping, pair)
+		}
+
+		name = strings.TrimSpace(name)
+
+		_, err := findPDNSBackend(backends, name)
+		if err != nil || name == "" {
+			return nil, fmt.Errorf("invalid value for config key `%s`: unknown PowerDNS backend %s", KeyPDNSBackendMapping, name)
+		}
+
+		mapping[strings.TrimSpace(organization)] = name
+	}
+
+	placement.Mapping = mapping
+
+	return &placement, nil
+}
+
+// placePDNSBackend returns the backend for a new zone of the organization, and of the cluster unless it is an
+// organization zone.
+//
+// The organization policy uses the backend annotation of the organization and then the config mapping. The region
+// policy uses the backend named by the region label of the cluster. The least-loaded policy uses the backend with the
+// fewest zones. The default backend is used when the policy does not name a backend.
+func placePDNSBackend(ctx context.Context, reader client.Reader, configManager *dyconfig.ConfigManager, organization *dockyardsv1.Organization, cluster *dockyardsv1.Cluster) (string, error) {
+	backends, err := ParsePDNSBackends(configManager)
+	if err != nil {
+		return "", err
+	}
+
+	placement, err := parsePDNSPlacement(configManager, backends)
+	if err != nil {
+		return "", err
+	}
+
+	var name string
+
+	switch placement.Policy {
+	case pdnsPlacementOrganization:
+		name = organization.Annotations[AnnotationPDNSBackend]
+		if name == "" {
+			name = placement.Mapping[organization.Name]
+		}
+	case pdnsPlacementRegion:
+		if cluster != nil {
+			name = cluster.Labels[placement.RegionLabel]
+		}
+	case pdnsPlacementLeastLoaded:
+		name, err = leastLoadedPDNSBackend(ctx, reader, backends)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	backend, err := findPDNSBackend(backends, name)
+	if err != nil {
+		return "", err
+	}
+
+	return backend.Name, nil
+}
+
+// leastLoadedPDNSBackend returns the backend with the fewest zones, the first one of them on a tie.
+func leastLoadedPDNSBackend(ctx context.Context, reader client.Reader, backends []PDNSBackend) (string, error) {
+	var zoneList pdnsv1.ZoneList
+	err := reader.List(ctx, &zoneList)
+	if err != nil {
+		return "", err
+	}
+
+	counts := make([]int, len(backends))
+
+	for _, zone := range zoneList.Items {
+		for i := range backends {
+			if backends[i].Serves(&zone) {
+				counts[i]++
+			}
+		}
+	}
+
+	least := 0
+	for i := range backends {
+		if counts[i] < counts[least] {
+			least = i
+		}
+	}
+
+	return backends[least].Name, nil
+}
+
+// recordedPDNSBackend returns the backend to record on a zone of the organization or cluster: the backend already
+// recorded on it, the default backend for an existing zone without one, or the placement for a new zone.
+//
+// Zones are never moved between backends by the placement policy.
+func recordedPDNSBackend(ctx context.Context, reader client.Reader, configManager *dyconfig.ConfigManager, current *pdnsv1.Zone, organization *dockyardsv1.Organization, cluster *dockyardsv1.Cluster) (string, error) {
+	name := current.Annotations[AnnotationPDNSBackend]
+	if name != "" {
+		return name, nil
+	}
+
+	if current.ResourceVersion != "" {
+		backend, err := pdnsBackendForZone(configManager, current)
+		if err != nil {
+			return "", err
+		}
+
+		return backend.Name, nil
+	}
+
+	return placePDNSBackend(ctx, reader, configManager, organization, cluster)
+}
@@ -0,0 +1,315 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParsePDNSBackends(t *testing.T) {
+	tt := []struct {
+		name     string
+		config   map[string]string
+		expected []PDNSBackend
+		invalid  bool
+	}{
+		{
+			name: "test single backend",
+			config: map[string]string{
+				string(KeyPDNSName):      "pdns",
+				string(KeyPDNSNamespace): "pdns-system",
+			},
+			expected: []PDNSBackend{
+				{Name: defaultPDNSBackendName, PDNSName: "pdns", PDNSNamespace: "pdns-system", Default: true},
+			},
+		},
+		{
+			name: "test backend list",
+			config: map[string]string{
+				string(KeyPDNSName):     "ignored",
+				string(KeyPDNSBackends): "eu-north=pdns-eu/pdns, us-east=pdns-us/powerdns",
+			},
+			expected: []PDNSBackend{
+				{Name: "eu-north", PDNSName: "pdns", PDNSNamespace: "pdns-eu", Default: true},
+				{Name: "us-east", PDNSName: "powerdns", PDNSNamespace: "pdns-us"},
+			},
+		},
+		{
+			name: "test missing namespace",
+			config: map[string]string{
+				string(KeyPDNSBackends): "eu-north=pdns",
+			},
+			invalid: true,
+		},
+		{
+			name: "test duplicate backend",
+			config: map[string]string{
+				string(KeyPDNSBackends): "eu=pdns-eu/pdns,eu=pdns-us/pdns",
+			},
+			invalid: true,
+		},
+		{
+			name: "test invalid name",
+			config: map[string]string{
+				string(KeyPDNSBackends): "EU=pdns-eu/pdns",
+			},
+			invalid: true,
+		},
+		{
+			name:    "test missing config",
+			config:  map[string]string{},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			configManager := dyconfig.NewFakeConfigManager(tc.config)
+
+			actual, err := ParsePDNSBackends(configManager)
+			if tc.invalid {
+				if err == nil {
+					t.Errorf("expected error, got %v", actual)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(actual, tc.expected) {
+				t.Errorf("diff: %s", cmp.Diff(tc.expected, actual))
+			}
+		})
+	}
+}
+
+func TestPlacePDNSBackend(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = pdnsv1.AddToScheme(scheme)
+
+	zones := []pdnsv1.Zone{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "org-a.test.com",
+				Namespace: "testing",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "org-b.test.com",
+				Namespace:   "testing",
+				Annotations: map[string]string{AnnotationPDNSBackend: "eu"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "org-c.test.com",
+				Namespace:   "testing",
+				Annotations: map[string]string{AnnotationPDNSBackend: "eu"},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zones[0], &zones[1], &zones[2]).Build()
+
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org",
+		},
+	}
+
+	annotatedOrganization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "annotated",
+			Annotations: map[string]string{AnnotationPDNSBackend: "eu"},
+		},
+	}
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test",
+			Labels: map[string]string{defaultPDNSRegionLabel: "us"},
+		},
+	}
+
+	tt := []struct {
+		name         string
+		config       map[string]string
+		organization *dockyardsv1.Organization
+		cluster      *dockyardsv1.Cluster
+		expected     string
+		invalid      bool
+	}{
+		{
+			name:         "test default",
+			organization: &organization,
+			cluster:      &cluster,
+			expected:     "default",
+		},
+		{
+			name: "test organization mapping",
+			config: map[string]string{
+				string(KeyPDNSBackendMapping): "org=us",
+			},
+			organization: &organization,
+			cluster:      &cluster,
+			expected:     "us",
+		},
+		{
+			name: "test organization annotation",
+			config: map[string]string{
+				string(KeyPDNSBackendMapping): "annotated=us",
+			},
+			organization: &annotatedOrganization,
+			cluster:      &cluster,
+			expected:     "eu",
+		},
+		{
+			name: "test unknown mapping",
+			config: map[string]string{
+				string(KeyPDNSBackendMapping): "org=ap",
+			},
+			organization: &organization,
+			cluster:      &cluster,
+			invalid:      true,
+		},
+		{
+			name: "test region",
+			config: map[string]string{
+				string(KeyPDNSPlacement): pdnsPlacementRegion,
+			},
+			organization: &organization,
+			cluster:      &cluster,
+			expected:     "us",
+		},
+		{
+			name: "test region of organization zone",
+			config: map[string]string{
+				string(KeyPDNSPlacement): pdnsPlacementRegion,
+			},
+			organization: &organization,
+			expected:     "default",
+		},
+		{
+			name: "test unknown region",
+			config: map[string]string{
+				string(KeyPDNSPlacement):   pdnsPlacementRegion,
+				string(KeyPDNSRegionLabel): "region",
+			},
+			organization: &organization,
+			cluster: &dockyardsv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"region": "ap"},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "test least loaded",
+			config: map[string]string{
+				string(KeyPDNSPlacement): pdnsPlacementLeastLoaded,
+			},
+			organization: &organization,
+			cluster:      &cluster,
+			expected:     "us",
+		},
+		{
+			name: "test unsupported placement",
+			config: map[string]string{
+				string(KeyPDNSPlacement): "random",
+			},
+			organization: &organization,
+			cluster:      &cluster,
+			invalid:      true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			config := map[string]string{
+				string(KeyPDNSBackends): "default=pdns/pdns,eu=pdns-eu/pdns,us=pdns-us/pdns",
+			}
+			for key, value := range tc.config {
+				config[key] = value
+			}
+
+			configManager := dyconfig.NewFakeConfigManager(config)
+
+			actual, err := placePDNSBackend(ctx, c, configManager, tc.organization, tc.cluster)
+			if tc.invalid {
+				if err == nil {
+					t.Errorf("expected error, got %s", actual)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != tc.expected {
+				t.Errorf("expected backend %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+
+	t.Run("test recorded backend", func(t *testing.T) {
+		configManager := dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyPDNSBackends):       "default=pdns/pdns,eu=pdns-eu/pdns,us=pdns-us/pdns",
+			string(KeyPDNSBackendMapping): "org=us",
+		})
+
+		expected := map[string]string{
+			"org-a.test.com":   "default",
+			"org-b.test.com":   "eu",
+			"org-new.test.com": "us",
+		}
+
+		for name, backend := range expected {
+			var current pdnsv1.Zone
+			for _, zone := range zones {
+				if zone.Name == name {
+					err := c.Get(ctx, client.ObjectKeyFromObject(&zone), &current)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			actual, err := recordedPDNSBackend(ctx, c, configManager, &current, &organization, &cluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != backend {
+				t.Errorf("expected backend %s for %s, got %s", backend, name, actual)
+			}
+		}
+	})
+}
@@ -0,0 +1,411 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=create;patch
+
+// PlannedChange is a change that dry-run mode computed but did not apply.
+type PlannedChange struct {
+	Operation string
+	Diff      string
+}
+
+// Plan collects the latest planned change per object while running in dry-run mode.
+type Plan struct {
+	mu         sync.Mutex
+	changes    map[string]PlannedChange
+	generation int
+}
+
+// NewPlan returns an empty plan.
+func NewPlan() *Plan {
+	return &Plan{
+		changes: make(map[string]PlannedChange),
+	}
+}
+
+// invalidPlanKey matches the characters that are not allowed in ConfigMap keys.
+var invalidPlanKey = regexp.MustCompile(`[^-._a-zA-Z0-9]+`)
+
+// planKey returns the ConfigMap key of a planned change.
+func planKey(elements ...string) string {
+	return invalidPlanKey.ReplaceAllString(strings.Join(elements, "."), "_")
+}
+
+// Record stores a planned change, replacing any earlier change planned for the same key.
+func (p *Plan) Record(key string, change PlannedChange) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.changes[key] == change {
+		return
+	}
+
+	p.changes[key] = change
+	p.generation++
+}
+
+// Changes returns a copy of the planned changes and the generation they belong to.
+func (p *Plan) Changes() (map[string]PlannedChange, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return maps.Clone(p.changes), p.generation
+}
+
+// RecordPDNSRequest records a PowerDNS API request that dry-run mode did not send.
+func (p *Plan) RecordPDNSRequest(method, path string, body []byte) {
+	p.Record(planKey("pdns", strings.ToLower(method), path), PlannedChange{Operation: strings.ToLower(method), Diff: string(body)})
+}
+
+// planClient sends every mutation to the API server as a dry-run request and records it in a plan, patches and
+// applies are only recorded when they change the live object.
+type planClient struct {
+	client.Client
+	plan *Plan
+}
+
+// NewPlanClient wraps a client so that mutations are recorded in the plan and never persisted.
+func NewPlanClient(c client.Client, plan *Plan) client.Client {
+	return &planClient{
+		Client: client.NewDryRunClient(c),
+		plan:   plan,
+	}
+}
+
+func (c *planClient) record(ctx context.Context, operation string, obj runtime.Object, diff []byte) {
+	kind := "Unknown"
+
+	gvk, err := c.GroupVersionKindFor(obj)
+	if err == nil {
+		kind = gvk.Kind
+	}
+
+	var namespace, name string
+
+	accessor, ok := obj.(metav1.Object)
+	if ok {
+		namespace = accessor.GetNamespace()
+		name = accessor.GetName()
+	}
+
+	c.recordChange(ctx, operation, kind, namespace, name, diff)
+}
+
+func (c *planClient) recordChange(ctx context.Context, operation, kind, namespace, name string, diff []byte) {
+	key := planKey(strings.ToLower(kind), namespace, name)
+	if strings.HasPrefix(operation, "status") {
+		key = planKey(key, "status")
+	}
+
+	c.plan.Record(key, PlannedChange{Operation: operation, Diff: string(diff)})
+
+	logger := ctrl.LoggerFrom(ctx)
+	logger.Info("Planned change", "kind", kind, "namespace", namespace, "name", name, "operation", operation, "diff", string(diff))
+}
+
+// Create records the object that would be created.
+func (c *planClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	diff, _ := json.Marshal(obj)
+	c.record(ctx, "create", obj, diff)
+
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// Update records the object that would replace the live object.
+func (c *planClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	diff, _ := json.Marshal(obj)
+	c.record(ctx, "update", obj, diff)
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// Patch sends the patch as a dry-run request and records the fields it would change on the live object.
+func (c *planClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	live := obj.DeepCopyObject().(client.Object)
+
+	err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if err != nil {
+		return err
+	}
+
+	err = c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+	if err != nil {
+		return err
+	}
+
+	c.recordDiff(ctx, "patch", obj, live, obj)
+
+	return nil
+}
+
+// Apply sends the apply configuration as a dry-run request and records the fields it would change on the live
+// object, or the whole object when it does not exist. The live object is compared on the fields of the apply
+// configuration only.
+func (c *planClient) Apply(ctx context.Context, obj runtime.ApplyConfiguration, opts ...client.ApplyOption) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	var u unstructured.Unstructured
+
+	err = json.Unmarshal(b, &u.Object)
+	if err != nil {
+		return err
+	}
+
+	var live unstructured.Unstructured
+	live.SetGroupVersionKind(u.GroupVersionKind())
+
+	err = c.Client.Get(ctx, client.ObjectKeyFromObject(&u), &live)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	var current any
+
+	if err == nil {
+		b, err := json.Marshal(live.Object)
+		if err != nil {
+			return err
+		}
+
+		current = reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+
+		err = json.Unmarshal(b, current)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Client.Apply(ctx, obj, append(opts, client.DryRunAll)...)
+	if err != nil {
+		return err
+	}
+
+	c.recordDiff(ctx, "apply", &u, current, obj)
+
+	return nil
+}
+
+// recordDiff records the fields a dry-run request changed on the live object, nothing is recorded when the request
+// changes no field. A nil live object is an object the request creates.
+func (c *planClient) recordDiff(ctx context.Context, operation string, obj runtime.Object, live, changed any) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	diff, err := planDiff(live, changed, strings.HasPrefix(operation, "status"))
+	if err != nil {
+		logger.Error(err, "error computing planned change", "operation", operation)
+
+		return
+	}
+
+	if diff == nil {
+		return
+	}
+
+	c.record(ctx, operation, obj, diff)
+}
+
+// planDiff returns the JSON merge patch from the live object to the changed object, without the fields the API
+// server maintains. Only the status is compared for status requests and only the other fields otherwise. It returns
+// nil when the objects do not differ.
+func planDiff(live, changed any, status bool) ([]byte, error) {
+	original, err := planFields(live, status)
+	if err != nil {
+		return nil, err
+	}
+
+	modified, err := planFields(changed, status)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := jsonpatch.CreateMergePatch(original, modified)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(diff) == "{}" {
+		return nil, nil
+	}
+
+	return diff, nil
+}
+
+// planFields returns the fields of the object that a planned change compares.
+func planFields(obj any, status bool) ([]byte, error) {
+	if obj == nil {
+		return []byte("{}"), nil
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if status {
+		fields = map[string]any{
+			"status": fields["status"],
+		}
+	} else {
+		delete(fields, "status")
+	}
+
+	delete(fields, "apiVersion")
+	delete(fields, "kind")
+
+	for _, field := range []string{"creationTimestamp", "generation", "managedFields", "resourceVersion", "uid"} {
+		unstructured.RemoveNestedField(fields, "metadata", field)
+	}
+
+	return json.Marshal(fields)
+}
+
+// Delete records the object that would be deleted.
+func (c *planClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.record(ctx, "delete", obj, nil)
+
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// Status returns a status writer that records its mutations in the plan.
+func (c *planClient) Status() client.SubResourceWriter {
+	return &planStatusWriter{
+		SubResourceWriter: c.Client.Status(),
+		client:            c,
+	}
+}
+
+// planStatusWriter records status mutations in the plan of its client.
+type planStatusWriter struct {
+	client.SubResourceWriter
+	client *planClient
+}
+
+// Update records the status that would replace the live status.
+func (w *planStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	diff, _ := json.Marshal(obj)
+	w.client.record(ctx, "status-update", obj, diff)
+
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+// Patch sends the status patch as a dry-run request and records the status fields it would change on the live
+// object.
+func (w *planStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	live := obj.DeepCopyObject().(client.Object)
+
+	err := w.client.Client.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if err != nil {
+		return err
+	}
+
+	err = w.SubResourceWriter.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+	if err != nil {
+		return err
+	}
+
+	w.client.recordDiff(ctx, "status-patch", obj, live, obj)
+
+	return nil
+}
+
+// PlanReporter periodically writes the planned changes of a dry run into a ConfigMap.
+type PlanReporter struct {
+	client.Client
+	Plan      *Plan
+	ConfigMap client.ObjectKey
+}
+
+// Start writes the report whenever the plan has changed until the context is cancelled.
+func (p *PlanReporter) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("plan-reporter")
+
+	reported := -1
+
+	for {
+		changes, generation := p.Plan.Changes()
+		if generation != reported {
+			err := p.report(ctx, changes)
+			if err != nil {
+				logger.Error(err, "error reporting planned changes")
+			} else {
+				reported = generation
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(planReportInterval):
+		}
+	}
+}
+
+// NeedLeaderElection makes sure only the leader writes the report.
+func (p *PlanReporter) NeedLeaderElection() bool {
+	return true
+}
+
+func (p *PlanReporter) report(ctx context.Context, changes map[string]PlannedChange) error {
+	configMap := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.ConfigMap.Name,
+			Namespace: p.ConfigMap.Namespace,
+		},
+	}
+
+	data := make(map[string]string, len(changes))
+
+	for key, change := range changes {
+		data[key] = change.Operation + " " + change.Diff
+	}
+
+	ac := corev1ac.ConfigMap(configMap.Name, configMap.Namespace).
+		WithData(data)
+
+	_, err := applyObject(ctx, p.Client, &configMap, ac)
+
+	return err
+}
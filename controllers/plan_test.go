@@ -0,0 +1,189 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestPlanClient(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = pdnsv1.AddToScheme(scheme)
+
+	existing := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "soa.org-cluster.test.com",
+			Namespace: "testing",
+		},
+		Spec: pdnsv1.RRsetSpec{
+			Type: "SOA",
+			TTL:  3600,
+		},
+	}
+
+	plan := NewPlan()
+	c := NewPlanClient(fake.NewClientBuilder().WithScheme(scheme).WithObjects(&existing).Build(), plan)
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-cluster.test.com",
+			Namespace: "testing",
+		},
+	}
+
+	operationResult, err := controllerutil.CreateOrPatch(ctx, c, &zone, func() error {
+		zone.Spec.Kind = zoneKindNative
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if operationResult != controllerutil.OperationResultCreated {
+		t.Errorf("expected created, got %s", operationResult)
+	}
+
+	rrset := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      existing.Name,
+			Namespace: existing.Namespace,
+		},
+	}
+
+	_, err = controllerutil.CreateOrPatch(ctx, c, &rrset, func() error {
+		rrset.Spec.TTL = 300
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &pdnsv1.Zone{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected zone to not be persisted, got %v", err)
+	}
+
+	err = c.Get(ctx, client.ObjectKeyFromObject(&existing), &rrset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rrset.Spec.TTL != 3600 {
+		t.Errorf("expected rrset to not be patched, got ttl %d", rrset.Spec.TTL)
+	}
+
+	changes, _ := plan.Changes()
+
+	created, found := changes["zone.testing.org-cluster.test.com"]
+	if !found || created.Operation != "create" {
+		t.Errorf("expected planned zone creation, got %v", changes)
+	}
+
+	patched, found := changes["rrset.testing.soa.org-cluster.test.com"]
+	if !found || patched.Operation != "patch" || patched.Diff != `{"spec":{"ttl":300}}` {
+		t.Errorf("expected planned rrset patch, got %v", changes)
+	}
+}
+
+func TestPlanClientUnchanged(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = pdnsv1.AddToScheme(scheme)
+
+	spec := pdnsv1.ZoneSpec{
+		Kind:        zoneKindNative,
+		Nameservers: []string{"ns1.test.com."},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	err := fakeClient.Apply(ctx, zoneApply("org-cluster.test.com", "testing", spec), client.FieldOwner(fieldManager))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := NewPlan()
+	c := NewPlanClient(fakeClient, plan)
+
+	t.Run("test unchanged zone", func(t *testing.T) {
+		zone := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "org-cluster.test.com",
+				Namespace: "testing",
+			},
+		}
+
+		_, err := applyObject(ctx, c, &zone, zoneApply(zone.Name, zone.Namespace, spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = controllerutil.CreateOrPatch(ctx, c, &zone, func() error {
+			zone.Spec.Kind = zoneKindNative
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changes, _ := plan.Changes()
+		if len(changes) != 0 {
+			t.Errorf("expected no planned changes, got %v", changes)
+		}
+	})
+
+	t.Run("test changed zone", func(t *testing.T) {
+		zone := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "org-cluster.test.com",
+				Namespace: "testing",
+			},
+		}
+
+		changed := pdnsv1.ZoneSpec{
+			Kind:        zoneKindNative,
+			Nameservers: []string{"ns2.test.com."},
+		}
+
+		_, err := applyObject(ctx, c, &zone, zoneApply(zone.Name, zone.Namespace, changed))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changes, _ := plan.Changes()
+
+		applied, found := changes["zone.testing.org-cluster.test.com"]
+		if !found || applied.Operation != "apply" || applied.Diff != `{"spec":{"nameservers":["ns2.test.com."]}}` {
+			t.Errorf("expected planned zone apply, got %v", changes)
+		}
+	})
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/prometheus/client_golang/prometheus"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// dnsProbeDuration measures how long the DNS service address takes to answer the SOA query of a zone.
+var dnsProbeDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "dockyards_pdns_dns_probe_duration_seconds",
+		Help:    "Time taken by the PowerDNS DNS service address to answer the SOA query of a zone.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
+	},
+	[]string{"namespace", "zone"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(dnsProbeDuration)
+}
+
+// ZoneProbe is what a nameserver answers for the SOA and apex NS records of a zone.
+type ZoneProbe struct {
+	Serial      uint32
+	Nameservers []string
+	Latency     time.Duration
+}
+
+// parseDNSProbeMode reads the DNS probe mode from the config key.
+func parseDNSProbeMode(configManager *dyconfig.ConfigManager) (string, error) {
+	mode := configManager.GetValueOrDefault(KeyDNSProbe, dnsProbeDisabled)
+
+	switch mode {
+	case dnsProbeDisabled, dnsProbeZone, dnsProbeDelegation:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid value for config key `%s`: unsupported mode %s", KeyDNSProbe, mode)
+	}
+}
+
+// normalizeNameservers returns the nameservers fully qualified, lower-cased and sorted.
+func normalizeNameservers(nameservers []string) []string {
+	normalized := make([]string, len(nameservers))
+	for i, nameserver := range nameservers {
+		normalized[i] = strings.ToLower(dns.Fqdn(nameserver))
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
+
+// probeZone queries a nameserver without recursion for the SOA and apex NS records of a zone it is authoritative for.
+func probeZone(ctx context.Context, server, zoneName string) (*ZoneProbe, error) {
+	fqdn := dns.Fqdn(zoneName)
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn, dns.TypeSOA)
+	msg.RecursionDesired = false
+
+	resp, rtt, err := exchangeDNS(ctx, msg, server)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("SOA query for %s failed with %s", zoneName, dns.RcodeToString[resp.Rcode])
+	}
+
+	if !resp.Authoritative {
+		return nil, fmt.Errorf("%s is not authoritative for %s", server, zoneName)
+	}
+
+	probe := ZoneProbe{
+		Latency: rtt,
+	}
+
+	found := false
+
+	for _, rr := range resp.Answer {
+		soa, ok := rr.(*dns.SOA)
+		if ok && strings.EqualFold(soa.Hdr.Name, fqdn) {
+			probe.Serial = soa.Serial
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no SOA record for %s", zoneName)
+	}
+
+	probe.Nameservers, err = queryNS(ctx, server, zoneName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &probe, nil
+}
+
+// queryNS returns the NS records of a name from the answer, or the referral, of a nameserver.
+func queryNS(ctx context.Context, server, name string, recursive bool) ([]string, error) {
+	fqdn := dns.Fqdn(name)
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn, dns.TypeNS)
+	msg.RecursionDesired = recursive
+
+	resp, _, err := exchangeDNS(ctx, msg, server)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("NS query for %s failed with %s", name, dns.RcodeToString[resp.Rcode])
+	}
+
+	var nameservers []string
+
+	for _, rr := range slices.Concat(resp.Answer, resp.Ns) {
+		ns, ok := rr.(*dns.NS)
+		if ok && strings.EqualFold(ns.Hdr.Name, fqdn) {
+			nameservers = append(nameservers, ns.Ns)
+		}
+	}
+
+	return normalizeNameservers(nameservers), nil
+}
+
+// compareZoneProbe compares a probe with the serial PowerDNS reports and the nameservers of the zone, it returns the
+// reason and message of the first mismatch or an empty reason when the zone is served as desired.
+func compareZoneProbe(probe *ZoneProbe, zone *pdnsv1.Zone) (string, string) {
+	if zone.Status.Serial != nil && probe.Serial < *zone.Status.Serial {
+		return StaleSerialReason, fmt.Sprintf("serving serial %d, expected at least %d", probe.Serial, *zone.Status.Serial)
+	}
+
+	expected := normalizeNameservers(zone.Spec.Nameservers)
+	if !slices.Equal(probe.Nameservers, expected) {
+		return NameserverMismatchReason, fmt.Sprintf("serving nameservers %s, expected %s", strings.Join(probe.Nameservers, ","), strings.Join(expected, ","))
+	}
+
+	return "", ""
+}
+
+// reconcileDNSServing probes the DNS service address for the zone and reports the DNSServing condition on the cluster.
+//
+// In delegation mode the nameservers of the zone are also resolved through the recursive resolver, which only finds
+// them when the parent zone delegates to the same nameservers.
+func (r *ZoneReconciler) reconcileDNSServing(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, ips *PDNSIPs) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	mode, err := parseDNSProbeMode(r.ConfigManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if mode == dnsProbeDisabled {
+		patch := client.MergeFrom(cluster.DeepCopy())
+		if meta.RemoveStatusCondition(&cluster.Status.Conditions, DNSServingCondition) {
+			return ctrl.Result{}, r.Status().Patch(ctx, cluster, patch)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	server := net.JoinHostPort(ips.DNSIP, "53")
+
+	probe, err := probeZone(ctx, server, zone.Name)
+	if err != nil {
+		logger.Info("unable to probe zone", "zone", zone.Name, "server", server, "err", err)
+
+		err := r.setDNSServingCondition(ctx, cluster, metav1.ConditionFalse, ZoneNotServedReason, err.Error())
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: dnsProbeRetryInterval}, nil
+	}
+
+	dnsProbeDuration.WithLabelValues(zone.Namespace, zone.Name).Observe(probe.Latency.Seconds())
+
+	latency := probe.Latency.Round(time.Millisecond)
+
+	reason, message := compareZoneProbe(probe, zone)
+	if reason == "" && mode == dnsProbeDelegation {
+		resolver, err := customDomainResolver(r.ConfigManager)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		delegated, err := queryNS(ctx, resolver, zone.Name, true)
+		if err != nil {
+			reason = DelegationMismatchReason
+			message = err.Error()
+		} else if !slices.Equal(delegated, probe.Nameservers) {
+			reason = DelegationMismatchReason
+			message = fmt.Sprintf("delegated to nameservers %s, expected %s", strings.Join(delegated, ","), strings.Join(probe.Nameservers, ","))
+		}
+	}
+
+	if reason != "" {
+		logger.Info("Zone not served as desired", "zone", zone.Name, "server", server, "reason", reason, "message", message)
+
+		err := r.setDNSServingCondition(ctx, cluster, metav1.ConditionFalse, reason, fmt.Sprintf("%s, answered in %s", message, latency))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: dnsProbeRetryInterval}, nil
+	}
+
+	message = fmt.Sprintf("served by %s with serial %d, answered in %s", server, probe.Serial, latency)
+
+	return ctrl.Result{}, r.setDNSServingCondition(ctx, cluster, metav1.ConditionTrue, ZoneServedReason, message)
+}
+
+// setDNSServingCondition patches the DNS serving condition of the cluster status.
+func (r *ZoneReconciler) setDNSServingCondition(ctx context.Context, cluster *dockyardsv1.Cluster, status metav1.ConditionStatus, reason, message string) error {
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	condition := metav1.Condition{
+		Type:               DNSServingCondition,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: cluster.Generation,
+	}
+
+	if !meta.SetStatusCondition(&cluster.Status.Conditions, condition) {
+		return nil
+	}
+
+	return r.Status().Patch(ctx, cluster, patch)
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// startDNSServer serves the handler on a local UDP port and returns its address.
+func startDNSServer(t *testing.T, handler dns.HandlerFunc) string {
+	t.Helper()
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	server := dns.Server{
+		PacketConn:        packetConn,
+		Handler:           handler,
+		NotifyStartedFunc: func() { close(started) },
+	}
+
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+
+	<-started
+
+	return packetConn.LocalAddr().String()
+}
+
+func TestProbeZone(t *testing.T) {
+	zoneName := "org-test.test.com."
+
+	nameservers := []dns.RR{
+		&dns.NS{
+			Hdr: dns.RR_Header{Name: zoneName, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 300},
+			Ns:  "ns1.org-test.test.com.",
+		},
+		&dns.NS{
+			Hdr: dns.RR_Header{Name: zoneName, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 300},
+			Ns:  "NS2.example.com.",
+		},
+	}
+
+	authoritative := startDNSServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+
+		question := req.Question[0]
+		if question.Name != zoneName {
+			resp.Rcode = dns.RcodeRefused
+			_ = w.WriteMsg(resp)
+
+			return
+		}
+
+		resp.Authoritative = true
+
+		switch question.Qtype {
+		case dns.TypeSOA:
+			resp.Answer = append(resp.Answer, &dns.SOA{
+				Hdr:    dns.RR_Header{Name: zoneName, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+				Ns:     "ns1.org-test.test.com.",
+				Mbox:   "hostmaster.org-test.test.com.",
+				Serial: 2025010101,
+			})
+		case dns.TypeNS:
+			resp.Answer = append(resp.Answer, nameservers...)
+		}
+
+		_ = w.WriteMsg(resp)
+	})
+
+	parent := startDNSServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Ns = append(resp.Ns, nameservers[0])
+
+		_ = w.WriteMsg(resp)
+	})
+
+	ctx := context.Background()
+
+	t.Run("test authoritative zone", func(t *testing.T) {
+		probe, err := probeZone(ctx, authoritative, zoneName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{
+			"ns1.org-test.test.com.",
+			"ns2.example.com.",
+		}
+
+		if probe.Serial != 2025010101 {
+			t.Errorf("expected serial 2025010101, got %d", probe.Serial)
+		}
+
+		if !cmp.Equal(probe.Nameservers, expected) {
+			t.Error(cmp.Diff(expected, probe.Nameservers))
+		}
+
+		if probe.Latency <= 0 {
+			t.Errorf("expected latency, got %s", probe.Latency)
+		}
+	})
+
+	t.Run("test unknown zone", func(t *testing.T) {
+		_, err := probeZone(ctx, authoritative, "other.test.com")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("test not authoritative", func(t *testing.T) {
+		_, err := probeZone(ctx, parent, zoneName)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("test referral", func(t *testing.T) {
+		actual, err := queryNS(ctx, parent, zoneName, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{
+			"ns1.org-test.test.com.",
+		}
+
+		if !cmp.Equal(actual, expected) {
+			t.Error(cmp.Diff(expected, actual))
+		}
+	})
+}
+
+func TestCompareZoneProbe(t *testing.T) {
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org-test.test.com",
+		},
+		Spec: pdnsv1.ZoneSpec{
+			Nameservers: []string{
+				"ns1.org-test.test.com",
+				"ns2.example.com",
+			},
+		},
+		Status: pdnsv1.ZoneStatus{
+			Serial: ptr.To(uint32(2025010102)),
+		},
+	}
+
+	tt := []struct {
+		name     string
+		probe    ZoneProbe
+		expected string
+	}{
+		{
+			name: "test served",
+			probe: ZoneProbe{
+				Serial: 2025010102,
+				Nameservers: []string{
+					"ns1.org-test.test.com.",
+					"ns2.example.com.",
+				},
+			},
+		},
+		{
+			name: "test newer serial",
+			probe: ZoneProbe{
+				Serial: 2025010103,
+				Nameservers: []string{
+					"ns1.org-test.test.com.",
+					"ns2.example.com.",
+				},
+			},
+		},
+		{
+			name: "test stale serial",
+			probe: ZoneProbe{
+				Serial: 2025010101,
+				Nameservers: []string{
+					"ns1.org-test.test.com.",
+					"ns2.example.com.",
+				},
+			},
+			expected: StaleSerialReason,
+		},
+		{
+			name: "test missing nameserver",
+			probe: ZoneProbe{
+				Serial: 2025010102,
+				Nameservers: []string{
+					"ns1.org-test.test.com.",
+				},
+			},
+			expected: NameserverMismatchReason,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, _ := compareZoneProbe(&tc.probe, &zone)
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// RecordTemplate describes a single RRset rendered into every zone of an organization.
+//
+// Name and Records are Go templates that are executed with RecordTemplateData.
+type RecordTemplate struct {
+	// Name is the record name relative to the zone, "@" denotes the zone apex.
+	Name string `json:"name"`
+	// Type is the record type (e.g. "TXT", "CAA", "MX").
+	Type string `json:"type"`
+	// TTL of the rendered RRset, defaults to the zone TTL when unset.
+	TTL uint32 `json:"ttl,omitempty"`
+	// Records is the list of record contents in PowerDNS presentation format.
+	Records []string `json:"records"`
+}
+
+// RecordTemplateData holds the values available as placeholders in record templates.
+type RecordTemplateData struct {
+	Zone         string
+	Cluster      string
+	Organization string
+}
+
+// renderedRecord is a record template that has been rendered for a specific zone.
+type renderedRecord struct {
+	key  string
+	spec pdnsv1.RRsetSpec
+}
+
+// recordTypesOwnedByController are managed by reconcileRRsets and may not be templated at the apex.
+var recordTypesOwnedByController = []string{
+	"SOA",
+	"NS",
+}
+
+// recordTemplateRRsetName returns the name of the RRset rendered from a template key, the prefix keeps template RRsets
+// apart from the RRsets the controller owns.
+func recordTemplateRRsetName(zone *pdnsv1.Zone, key string) string {
+	return "tmpl." + key + "." + zone.Name
+}
+
+// parseRecordTemplates reads record templates from the data of a template ConfigMap.
+//
+// Every key in the data is the identifier of one template and is used to name the rendered RRset, see
+// recordTemplateRRsetName.
+func parseRecordTemplates(data map[string]string) (map[string]RecordTemplate, error) {
+	templates := make(map[string]RecordTemplate, len(data))
+
+	for key, value := range data {
+		errs := validation.IsDNS1123Label(key)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("invalid template key %s: %s", key, strings.Join(errs, ", "))
+		}
+
+		var recordTemplate RecordTemplate
+		err := yaml.UnmarshalStrict([]byte(value), &recordTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template %s: %w", key, err)
+		}
+
+		if recordTemplate.Name == "" {
+			return nil, fmt.Errorf("template %s has no name", key)
+		}
+
+		if recordTemplate.Type == "" {
+			return nil, fmt.Errorf("template %s has no type", key)
+		}
+
+		recordTemplate.Type = strings.ToUpper(recordTemplate.Type)
+
+		if recordTemplate.Name == "@" && slices.Contains(recordTypesOwnedByController, recordTemplate.Type) {
+			return nil, fmt.Errorf("template %s uses reserved type %s at zone apex", key, recordTemplate.Type)
+		}
+
+		if len(recordTemplate.Records) == 0 {
+			return nil, fmt.Errorf("template %s has no records", key)
+		}
+
+		templates[key] = recordTemplate
+	}
+
+	return templates, nil
+}
+
+// renderRecordTemplates executes the record templates for a zone and returns them sorted by key.
+func renderRecordTemplates(templates map[string]RecordTemplate, zone *pdnsv1.Zone, data RecordTemplateData) ([]renderedRecord, error) {
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	rendered := make([]renderedRecord, 0, len(keys))
+
+	for _, key := range keys {
+		recordTemplate := templates[key]
+
+		name, err := executeRecordTemplate(key, recordTemplate.Name, data)
+		if err != nil {
+			return nil, err
+		}
+
+		if name == "" || name == "@" {
+			name = zone.Name + "."
+		}
+
+		records := make([]string, len(recordTemplate.Records))
+		for i, record := range recordTemplate.Records {
+			records[i], err = executeRecordTemplate(key, record, data)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ttl := recordTemplate.TTL
+		if ttl == 0 {
+			ttl = uint32(zoneTTL)
+		}
+
+		rendered = append(rendered, renderedRecord{
+			key: key,
+			spec: pdnsv1.RRsetSpec{
+				Type:    recordTemplate.Type,
+				TTL:     ttl,
+				Name:    name,
+				Records: records,
+				ZoneRef: pdnsv1.ZoneRef{
+					Name: zone.Name,
+					Kind: zone.Kind,
+				},
+			},
+		})
+	}
+
+	return rendered, nil
+}
+
+// reservedRecordKey identifies an RRset by its fully qualified name and type.
+func reservedRecordKey(name, recordType string) string {
+	return strings.ToUpper(recordType) + " " + name
+}
+
+// reservedRecords returns the RRsets the controller maintains in the zone, keyed by reservedRecordKey, so record
+// templates cannot take them over.
+func (r *ZoneReconciler) reservedRecords(ctx context.Context, zone *pdnsv1.Zone) (map[string]string, error) {
+	reserved := make(map[string]string)
+
+	for _, recordType := range recordTypesOwnedByController {
+		reserved[reservedRecordKey(zone.Name+".", recordType)] = "the zone apex " + recordType
+	}
+
+	reserved[reservedRecordKey("ns1."+zone.Name+".", "A")] = "the primary nameserver"
+	reserved[reservedRecordKey("ns1."+zone.Name+".", "AAAA")] = "the primary nameserver"
+
+	if !r.caaPolicy(zone).IsEmpty() {
+		reserved[reservedRecordKey(zone.Name+".", "CAA")] = "the CAA policy"
+	}
+
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList, client.InNamespace(zone.Namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range zoneList.Items {
+		if child.Annotations[AnnotationParentZone] != zone.Name {
+			continue
+		}
+
+		reserved[reservedRecordKey(child.Name+".", "NS")] = "the delegation of zone " + child.Name
+		reserved[reservedRecordKey("ns1."+child.Name+".", "A")] = "the delegation of zone " + child.Name
+	}
+
+	enabled, err := r.isACMEEnabled(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		hosts, err := parseACMEHosts(r.GetValueOrDefault(KeyACMEHosts, defaultACMEHosts))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %w", KeyACMEHosts, err)
+		}
+
+		reserved[reservedRecordKey(rrsetFQDN(strings.TrimSuffix(acmeChallengeZonePrefix, "."), zone.Name), "NS")] = "the ACME challenge delegation"
+
+		for _, host := range hosts {
+			name, _ := acmeChallengeRecord(zone, host)
+
+			reserved[reservedRecordKey(rrsetFQDN(name, zone.Name), "CNAME")] = "the ACME challenge of " + host
+		}
+	}
+
+	return reserved, nil
+}
+
+func executeRecordTemplate(key, text string, data RecordTemplateData) (string, error) {
+	t, err := template.New(key).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %w", key, err)
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("error rendering template %s: %w", key, err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,371 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRenderRecordTemplates(t *testing.T) {
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org-cluster.test.com",
+		},
+	}
+
+	data := RecordTemplateData{
+		Zone:         zone.Name,
+		Cluster:      "cluster",
+		Organization: "org",
+	}
+
+	tt := []struct {
+		name     string
+		data     map[string]string
+		expected []renderedRecord
+	}{
+		{
+			name: "test apex spf",
+			data: map[string]string{
+				"spf": "name: '@'\ntype: txt\nttl: 3600\nrecords:\n- '\"v=spf1 -all\"'\n",
+			},
+			expected: []renderedRecord{
+				{
+					key: "spf",
+					spec: pdnsv1.RRsetSpec{
+						Type: "TXT",
+						TTL:  3600,
+						Name: zone.Name + ".",
+						Records: []string{
+							"\"v=spf1 -all\"",
+						},
+						ZoneRef: pdnsv1.ZoneRef{
+							Name: zone.Name,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test placeholders",
+			data: map[string]string{
+				"verification": "name: _verify\ntype: TXT\nrecords:\n- '\"{{ .Organization }}/{{ .Cluster }}\"'\n",
+				"dmarc":        "name: _dmarc\ntype: TXT\nrecords:\n- '\"v=DMARC1; p=reject; rua=mailto:dmarc@{{ .Zone }}\"'\n",
+			},
+			expected: []renderedRecord{
+				{
+					key: "dmarc",
+					spec: pdnsv1.RRsetSpec{
+						Type: "TXT",
+						TTL:  zoneTTL,
+						Name: "_dmarc",
+						Records: []string{
+							"\"v=DMARC1; p=reject; rua=mailto:dmarc@org-cluster.test.com\"",
+						},
+						ZoneRef: pdnsv1.ZoneRef{
+							Name: zone.Name,
+						},
+					},
+				},
+				{
+					key: "verification",
+					spec: pdnsv1.RRsetSpec{
+						Type: "TXT",
+						TTL:  zoneTTL,
+						Name: "_verify",
+						Records: []string{
+							"\"org/cluster\"",
+						},
+						ZoneRef: pdnsv1.ZoneRef{
+							Name: zone.Name,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			templates, err := parseRecordTemplates(tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := renderRecordTemplates(templates, &zone, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(actual, tc.expected, cmp.AllowUnexported(renderedRecord{})) {
+				t.Error(cmp.Diff(tc.expected, actual, cmp.AllowUnexported(renderedRecord{})))
+			}
+		})
+	}
+}
+
+func TestParseRecordTemplatesInvalid(t *testing.T) {
+	tt := []struct {
+		name string
+		data map[string]string
+	}{
+		{
+			name: "test reserved apex type",
+			data: map[string]string{
+				"soa": "name: '@'\ntype: SOA\nrecords:\n- x\n",
+			},
+		},
+		{
+			name: "test missing records",
+			data: map[string]string{
+				"empty": "name: www\ntype: A\n",
+			},
+		},
+		{
+			name: "test invalid key",
+			data: map[string]string{
+				"Not_Valid": "name: www\ntype: A\nrecords:\n- 1.2.3.4\n",
+			},
+		},
+		{
+			name: "test unknown field",
+			data: map[string]string{
+				"www": "name: www\ntype: A\ncontent: 1.2.3.4\n",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseRecordTemplates(tc.data)
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestReconcileRecordTemplates(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = corev1.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org",
+		},
+	}
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster",
+			Namespace: "testing",
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-cluster.test.com",
+			Namespace: "testing",
+			UID:       "a1b2c3",
+			Annotations: map[string]string{
+				AnnotationRecordTemplate: "baseline-records",
+			},
+		},
+	}
+
+	childZone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "child.org-cluster.test.com",
+			Namespace: "testing",
+			Annotations: map[string]string{
+				AnnotationParentZone: zone.Name,
+			},
+		},
+	}
+
+	soa := pdnsv1.RRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "soa." + zone.Name,
+			Namespace: "testing",
+		},
+		Spec: pdnsv1.RRsetSpec{
+			Type: "SOA",
+			Name: zone.Name + ".",
+		},
+	}
+
+	configMap := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "baseline-records",
+			Namespace: "testing",
+		},
+		Data: map[string]string{
+			"soa": "name: www\ntype: A\nrecords:\n- 192.0.2.1\n",
+			"spf": "name: '@'\ntype: TXT\nrecords:\n- '\"v=spf1 -all\"'\n",
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zone, &childZone, &soa, &configMap).Build()
+
+	configManager := dyconfig.NewFakeConfigManager(map[string]string{})
+
+	r := ZoneReconciler{
+		Client:        c,
+		ConfigManager: configManager,
+	}
+
+	t.Run("test apply", func(t *testing.T) {
+		_, err := r.reconcileRecordTemplates(ctx, &zone, &cluster, &organization)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"tmpl.soa." + zone.Name, "tmpl.spf." + zone.Name} {
+			var rrset pdnsv1.RRset
+			err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: "testing"}, &rrset)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rrset.Labels[LabelRecordTemplate] != "baseline-records" {
+				t.Errorf("expected record template label on %s", name)
+			}
+		}
+
+		var actual pdnsv1.RRset
+		err = c.Get(ctx, client.ObjectKeyFromObject(&soa), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual.Spec.Type != "SOA" {
+			t.Errorf("expected soa rrset to be untouched, got type %s", actual.Spec.Type)
+		}
+	})
+
+	t.Run("test prune", func(t *testing.T) {
+		delete(configMap.Data, "soa")
+
+		err := c.Update(ctx, &configMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileRecordTemplates(ctx, &zone, &cluster, &organization)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rrsetList pdnsv1.RRsetList
+		err = c.List(ctx, &rrsetList, client.InNamespace("testing"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actual []string
+		for _, rrset := range rrsetList.Items {
+			actual = append(actual, rrset.Name)
+		}
+
+		expected := []string{
+			"soa." + zone.Name,
+			"tmpl.spf." + zone.Name,
+		}
+
+		if !cmp.Equal(actual, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual))
+		}
+	})
+
+	tt := []struct {
+		name   string
+		config map[string]string
+		data   string
+	}{
+		{
+			name: "test caa policy collision",
+			config: map[string]string{
+				string(KeyCAAIssuers): "letsencrypt.org",
+			},
+			data: "name: '@'\ntype: caa\nrecords:\n- '0 issue \"example.net\"'\n",
+		},
+		{
+			name: "test delegation collision",
+			data: "name: child\ntype: NS\nrecords:\n- ns1.example.net.\n",
+		},
+		{
+			name: "test rendered apex soa collision",
+			data: "name: '{{ .Zone }}.'\ntype: SOA\nrecords:\n- x\n",
+		},
+		{
+			name: "test empty apex ns collision",
+			data: "name: '{{ if false }}www{{ end }}'\ntype: ns\nrecords:\n- ns1.example.net.\n",
+		},
+		{
+			name: "test primary nameserver collision",
+			data: "name: NS1\ntype: AAAA\nrecords:\n- 2001:db8::53\n",
+		},
+		{
+			name: "test acme challenge collision",
+			config: map[string]string{
+				string(KeyACMEChallenges): "true",
+			},
+			data: "name: _acme-challenge\ntype: CNAME\nrecords:\n- example.net.\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			configMap.Data["collision"] = tc.data
+
+			err := c.Update(ctx, &configMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := ZoneReconciler{
+				Client:        c,
+				ConfigManager: dyconfig.NewFakeConfigManager(tc.config),
+			}
+
+			_, err = r.reconcileRecordTemplates(ctx, &zone, &cluster, &organization)
+			if err == nil {
+				t.Fatal("expected collision error")
+			}
+
+			var rrset pdnsv1.RRset
+			err = c.Get(ctx, client.ObjectKey{Name: "tmpl.collision." + zone.Name, Namespace: "testing"}, &rrset)
+			if err == nil {
+				t.Error("expected colliding template not to be applied")
+			}
+		})
+	}
+}
@@ -0,0 +1,574 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=clusterzones;clusterrrsets,verbs=create;patch;get;list;watch
+
+// ReversePool is an address pool whose addresses get PTR records in a reverse zone.
+type ReversePool struct {
+	Prefix netip.Prefix
+	Zone   string
+}
+
+// Classless reports whether the pool is smaller than an IPv4 /24 and delegated from its /24 zone as described in
+// RFC 2317.
+func (p *ReversePool) Classless() bool {
+	return p.Prefix.Addr().Is4() && p.Prefix.Bits() > 24
+}
+
+// ParentZone returns the /24 zone a classless pool is delegated from.
+func (p *ReversePool) ParentZone() string {
+	parent, _ := reverseZoneName(netip.PrefixFrom(p.Prefix.Addr(), 24).Masked())
+
+	return parent
+}
+
+// PTRName returns the fully qualified name of the PTR RRset of an address in the pool.
+func (p *ReversePool) PTRName(addr netip.Addr) string {
+	if p.Classless() {
+		return strconv.Itoa(int(addr.As4()[3])) + "." + p.Zone + "."
+	}
+
+	return reverseName(addr)
+}
+
+// ReverseDNSPolicy maps address pools to the reverse zones their PTR records are kept in.
+type ReverseDNSPolicy struct {
+	Pools       []ReversePool
+	Nameservers []string
+}
+
+// Pool returns the most specific pool that contains the address, or nil.
+func (p *ReverseDNSPolicy) Pool(addr netip.Addr) *ReversePool {
+	var match *ReversePool
+
+	for i := range p.Pools {
+		pool := &p.Pools[i]
+		if pool.Prefix.Contains(addr) && (match == nil || pool.Prefix.Bits() > match.Prefix.Bits()) {
+			match = pool
+		}
+	}
+
+	return match
+}
+
+// ZonePool returns the pool of the reverse zone, or nil.
+func (p *ReverseDNSPolicy) ZonePool(zoneName string) *ReversePool {
+	for i := range p.Pools {
+		if p.Pools[i].Zone == zoneName {
+			return &p.Pools[i]
+		}
+	}
+
+	return nil
+}
+
+// parseReverseDNSPolicy reads the reverse DNS config keys, nameservers are required once a pool is configured.
+func parseReverseDNSPolicy(configManager *dyconfig.ConfigManager) (*ReverseDNSPolicy, error) {
+	pools, err := parseReversePools(configManager.GetValueOrDefault(KeyReverseZones, ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for config key `%s`: %w", KeyReverseZones, err)
+	}
+
+	policy := ReverseDNSPolicy{
+		Pools: pools,
+	}
+
+	if len(pools) == 0 {
+		return &policy, nil
+	}
+
+	value, err := requiredConfigValue(configManager, KeyReverseNameservers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, nameserver := range strings.Split(value, ",") {
+		nameserver = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(nameserver), "."))
+		if nameserver == "" {
+			continue
+		}
+
+		errs := validation.IsDNS1123Subdomain(nameserver)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %s: %s", KeyReverseNameservers, nameserver, strings.Join(errs, ", "))
+		}
+
+		policy.Nameservers = append(policy.Nameservers, nameserver)
+	}
+
+	return &policy, nil
+}
+
+// parseReversePools parses comma-separated `<cidr>[=<zone>]` pools, the zone name may only be set for classless pools
+// and defaults to `<network>-<prefix length>.<parent zone>`.
+func parseReversePools(value string) ([]ReversePool, error) {
+	var pools []ReversePool
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		cidr, zoneName, hasZoneName := strings.Cut(entry, "=")
+
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, err
+		}
+
+		if prefix != prefix.Masked() {
+			return nil, fmt.Errorf("pool %s is not a network address", prefix)
+		}
+
+		pool := ReversePool{
+			Prefix: prefix,
+		}
+
+		pool.Zone, err = reverseZoneName(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		if hasZoneName {
+			zoneName = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(zoneName), "."))
+
+			if !pool.Classless() {
+				return nil, fmt.Errorf("pool %s is not classless, its zone name cannot be set", prefix)
+			}
+
+			errs := validation.IsDNS1123Subdomain(zoneName)
+			if len(errs) > 0 {
+				return nil, fmt.Errorf("invalid zone name %s: %s", zoneName, strings.Join(errs, ", "))
+			}
+
+			if !strings.HasSuffix(zoneName, ".in-addr.arpa") {
+				return nil, fmt.Errorf("zone name %s is not in in-addr.arpa", zoneName)
+			}
+
+			pool.Zone = zoneName
+		}
+
+		for _, other := range pools {
+			if other.Prefix == pool.Prefix || other.Zone == pool.Zone {
+				return nil, fmt.Errorf("pool %s overlaps pool %s", prefix, other.Prefix)
+			}
+		}
+
+		pools = append(pools, pool)
+	}
+
+	return pools, nil
+}
+
+// reverseZoneName returns the name of the reverse zone of a prefix.
+//
+// IPv4 zones are delegated on octet boundaries, prefixes between /25 and /31 get an RFC 2317 zone below their /24
+// zone. IPv6 zones are delegated on nibble boundaries.
+func reverseZoneName(prefix netip.Prefix) (string, error) {
+	addr := prefix.Addr()
+	bits := prefix.Bits()
+
+	if addr.Is4() {
+		octets := addr.As4()
+
+		switch {
+		case bits == 8 || bits == 16 || bits == 24:
+			labels := make([]string, 0, bits/8+1)
+			for i := bits/8 - 1; i >= 0; i-- {
+				labels = append(labels, strconv.Itoa(int(octets[i])))
+			}
+
+			return strings.Join(append(labels, "in-addr.arpa"), "."), nil
+		case bits > 24 && bits < 32:
+			parent, _ := reverseZoneName(netip.PrefixFrom(addr, 24).Masked())
+
+			return fmt.Sprintf("%d-%d.%s", octets[3], bits, parent), nil
+		}
+
+		return "", fmt.Errorf("pool %s must be a /8, /16, /24 or between /25 and /31", prefix)
+	}
+
+	if bits == 0 || bits >= 128 || bits%4 != 0 {
+		return "", fmt.Errorf("pool %s must end on a nibble boundary", prefix)
+	}
+
+	nibbles := reverseNibbles(addr)
+
+	return strings.Join(append(nibbles[len(nibbles)-bits/4:], "ip6.arpa"), "."), nil
+}
+
+// reverseName returns the fully qualified in-addr.arpa or ip6.arpa name of an address.
+func reverseName(addr netip.Addr) string {
+	if addr.Is4() {
+		octets := addr.As4()
+
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", octets[3], octets[2], octets[1], octets[0])
+	}
+
+	return strings.Join(append(reverseNibbles(addr), "ip6.arpa."), ".")
+}
+
+// reverseNibbles returns the hexadecimal nibbles of an IPv6 address, least significant first.
+func reverseNibbles(addr netip.Addr) []string {
+	bytes := addr.As16()
+
+	nibbles := make([]string, 0, 32)
+	for i := len(bytes) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, strconv.FormatUint(uint64(bytes[i]&0x0f), 16), strconv.FormatUint(uint64(bytes[i]>>4), 16))
+	}
+
+	return nibbles
+}
+
+// ReverseAddress is an address allocated to a cluster and the name in the cluster zone it resolves back to.
+type ReverseAddress struct {
+	Address netip.Addr
+	Name    string
+}
+
+// Target returns the fully qualified name the PTR record of the address points at, the zone apex without a name.
+func (a *ReverseAddress) Target(zoneName string) string {
+	return rrsetFQDN(a.Name, zoneName)
+}
+
+// parseReverseAddresses parses comma-separated `<address>[=<name>]` allocations, names are relative to the cluster
+// zone.
+func parseReverseAddresses(value string) ([]ReverseAddress, error) {
+	var addresses []ReverseAddress
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		address, name, _ := strings.Cut(entry, "=")
+
+		addr, err := netip.ParseAddr(strings.TrimSpace(address))
+		if err != nil {
+			return nil, err
+		}
+
+		addr = addr.Unmap()
+
+		if addr.Zone() != "" {
+			return nil, fmt.Errorf("invalid address %s: zones are not allowed", addr)
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && name != "@" {
+			errs := validation.IsDNS1123Subdomain(name)
+			if len(errs) > 0 {
+				return nil, fmt.Errorf("invalid name %s for address %s: %s", name, addr, strings.Join(errs, ", "))
+			}
+		}
+
+		if slices.ContainsFunc(addresses, func(other ReverseAddress) bool { return other.Address == addr }) {
+			return nil, fmt.Errorf("duplicate address %s", addr)
+		}
+
+		addresses = append(addresses, ReverseAddress{
+			Address: addr,
+			Name:    name,
+		})
+	}
+
+	return addresses, nil
+}
+
+// reconcileReverseDNS maintains the PTR RRsets of the addresses allocated to the cluster and reports them on the
+// cluster.
+//
+// Reverse zones are ClusterZones shared by every cluster and are never deleted. The PTR RRsets are controlled by the
+// cluster zone and deleted once their address is released. For a classless pool whose /24 zone is also managed, the
+// /24 zone delegates the classless zone and gets a CNAME per address.
+func (r *ZoneReconciler) reconcileReverseDNS(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	policy, err := parseReverseDNSPolicy(r.ConfigManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	backend, err := r.backend(ctx, zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	addresses, err := parseReverseAddresses(cluster.Annotations[AnnotationAddresses])
+	if err != nil {
+		logger.Info("ignoring invalid addresses", "cluster", cluster.Name, "err", err)
+
+		return ctrl.Result{}, r.setReverseDNSCondition(ctx, cluster, metav1.ConditionFalse, InvalidReverseAddressReason, err.Error())
+	}
+
+	if backendOwnsZones(backend) {
+		if len(addresses) == 0 {
+			return ctrl.Result{}, r.removeReverseDNSCondition(ctx, cluster)
+		}
+
+		message := "reverse zones are only managed with the crd backend"
+
+		return ctrl.Result{}, r.setReverseDNSCondition(ctx, cluster, metav1.ConditionFalse, UnsupportedBackendReason, message)
+	}
+
+	var rrsetList pdnsv1.RRsetList
+	err = r.List(ctx, &rrsetList, client.HasLabels{LabelReverseDNS})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Reverse names claimed by the zones of other clusters, records of a zone the cluster migrates from are taken over.
+	claimed := make(map[string]string)
+	for _, rrset := range rrsetList.Items {
+		if rrset.Namespace == zone.Namespace && rrset.Labels[dockyardsv1.LabelClusterName] == cluster.Name {
+			continue
+		}
+
+		claimed[rrset.Spec.Name] = rrset.Namespace + "/" + rrset.Labels[LabelReverseDNS]
+	}
+
+	var pools []*ReversePool
+	var records []BackendRecord
+	var invalid []string
+
+	for _, address := range addresses {
+		pool := policy.Pool(address.Address)
+		if pool == nil {
+			invalid = append(invalid, fmt.Sprintf("address %s is not in a reverse zone pool", address.Address))
+
+			continue
+		}
+
+		ptrName := pool.PTRName(address.Address)
+
+		owner, found := claimed[ptrName]
+		if found {
+			invalid = append(invalid, fmt.Sprintf("address %s is allocated to zone %s", address.Address, owner))
+
+			continue
+		}
+
+		if !slices.ContainsFunc(pools, func(other *ReversePool) bool { return other.Zone == pool.Zone }) {
+			pools = append(pools, pool)
+		}
+
+		records = append(records, BackendRecord{
+			ID: "ptr." + strings.TrimSuffix(ptrName, ".") + "." + zone.Name,
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "PTR",
+				TTL:     uint32(zoneTTL),
+				Name:    ptrName,
+				Records: []string{address.Target(zone.Name)},
+				ZoneRef: pdnsv1.ZoneRef{
+					Name: pool.Zone,
+					Kind: "ClusterZone",
+				},
+			},
+		})
+
+		if pool.Classless() && policy.ZonePool(pool.ParentZone()) != nil {
+			name := reverseName(address.Address)
+
+			records = append(records, BackendRecord{
+				ID: "cname." + strings.TrimSuffix(name, ".") + "." + zone.Name,
+				Spec: pdnsv1.RRsetSpec{
+					Type:    "CNAME",
+					TTL:     uint32(zoneTTL),
+					Name:    name,
+					Records: []string{ptrName},
+					ZoneRef: pdnsv1.ZoneRef{
+						Name: pool.ParentZone(),
+						Kind: "ClusterZone",
+					},
+				},
+			})
+		}
+	}
+
+	desired := make(map[string]bool)
+	for _, record := range records {
+		desired[record.ID] = true
+	}
+
+	// Released records, and those of a zone the cluster migrates from, are deleted before any record is applied so
+	// that two RRsets never publish the same reverse name.
+	for _, rrset := range rrsetList.Items {
+		if rrset.Namespace != zone.Namespace || rrset.Labels[dockyardsv1.LabelClusterName] != cluster.Name || desired[rrset.Name] {
+			continue
+		}
+
+		deleted, err := backend.DeleteRecord(ctx, zone, BackendRecord{ID: rrset.Name, Spec: rrset.Spec})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if deleted {
+			logger.Info("Deleted released reverse DNS RRSet", "zone", zone.Name, "rrset", rrset.Name)
+		}
+	}
+
+	for _, pool := range pools {
+		err := r.reconcileReverseZone(ctx, policy, pool)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	for _, record := range records {
+		record.Labels = map[string]string{
+			LabelReverseDNS: zone.Name,
+		}
+
+		operationResult, err := backend.EnsureRecord(ctx, zone, record)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Reconciled reverse DNS RRSet", "zone", zone.Name, "rrset", record.ID, "operationResult", operationResult)
+	}
+
+	if len(addresses) == 0 {
+		return ctrl.Result{}, r.removeReverseDNSCondition(ctx, cluster)
+	}
+
+	if len(invalid) > 0 {
+		return ctrl.Result{}, r.setReverseDNSCondition(ctx, cluster, metav1.ConditionFalse, InvalidReverseAddressReason, strings.Join(invalid, "; "))
+	}
+
+	return ctrl.Result{}, r.setReverseDNSCondition(ctx, cluster, metav1.ConditionTrue, ReverseDNSReconciledReason, fmt.Sprintf("Reconciled %d addresses", len(addresses)))
+}
+
+// reconcileReverseZone applies the ClusterZone of a pool and, for a classless pool whose /24 zone is also managed,
+// the NS RRset that delegates it.
+func (r *ZoneReconciler) reconcileReverseZone(ctx context.Context, policy *ReverseDNSPolicy, pool *ReversePool) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	if len(policy.Nameservers) == 0 {
+		return errors.New("no nameservers for reverse zones")
+	}
+
+	labels := map[string]string{
+		LabelZoneType: ZoneTypeReverse,
+	}
+
+	zone := pdnsv1.ClusterZone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: pool.Zone,
+		},
+	}
+
+	zoneAC := clusterZoneApply(zone.Name, pdnsv1.ZoneSpec{
+		Kind:        zoneKindNative,
+		Nameservers: policy.Nameservers,
+	})
+
+	zoneAC.WithLabels(labels)
+
+	operationResult, err := applyObject(ctx, r.Client, &zone, zoneAC)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Reconciled reverse ClusterZone", "zone", zone.Name, "operationResult", operationResult)
+
+	if !pool.Classless() || policy.ZonePool(pool.ParentZone()) == nil {
+		return nil
+	}
+
+	var records []string
+	for _, nameserver := range policy.Nameservers {
+		records = append(records, nameserver+".")
+	}
+
+	rrset := pdnsv1.ClusterRRset{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "delegation." + pool.Zone,
+		},
+	}
+
+	rrsetAC := clusterRRsetApply(rrset.Name, pdnsv1.RRsetSpec{
+		Type:    "NS",
+		TTL:     uint32(zoneTTL),
+		Name:    pool.Zone + ".",
+		Records: records,
+		ZoneRef: pdnsv1.ZoneRef{
+			Name: pool.ParentZone(),
+			Kind: "ClusterZone",
+		},
+	})
+
+	rrsetAC.WithLabels(labels)
+
+	operationResult, err = applyObject(ctx, r.Client, &rrset, rrsetAC)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Reconciled reverse ClusterRRset", "rrset", rrset.Name, "operationResult", operationResult)
+
+	return nil
+}
+
+// setReverseDNSCondition patches the reverse DNS condition of the cluster status.
+func (r *ZoneReconciler) setReverseDNSCondition(ctx context.Context, cluster *dockyardsv1.Cluster, status metav1.ConditionStatus, reason, message string) error {
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	condition := metav1.Condition{
+		Type:               ReverseDNSReadyCondition,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: cluster.Generation,
+	}
+
+	if !meta.SetStatusCondition(&cluster.Status.Conditions, condition) {
+		return nil
+	}
+
+	return r.Status().Patch(ctx, cluster, patch)
+}
+
+// removeReverseDNSCondition removes the reverse DNS condition from the cluster status.
+func (r *ZoneReconciler) removeReverseDNSCondition(ctx context.Context, cluster *dockyardsv1.Cluster) error {
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	if !meta.RemoveStatusCondition(&cluster.Status.Conditions, ReverseDNSReadyCondition) {
+		return nil
+	}
+
+	return r.Status().Patch(ctx, cluster, patch)
+}
@@ -0,0 +1,480 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseReversePools(t *testing.T) {
+	tt := []struct {
+		name     string
+		value    string
+		expected []string
+		invalid  bool
+	}{
+		{
+			name:  "test octet boundaries",
+			value: "10.0.0.0/8, 172.16.0.0/16,192.0.2.0/24",
+			expected: []string{
+				"10.in-addr.arpa",
+				"16.172.in-addr.arpa",
+				"2.0.192.in-addr.arpa",
+			},
+		},
+		{
+			name:  "test classless",
+			value: "198.51.100.64/27,198.51.100.128/25=customer.100.51.198.in-addr.arpa.",
+			expected: []string{
+				"64-27.100.51.198.in-addr.arpa",
+				"customer.100.51.198.in-addr.arpa",
+			},
+		},
+		{
+			name:  "test ipv6",
+			value: "2001:db8::/32,2001:db8:1234::/52",
+			expected: []string{
+				"8.b.d.0.1.0.0.2.ip6.arpa",
+				"0.4.3.2.1.8.b.d.0.1.0.0.2.ip6.arpa",
+			},
+		},
+		{
+			name: "test empty",
+		},
+		{
+			name:    "test prefix length",
+			value:   "192.0.2.0/23",
+			invalid: true,
+		},
+		{
+			name:    "test host address",
+			value:   "192.0.2.1/24",
+			invalid: true,
+		},
+		{
+			name:    "test ipv6 nibble boundary",
+			value:   "2001:db8::/33",
+			invalid: true,
+		},
+		{
+			name:    "test zone name of octet pool",
+			value:   "192.0.2.0/24=customer.2.0.192.in-addr.arpa",
+			invalid: true,
+		},
+		{
+			name:    "test zone name outside in-addr.arpa",
+			value:   "192.0.2.0/26=reverse.example.com",
+			invalid: true,
+		},
+		{
+			name:    "test duplicate pool",
+			value:   "192.0.2.0/24,192.0.2.0/24",
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pools, err := parseReversePools(tc.value)
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected validation error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual []string
+			for _, pool := range pools {
+				actual = append(actual, pool.Zone)
+			}
+
+			if !cmp.Equal(actual, tc.expected) {
+				t.Error(cmp.Diff(tc.expected, actual))
+			}
+		})
+	}
+}
+
+func TestReversePoolPTRName(t *testing.T) {
+	tt := []struct {
+		name     string
+		pool     string
+		address  string
+		expected string
+	}{
+		{
+			name:     "test ipv4",
+			pool:     "192.0.2.0/24",
+			address:  "192.0.2.10",
+			expected: "10.2.0.192.in-addr.arpa.",
+		},
+		{
+			name:     "test classless",
+			pool:     "192.0.2.64/27",
+			address:  "192.0.2.70",
+			expected: "70.64-27.2.0.192.in-addr.arpa.",
+		},
+		{
+			name:     "test ipv6",
+			pool:     "2001:db8::/32",
+			address:  "2001:db8::1",
+			expected: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pools, err := parseReversePools(tc.pool)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := pools[0].PTRName(netip.MustParseAddr(tc.address))
+			if actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReconcileReverseDNS(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testing",
+			Annotations: map[string]string{
+				AnnotationAddresses: "192.0.2.10=api, 192.0.2.70, 2001:db8::1=ingress",
+			},
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+			UID:       "a1b2c3",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: cluster.Name,
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&cluster, &zone).WithStatusSubresource(&cluster).Build()
+
+	r := ZoneReconciler{
+		Client: c,
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyReverseZones):       "192.0.2.0/24,192.0.2.64/27,2001:db8::/32",
+			string(KeyReverseNameservers): "ns1.example.com",
+		}),
+	}
+
+	reverseRRsets := func(t *testing.T) map[string][]string {
+		var rrsetList pdnsv1.RRsetList
+		err := c.List(ctx, &rrsetList, client.InNamespace("testing"), client.MatchingLabels{LabelReverseDNS: zone.Name})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rrsets := make(map[string][]string)
+		for _, rrset := range rrsetList.Items {
+			rrsets[rrset.Name] = rrset.Spec.Records
+		}
+
+		return rrsets
+	}
+
+	reverseCondition := func(t *testing.T) *metav1.Condition {
+		var actual dockyardsv1.Cluster
+		err := c.Get(ctx, client.ObjectKeyFromObject(&cluster), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return meta.FindStatusCondition(actual.Status.Conditions, ReverseDNSReadyCondition)
+	}
+
+	t.Run("test allocate", func(t *testing.T) {
+		_, err := r.reconcileReverseDNS(ctx, &zone, &cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string][]string{
+			"ptr.10.2.0.192.in-addr.arpa.org-test.test.com":                                                  {"api.org-test.test.com."},
+			"ptr.70.64-27.2.0.192.in-addr.arpa.org-test.test.com":                                            {"org-test.test.com."},
+			"cname.70.2.0.192.in-addr.arpa.org-test.test.com":                                                {"70.64-27.2.0.192.in-addr.arpa."},
+			"ptr.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.org-test.test.com": {"ingress.org-test.test.com."},
+		}
+
+		actual := reverseRRsets(t)
+		if !cmp.Equal(actual, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual))
+		}
+
+		for _, name := range []string{"2.0.192.in-addr.arpa", "64-27.2.0.192.in-addr.arpa", "8.b.d.0.1.0.0.2.ip6.arpa"} {
+			var clusterZone pdnsv1.ClusterZone
+			err := c.Get(ctx, client.ObjectKey{Name: name}, &clusterZone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(clusterZone.Spec.Nameservers, []string{"ns1.example.com"}) {
+				t.Errorf("unexpected nameservers %v", clusterZone.Spec.Nameservers)
+			}
+		}
+
+		var delegation pdnsv1.ClusterRRset
+		err = c.Get(ctx, client.ObjectKey{Name: "delegation.64-27.2.0.192.in-addr.arpa"}, &delegation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if delegation.Spec.Type != "NS" || delegation.Spec.ZoneRef.Name != "2.0.192.in-addr.arpa" {
+			t.Errorf("unexpected delegation %v", delegation.Spec)
+		}
+
+		condition := reverseCondition(t)
+		if condition == nil || condition.Status != metav1.ConditionTrue {
+			t.Errorf("expected reverse DNS to be ready, got %v", condition)
+		}
+	})
+
+	t.Run("test release", func(t *testing.T) {
+		cluster.Annotations[AnnotationAddresses] = "192.0.2.10=api"
+
+		_, err := r.reconcileReverseDNS(ctx, &zone, &cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string][]string{
+			"ptr.10.2.0.192.in-addr.arpa.org-test.test.com": {"api.org-test.test.com."},
+		}
+
+		actual := reverseRRsets(t)
+		if !cmp.Equal(actual, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual))
+		}
+	})
+
+	t.Run("test address of other cluster", func(t *testing.T) {
+		otherZone := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "org-other.test.com",
+				Namespace: "other",
+				UID:       "d4e5f6",
+				Labels: map[string]string{
+					dockyardsv1.LabelClusterName: "other",
+				},
+			},
+		}
+
+		otherCluster := dockyardsv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "other",
+				Namespace: "other",
+				Annotations: map[string]string{
+					AnnotationAddresses: "192.0.2.10",
+				},
+			},
+		}
+
+		err := c.Create(ctx, &otherCluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileReverseDNS(ctx, &otherZone, &otherCluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rrsetList pdnsv1.RRsetList
+		err = c.List(ctx, &rrsetList, client.InNamespace("other"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rrsetList.Items) != 0 {
+			t.Errorf("expected no rrsets, got %d", len(rrsetList.Items))
+		}
+
+		condition := meta.FindStatusCondition(otherCluster.Status.Conditions, ReverseDNSReadyCondition)
+		if condition == nil || condition.Reason != InvalidReverseAddressReason {
+			t.Errorf("expected invalid address condition, got %v", condition)
+		}
+	})
+
+	t.Run("test address of other cluster in namespace", func(t *testing.T) {
+		neighbourZone := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "org-neighbour.test.com",
+				Namespace: "testing",
+				UID:       "g7h8i9",
+				Labels: map[string]string{
+					dockyardsv1.LabelClusterName: "neighbour",
+				},
+			},
+		}
+
+		neighbourCluster := dockyardsv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "neighbour",
+				Namespace: "testing",
+				Annotations: map[string]string{
+					AnnotationAddresses: "192.0.2.10",
+				},
+			},
+		}
+
+		err := c.Create(ctx, &neighbourCluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileReverseDNS(ctx, &neighbourZone, &neighbourCluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		condition := meta.FindStatusCondition(neighbourCluster.Status.Conditions, ReverseDNSReadyCondition)
+		if condition == nil || condition.Reason != InvalidReverseAddressReason {
+			t.Errorf("expected invalid address condition, got %v", condition)
+		}
+
+		cluster.Annotations[AnnotationAddresses] = "192.0.2.11=api"
+
+		_, err = r.reconcileReverseDNS(ctx, &zone, &cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileReverseDNS(ctx, &neighbourZone, &neighbourCluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rrset pdnsv1.RRset
+		err = c.Get(ctx, client.ObjectKey{Name: "ptr.10.2.0.192.in-addr.arpa.org-neighbour.test.com", Namespace: "testing"}, &rrset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !metav1.IsControlledBy(&rrset, &neighbourZone) || len(rrset.OwnerReferences) != 1 {
+			t.Errorf("expected rrset to be only owned by the neighbour zone, got %v", rrset.OwnerReferences)
+		}
+
+		expected := map[string][]string{
+			"ptr.11.2.0.192.in-addr.arpa.org-test.test.com": {"api.org-test.test.com."},
+		}
+
+		actual := reverseRRsets(t)
+		if !cmp.Equal(actual, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual))
+		}
+
+		cluster.Annotations[AnnotationAddresses] = "192.0.2.10=api"
+		neighbourCluster.Annotations[AnnotationAddresses] = ""
+
+		_, err = r.reconcileReverseDNS(ctx, &neighbourZone, &neighbourCluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("test address outside pools", func(t *testing.T) {
+		cluster.Annotations[AnnotationAddresses] = "192.0.2.10=api,203.0.113.1"
+
+		_, err := r.reconcileReverseDNS(ctx, &zone, &cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		condition := reverseCondition(t)
+		if condition == nil || condition.Reason != InvalidReverseAddressReason {
+			t.Errorf("expected invalid address condition, got %v", condition)
+		}
+
+		if len(reverseRRsets(t)) != 1 {
+			t.Error("expected allocated address to keep its rrset")
+		}
+	})
+
+	t.Run("test unannotated", func(t *testing.T) {
+		delete(cluster.Annotations, AnnotationAddresses)
+
+		_, err := r.reconcileReverseDNS(ctx, &zone, &cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(reverseRRsets(t)) != 0 {
+			t.Error("expected rrsets to be deleted")
+		}
+
+		if reverseCondition(t) != nil {
+			t.Error("expected condition to be removed")
+		}
+	})
+
+	t.Run("test api backend", func(t *testing.T) {
+		r := ZoneReconciler{
+			Client:        c,
+			ConfigManager: r.ConfigManager,
+			Backend:       NewMemoryBackend(),
+		}
+
+		cluster.Annotations[AnnotationAddresses] = "192.0.2.10=api"
+
+		_, err := r.reconcileReverseDNS(ctx, &zone, &cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(reverseRRsets(t)) != 0 {
+			t.Error("expected no rrsets")
+		}
+
+		condition := reverseCondition(t)
+		if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != UnsupportedBackendReason {
+			t.Errorf("expected unsupported backend condition, got %v", condition)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=delete
+
+// rfc2136Metadata are the zone metadata kinds that authorize dynamic updates with the zone TSIG key.
+//
+// TSIG-ALLOW-AXFR is shared with the transfer TSIG key and is reconciled by reconcileTSIGAllowAXFR instead.
+var rfc2136Metadata = []string{
+	pdnsapi.MetadataTSIGAllowDNSUpdate,
+	pdnsapi.MetadataAllowDNSUpdateFrom,
+}
+
+// tsigSecretName returns the name of the secret holding the TSIG key of a zone.
+func tsigSecretName(zone *pdnsv1.Zone) string {
+	return "tsig." + zone.Name
+}
+
+// externalDNSProvider returns the ExternalDNS provider for the zone, the annotation overrides the config key.
+func (r *ZoneReconciler) externalDNSProvider(zone *pdnsv1.Zone) (string, error) {
+	provider, found := zone.Annotations[AnnotationExternalDNSProvider]
+	if !found {
+		provider = r.GetValueOrDefault(KeyExternalDNSProvider, externalDNSProviderPDNS)
+	}
+
+	switch provider {
+	case externalDNSProviderPDNS, externalDNSProviderRFC2136:
+		return provider, nil
+	default:
+		return "", fmt.Errorf("unsupported ExternalDNS provider %s", provider)
+	}
+}
+
+// allowDNSUpdateFrom returns the networks that may send dynamic updates.
+func (r *ZoneReconciler) allowDNSUpdateFrom() ([]string, error) {
+	value := r.GetValueOrDefault(KeyAllowDNSUpdateFrom, defaultAllowDNSUpdateFrom)
+
+	var networks []string
+
+	for _, network := range strings.Split(value, ",") {
+		network = strings.TrimSpace(network)
+		if network == "" {
+			continue
+		}
+
+		_, _, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %w", KeyAllowDNSUpdateFrom, err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("no value for config key `%s`", KeyAllowDNSUpdateFrom)
+	}
+
+	return networks, nil
+}
+
+// reconcileExternalDNSRFC2136 configures ExternalDNS to publish records through authenticated dynamic updates.
+//
+// Every zone gets its own TSIG key that PowerDNS only accepts for updates and transfers of that zone.
+func (r *ZoneReconciler) reconcileExternalDNSRFC2136(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, ips *PDNSIPs) (ctrl.Result, error) {
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	key, err := r.reconcileTSIGKey(ctx, pdnsClient, zone, tsigSecretName(zone), zone.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	networks, err := r.allowDNSUpdateFrom()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = pdnsClient.EnsureZoneMetadata(ctx, zone.Name, pdnsapi.MetadataTSIGAllowDNSUpdate, []string{key.Name})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = pdnsClient.EnsureZoneMetadata(ctx, zone.Name, pdnsapi.MetadataAllowDNSUpdateFrom, networks)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = r.reconcileTSIGAllowAXFR(ctx, pdnsClient, zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	credentials := map[string]string{
+		"rfc2136TsigSecret": key.Key,
+	}
+
+	env := map[string]string{
+		"EXTERNAL_DNS_RFC2136_HOST":            ips.DNSIP,
+		"EXTERNAL_DNS_RFC2136_PORT":            "53",
+		"EXTERNAL_DNS_RFC2136_ZONE":            zone.Name,
+		"EXTERNAL_DNS_RFC2136_TSIG_KEYNAME":    key.Name,
+		"EXTERNAL_DNS_RFC2136_TSIG_SECRET_ALG": key.Algorithm,
+		"EXTERNAL_DNS_RFC2136_TSIG_AXFR":       "true",
+	}
+
+	return r.reconcileExternalDNSWorkload(ctx, zone, cluster, externalDNSProviderRFC2136, credentials, env)
+}
+
+// deleteRFC2136 revokes the dynamic update authorization and TSIG key of a zone that no longer uses RFC 2136.
+func (r *ZoneReconciler) deleteRFC2136(ctx context.Context, zone *pdnsv1.Zone, ips *PDNSIPs) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var secret corev1.Secret
+	err := r.Get(ctx, client.ObjectKey{Name: tsigSecretName(zone), Namespace: zone.Namespace}, &secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range rfc2136Metadata {
+		err := pdnsClient.DeleteZoneMetadata(ctx, zone.Name, kind)
+		if pdnsapi.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	err = r.reconcileTSIGAllowAXFR(ctx, pdnsClient, zone)
+	if err != nil {
+		return err
+	}
+
+	err = pdnsClient.DeleteTSIGKey(ctx, zone.Name)
+	if pdnsapi.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	err = r.Delete(ctx, &secret)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	logger.Info("Deleted RFC 2136 TSIG key", "zone", zone.Name, "secret", secret.Name)
+
+	return nil
+}
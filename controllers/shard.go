@@ -0,0 +1,282 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Shard selects the clusters and zones reconciled by one of several instances sharing the Dockyards API.
+//
+// Zones are claimed with the shard label, which is only ever set on unclaimed zones using an optimistic lock or when
+// the zone is created, so two instances never patch the same zone. A shard releases the zones of clusters and
+// organizations it no longer matches, see releaseZones. A nil shard is the unsharded instance and only handles unlabelled zones.
+type Shard struct {
+	Name string
+
+	// Namespaces limits the shard to clusters and organizations in the namespaces, all namespaces when empty.
+	Namespaces []string
+
+	// ClusterSelector and OrganizationSelector limit the shard to matching clusters, everything when nil.
+	ClusterSelector      labels.Selector
+	OrganizationSelector labels.Selector
+}
+
+// name returns the shard label value of zones owned by the shard.
+func (s *Shard) name() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.Name
+}
+
+// matchesNamespace reports whether the namespace belongs to the shard.
+func (s *Shard) matchesNamespace(namespace string) bool {
+	if s == nil || len(s.Namespaces) == 0 {
+		return true
+	}
+
+	return slices.Contains(s.Namespaces, namespace)
+}
+
+// MatchesOrganization reports whether the zones of the organization belong to the shard.
+func (s *Shard) MatchesOrganization(organization *dockyardsv1.Organization) bool {
+	if s == nil {
+		return true
+	}
+
+	if organization.Spec.NamespaceRef != nil && !s.matchesNamespace(organization.Spec.NamespaceRef.Name) {
+		return false
+	}
+
+	return s.OrganizationSelector == nil || s.OrganizationSelector.Matches(labels.Set(organization.Labels))
+}
+
+// MatchesCluster reports whether the zones of the cluster owned by the organization belong to the shard.
+func (s *Shard) MatchesCluster(cluster *dockyardsv1.Cluster, organization *dockyardsv1.Organization) bool {
+	if s == nil {
+		return true
+	}
+
+	if !s.matchesNamespace(cluster.Namespace) {
+		return false
+	}
+
+	if s.ClusterSelector != nil && !s.ClusterSelector.Matches(labels.Set(cluster.Labels)) {
+		return false
+	}
+
+	return s.OrganizationSelector == nil || s.OrganizationSelector.Matches(labels.Set(organization.Labels))
+}
+
+// Owns reports whether the object is claimed by the shard.
+func (s *Shard) Owns(obj metav1.Object) bool {
+	return obj.GetLabels()[LabelShard] == s.name()
+}
+
+// Predicate filters events of zones not claimed by the shard.
+func (s *Shard) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return s.Owns(obj)
+	})
+}
+
+// ClaimablePredicate filters events of zones claimed by another shard, so that a zone released by another shard is
+// claimed by the next matching one.
+func (s *Shard) ClaimablePredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return s.Owns(obj) || obj.GetLabels()[LabelShard] == ""
+	})
+}
+
+// setLabel marks a zone created or patched by the shard as claimed.
+func (s *Shard) setLabel(obj metav1.Object) {
+	if s.name() == "" {
+		return
+	}
+
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = make(map[string]string)
+	}
+
+	objLabels[LabelShard] = s.name()
+
+	obj.SetLabels(objLabels)
+}
+
+// applyLabel keeps the claim of the shard on a zone it applies.
+func (s *Shard) applyLabel(ac *metav1ac.ObjectMetaApplyConfiguration) {
+	if s.name() == "" {
+		return
+	}
+
+	ac.WithLabels(map[string]string{
+		LabelShard: s.name(),
+	})
+}
+
+// errClaimLost is returned by applyZone when another instance created the zone first.
+var errClaimLost = errors.New("zone was created by another instance")
+
+// claimZone reports whether the shard may patch the zone, claiming it first if it exists unclaimed.
+//
+// A zone that does not exist yet is claimed when applyZone creates it.
+func (s *Shard) claimZone(ctx context.Context, c client.Client, key client.ObjectKey) (bool, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var zone pdnsv1.Zone
+	err := c.Get(ctx, key, &zone)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if s.Owns(&zone) {
+		return true, nil
+	}
+
+	if zone.Labels[LabelShard] != "" {
+		logger.Info("ignoring zone claimed by another shard", "zone", zone.Name, "shard", zone.Labels[LabelShard])
+
+		return false, nil
+	}
+
+	patch := client.MergeFromWithOptions(zone.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+	s.setLabel(&zone)
+
+	err = c.Patch(ctx, &zone, patch)
+	if err != nil {
+		return false, fmt.Errorf("error claiming zone %s: %w", zone.Name, err)
+	}
+
+	logger.Info("Claimed DNS Zone", "zone", zone.Name, "shard", s.name())
+
+	return true, nil
+}
+
+// releaseZones removes the claim of the shard from the zones matching the labels, so that the shard the cluster or
+// organization moved to can claim them with claimZone.
+//
+// Paused zones keep their claim until they are resumed. The label is removed using an optimistic lock, like it is set.
+func (s *Shard) releaseZones(ctx context.Context, c client.Client, configManager *dyconfig.ConfigManager, namespace string, matchingLabels client.MatchingLabels) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	if s.name() == "" {
+		return nil
+	}
+
+	selector := client.MatchingLabels{
+		LabelShard: s.name(),
+	}
+
+	for key, value := range matchingLabels {
+		selector[key] = value
+	}
+
+	var zoneList pdnsv1.ZoneList
+	err := c.List(ctx, &zoneList, client.InNamespace(namespace), selector)
+	if err != nil {
+		return err
+	}
+
+	for i := range zoneList.Items {
+		zone := &zoneList.Items[i]
+
+		reason, err := pauseReason(configManager, zone)
+		if err != nil {
+			return err
+		}
+
+		if reason != "" {
+			logger.Info("Skipping release of paused DNS Zone", "zone", zone.Name, "reason", reason)
+
+			continue
+		}
+
+		patch := client.MergeFromWithOptions(zone.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+		delete(zone.Labels, LabelShard)
+
+		err = c.Patch(ctx, zone, patch)
+		if client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("error releasing zone %s: %w", zone.Name, err)
+		}
+
+		logger.Info("Released DNS Zone", "zone", zone.Name, "shard", s.name())
+	}
+
+	return nil
+}
+
+// applyZone applies a zone the shard claimed with claimZone.
+//
+// A zone that does not exist yet is created with a plain create carrying the shard label, so when instances race to
+// create it only one succeeds. The others get errClaimLost and leave the zone alone, their next claim sees the label of
+// the winner.
+func (s *Shard) applyZone(ctx context.Context, c client.Client, zone *pdnsv1.Zone, ac *zoneApplyConfiguration) (controllerutil.OperationResult, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	err := c.Get(ctx, client.ObjectKeyFromObject(zone), zone)
+	if client.IgnoreNotFound(err) != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	if err == nil {
+		return applyObject(ctx, c, zone, ac)
+	}
+
+	b, err := json.Marshal(ac)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	err = json.Unmarshal(b, zone)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	err = c.Create(ctx, zone, client.FieldOwner(fieldManager))
+	if apierrors.IsAlreadyExists(err) {
+		logger.Info("ignoring zone created by another instance", "zone", zone.Name, "shard", s.name())
+
+		return controllerutil.OperationResultNone, errClaimLost
+	}
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	return controllerutil.OperationResultCreated, nil
+}
@@ -0,0 +1,443 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestShardMatchesCluster(t *testing.T) {
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test",
+			Labels: map[string]string{"tier": "regulated"},
+		},
+		Spec: dockyardsv1.OrganizationSpec{
+			NamespaceRef: &corev1.LocalObjectReference{Name: "testing"},
+		},
+	}
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testing",
+			Labels:    map[string]string{"region": "eu-north"},
+		},
+	}
+
+	tt := []struct {
+		name     string
+		shard    *Shard
+		expected bool
+	}{
+		{
+			name:     "test unsharded",
+			expected: true,
+		},
+		{
+			name: "test matching namespace",
+			shard: &Shard{
+				Name:       "eu",
+				Namespaces: []string{"other", "testing"},
+			},
+			expected: true,
+		},
+		{
+			name: "test other namespace",
+			shard: &Shard{
+				Name:       "eu",
+				Namespaces: []string{"other"},
+			},
+		},
+		{
+			name: "test matching selectors",
+			shard: &Shard{
+				Name:                 "eu",
+				ClusterSelector:      labels.SelectorFromSet(labels.Set{"region": "eu-north"}),
+				OrganizationSelector: labels.SelectorFromSet(labels.Set{"tier": "regulated"}),
+			},
+			expected: true,
+		},
+		{
+			name: "test other cluster selector",
+			shard: &Shard{
+				Name:            "us",
+				ClusterSelector: labels.SelectorFromSet(labels.Set{"region": "us-east"}),
+			},
+		},
+		{
+			name: "test other organization selector",
+			shard: &Shard{
+				Name:                 "eu",
+				OrganizationSelector: labels.SelectorFromSet(labels.Set{"tier": "standard"}),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := tc.shard.MatchesCluster(&cluster, &organization)
+			if actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestShardClaimZone(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = pdnsv1.AddToScheme(scheme)
+
+	zones := []pdnsv1.Zone{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "org-unclaimed.test.com",
+				Namespace: "testing",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "org-eu.test.com",
+				Namespace: "testing",
+				Labels:    map[string]string{LabelShard: "eu"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "org-us.test.com",
+				Namespace: "testing",
+				Labels:    map[string]string{LabelShard: "us"},
+			},
+		},
+	}
+
+	tt := []struct {
+		name          string
+		shard         *Shard
+		zone          string
+		expected      bool
+		expectedLabel string
+	}{
+		{
+			name:     "test missing zone",
+			shard:    &Shard{Name: "eu"},
+			zone:     "org-missing.test.com",
+			expected: true,
+		},
+		{
+			name:          "test unclaimed zone",
+			shard:         &Shard{Name: "eu"},
+			zone:          "org-unclaimed.test.com",
+			expected:      true,
+			expectedLabel: "eu",
+		},
+		{
+			name:          "test owned zone",
+			shard:         &Shard{Name: "eu"},
+			zone:          "org-eu.test.com",
+			expected:      true,
+			expectedLabel: "eu",
+		},
+		{
+			name:          "test zone of other shard",
+			shard:         &Shard{Name: "eu"},
+			zone:          "org-us.test.com",
+			expectedLabel: "us",
+		},
+		{
+			name:     "test unsharded unclaimed zone",
+			zone:     "org-unclaimed.test.com",
+			expected: true,
+		},
+		{
+			name:          "test unsharded claimed zone",
+			zone:          "org-eu.test.com",
+			expectedLabel: "eu",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zones[0], &zones[1], &zones[2]).Build()
+
+			key := client.ObjectKey{Name: tc.zone, Namespace: "testing"}
+
+			actual, err := tc.shard.claimZone(ctx, c, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+
+			var zone pdnsv1.Zone
+			err = c.Get(ctx, key, &zone)
+			if client.IgnoreNotFound(err) != nil {
+				t.Fatal(err)
+			}
+
+			if zone.Labels[LabelShard] != tc.expectedLabel {
+				t.Errorf("expected shard label %q, got %q", tc.expectedLabel, zone.Labels[LabelShard])
+			}
+		})
+	}
+
+	t.Run("test stale claim", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zones[0]).Build()
+
+		var stale pdnsv1.Zone
+		err := c.Get(ctx, client.ObjectKeyFromObject(&zones[0]), &stale)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claimed, err := (&Shard{Name: "us"}).claimZone(ctx, c, client.ObjectKeyFromObject(&zones[0]))
+		if err != nil || !claimed {
+			t.Fatalf("expected zone to be claimed, got %t, %v", claimed, err)
+		}
+
+		patch := client.MergeFromWithOptions(stale.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+		(&Shard{Name: "eu"}).setLabel(&stale)
+
+		err = c.Patch(ctx, &stale, patch)
+		if err == nil {
+			t.Error("expected conflicting claim to fail")
+		}
+	})
+
+	t.Run("test racing creates", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		eu := &Shard{Name: "eu"}
+		us := &Shard{Name: "us"}
+
+		key := client.ObjectKey{Name: "org-new.test.com", Namespace: "testing"}
+
+		for _, shard := range []*Shard{eu, us} {
+			claimed, err := shard.claimZone(ctx, c, key)
+			if err != nil || !claimed {
+				t.Fatalf("expected missing zone to be claimable by %s, got %t, %v", shard.Name, claimed, err)
+			}
+		}
+
+		spec := pdnsv1.ZoneSpec{
+			Kind:        "Native",
+			Nameservers: []string{"ns1." + key.Name},
+		}
+
+		euAC := zoneApply(key.Name, key.Namespace, spec)
+		eu.applyLabel(euAC.ObjectMetaApplyConfiguration)
+
+		euZone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+
+		operationResult, err := eu.applyZone(ctx, c, &euZone, euAC)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if operationResult != controllerutil.OperationResultCreated {
+			t.Errorf("expected created, got %s", operationResult)
+		}
+
+		usAC := zoneApply(key.Name, key.Namespace, spec)
+		us.applyLabel(usAC.ObjectMetaApplyConfiguration)
+
+		// The zone of the losing shard was not found before the other shard created it.
+		usZone := pdnsv1.Zone{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+
+		racing := interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				return apierrors.NewNotFound(pdnsv1.GroupVersion.WithResource("zones").GroupResource(), key.Name)
+			},
+		}
+
+		_, err = us.applyZone(ctx, interceptor.NewClient(c, racing), &usZone, usAC)
+		if !errors.Is(err, errClaimLost) {
+			t.Fatalf("expected lost claim, got %v", err)
+		}
+
+		var actual pdnsv1.Zone
+		err = c.Get(ctx, key, &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual.Labels[LabelShard] != "eu" {
+			t.Errorf("expected zone to stay claimed by eu, got %q", actual.Labels[LabelShard])
+		}
+
+		claimed, err := us.claimZone(ctx, c, key)
+		if err != nil || claimed {
+			t.Errorf("expected zone not to be claimable by us, got %t, %v", claimed, err)
+		}
+	})
+}
+
+func TestShardHandover(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org",
+			UID:  "organization-uid",
+		},
+		Spec: dockyardsv1.OrganizationSpec{
+			NamespaceRef: &corev1.LocalObjectReference{Name: "testing"},
+		},
+	}
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testing",
+			UID:       "cluster-uid",
+			Labels:    map[string]string{"region": "eu-north"},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: dockyardsv1.GroupVersion.String(),
+					Kind:       dockyardsv1.OrganizationKind,
+					Name:       organization.Name,
+					UID:        organization.UID,
+				},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&organization, &cluster).WithStatusSubresource(&cluster).Build()
+
+	configManager := dyconfig.NewFakeConfigManager(map[string]string{
+		string(KeyManagementDomain): "test.com",
+		string(KeyPDNSName):         "pdns",
+		string(KeyPDNSNamespace):    "pdns",
+	})
+
+	shards := map[string]*DockyardsClusterReconciler{
+		"eu": {
+			Client:        c,
+			ConfigManager: configManager,
+			Shard: &Shard{
+				Name:            "eu",
+				ClusterSelector: labels.SelectorFromSet(labels.Set{"region": "eu-north"}),
+			},
+		},
+		"us": {
+			Client:        c,
+			ConfigManager: configManager,
+			Shard: &Shard{
+				Name:            "us",
+				ClusterSelector: labels.SelectorFromSet(labels.Set{"region": "us-east"}),
+			},
+		},
+	}
+
+	req := ctrl.Request{
+		NamespacedName: client.ObjectKeyFromObject(&cluster),
+	}
+
+	key := client.ObjectKey{Name: "org-test.test.com", Namespace: "testing"}
+
+	tt := []struct {
+		name          string
+		region        string
+		shard         string
+		expectedLabel string
+	}{
+		{
+			name:          "test claim",
+			region:        "eu-north",
+			shard:         "eu",
+			expectedLabel: "eu",
+		},
+		{
+			name:          "test claimed by previous shard",
+			region:        "us-east",
+			shard:         "us",
+			expectedLabel: "eu",
+		},
+		{
+			name:   "test release",
+			region: "us-east",
+			shard:  "eu",
+		},
+		{
+			name:          "test handover",
+			region:        "us-east",
+			shard:         "us",
+			expectedLabel: "us",
+		},
+		{
+			name:          "test previous shard",
+			region:        "us-east",
+			shard:         "eu",
+			expectedLabel: "us",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.Get(ctx, req.NamespacedName, &cluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cluster.Labels["region"] = tc.region
+
+			err = c.Update(ctx, &cluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = shards[tc.shard].Reconcile(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var zone pdnsv1.Zone
+			err = c.Get(ctx, key, &zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if zone.Labels[LabelShard] != tc.expectedLabel {
+				t.Errorf("expected shard label %q, got %q", tc.expectedLabel, zone.Labels[LabelShard])
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// transferZoneAnnotations are copied onto zones that are not cluster zones so they are served like the cluster zone.
+var transferZoneAnnotations = []string{
+	AnnotationZoneKind,
+	AnnotationSecondaryNameservers,
+	AnnotationSecondaryAddresses,
+	AnnotationTransferTSIG,
+}
+
+// ZoneTransferPolicy describes how a zone is replicated to secondary nameservers.
+type ZoneTransferPolicy struct {
+	Kind        string
+	Nameservers []string
+	Addresses   []string
+	TSIG        bool
+}
+
+// parseZoneTransferPolicy reads the zone kind and its secondaries from annotations, falling back to the config keys.
+//
+// Secondaries only apply to Master zones, Native zones rely on PowerDNS database replication.
+func parseZoneTransferPolicy(annotations map[string]string, configManager *dyconfig.ConfigManager) (*ZoneTransferPolicy, error) {
+	kind, found := annotations[AnnotationZoneKind]
+	if !found {
+		kind = configManager.GetValueOrDefault(KeyZoneKind, zoneKindNative)
+	}
+
+	switch {
+	case strings.EqualFold(kind, zoneKindNative):
+		return &ZoneTransferPolicy{Kind: zoneKindNative}, nil
+	case strings.EqualFold(kind, zoneKindMaster):
+	default:
+		return nil, fmt.Errorf("unsupported zone kind %s", kind)
+	}
+
+	policy := ZoneTransferPolicy{
+		Kind: zoneKindMaster,
+	}
+
+	nameservers, found := annotations[AnnotationSecondaryNameservers]
+	if !found {
+		nameservers = configManager.GetValueOrDefault(KeySecondaryNameservers, "")
+	}
+
+	for _, nameserver := range strings.Split(nameservers, ",") {
+		nameserver = strings.TrimSuffix(strings.TrimSpace(nameserver), ".")
+		if nameserver == "" {
+			continue
+		}
+
+		errs := validation.IsDNS1123Subdomain(nameserver)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("invalid secondary nameserver %s: %s", nameserver, strings.Join(errs, ", "))
+		}
+
+		policy.Nameservers = append(policy.Nameservers, nameserver)
+	}
+
+	addresses, found := annotations[AnnotationSecondaryAddresses]
+	if !found {
+		addresses = configManager.GetValueOrDefault(KeySecondaryAddresses, "")
+	}
+
+	for _, address := range strings.Split(addresses, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secondary address %s: %w", address, err)
+		}
+
+		policy.Addresses = append(policy.Addresses, addr.String())
+	}
+
+	if len(policy.Nameservers) == 0 {
+		return nil, fmt.Errorf("no secondary nameservers for zone kind %s", zoneKindMaster)
+	}
+
+	if len(policy.Addresses) == 0 {
+		return nil, fmt.Errorf("no secondary addresses for zone kind %s", zoneKindMaster)
+	}
+
+	transferTSIG, found := annotations[AnnotationTransferTSIG]
+	if !found {
+		transferTSIG = configManager.GetValueOrDefault(KeyTransferTSIG, "false")
+	}
+
+	enabled, err := strconv.ParseBool(transferTSIG)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s for transfer TSIG: %w", transferTSIG, err)
+	}
+
+	policy.TSIG = enabled
+
+	return &policy, nil
+}
+
+// ZoneNameservers returns the nameservers of a zone, the primary followed by any secondaries.
+func (p *ZoneTransferPolicy) ZoneNameservers(zoneName string) []string {
+	nameservers := []string{
+		"ns1." + zoneName,
+	}
+
+	return append(nameservers, p.Nameservers...)
+}
+
+// soaSerial returns the YYYYMMDDnn serial of an SOA record with the fields, which never falls below the serial
+// PowerDNS reports for the zone or the serial last written to AnnotationSOA.
+//
+// Secondaries only transfer a zone when its serial increases. PowerDNS increases the serial when other records are
+// changed through the API, see soaEditAPI, but not when the SOA record itself is written, so the serial is increased
+// here when the fields differ from the ones last written.
+func soaSerial(zone *pdnsv1.Zone, fields string, now time.Time) uint32 {
+	date, _ := strconv.ParseUint(now.Format("20060102"), 10, 32)
+
+	serial := uint32(date*100 + 1)
+
+	var current uint32
+	if zone.Status.Serial != nil {
+		current = *zone.Status.Serial
+	}
+
+	writtenSerial, writtenFields, found := strings.Cut(zone.Annotations[AnnotationSOA], " ")
+	if found {
+		written, err := strconv.ParseUint(writtenSerial, 10, 32)
+		if err == nil && uint32(written) > current {
+			current = uint32(written)
+		}
+	}
+
+	if found && writtenFields != fields && current >= serial {
+		return current + 1
+	}
+
+	if current > serial {
+		return current
+	}
+
+	return serial
+}
+
+// transferTSIGSecretName returns the name of the secret holding the transfer TSIG key of a zone.
+func transferTSIGSecretName(zone *pdnsv1.Zone) string {
+	return transferTSIGPrefix + zone.Name
+}
+
+// transferTSIGKeys returns the TSIG keys that may transfer the zone, the transfer key is first so that PowerDNS signs
+// notifications with it.
+func (r *ZoneReconciler) transferTSIGKeys(zone *pdnsv1.Zone) ([]string, error) {
+	policy, err := parseZoneTransferPolicy(zone.Annotations, r.ConfigManager)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := r.externalDNSProvider(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+
+	if policy.Kind == zoneKindMaster && policy.TSIG {
+		keys = append(keys, transferTSIGPrefix+zone.Name)
+	}
+
+	if provider == externalDNSProviderRFC2136 && zone.Labels[LabelZoneType] == "" {
+		keys = append(keys, zone.Name)
+	}
+
+	return keys, nil
+}
+
+// reconcileTSIGAllowAXFR sets the TSIG-ALLOW-AXFR metadata from the transfer and RFC 2136 keys of the zone.
+func (r *ZoneReconciler) reconcileTSIGAllowAXFR(ctx context.Context, pdnsClient *pdnsapi.Client, zone *pdnsv1.Zone) error {
+	keys, err := r.transferTSIGKeys(zone)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		err := pdnsClient.DeleteZoneMetadata(ctx, zone.Name, pdnsapi.MetadataTSIGAllowAXFR)
+
+		return pdnsapi.IgnoreNotFound(err)
+	}
+
+	return pdnsClient.EnsureZoneMetadata(ctx, zone.Name, pdnsapi.MetadataTSIGAllowAXFR, keys)
+}
+
+// reconcileZoneTransfers authorizes transfers to and notifications of the secondaries of a Master zone.
+//
+// With a transfer TSIG key, transfers are only authorized by the key and ALLOW-AXFR-FROM is left unset.
+func (r *ZoneReconciler) reconcileZoneTransfers(ctx context.Context, zone *pdnsv1.Zone, ips *PDNSIPs) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	policy, err := parseZoneTransferPolicy(zone.Annotations, r.ConfigManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if policy.Kind != zoneKindMaster {
+		return ctrl.Result{}, r.deleteZoneTransfers(ctx, zone, ips)
+	}
+
+	// The zone is marked before any metadata is set, so it is cleaned up even if reconciling the transfers fails.
+	if !metav1.HasAnnotation(zone.ObjectMeta, AnnotationZoneTransfers) {
+		patch := client.MergeFrom(zone.DeepCopy())
+
+		metav1.SetMetaDataAnnotation(&zone.ObjectMeta, AnnotationZoneTransfers, "true")
+
+		err := r.Patch(ctx, zone, patch)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if policy.TSIG {
+		_, err := r.reconcileTSIGKey(ctx, pdnsClient, zone, transferTSIGSecretName(zone), transferTSIGPrefix+zone.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		err = pdnsClient.DeleteZoneMetadata(ctx, zone.Name, pdnsapi.MetadataAllowAXFRFrom)
+		if pdnsapi.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	} else {
+		err := pdnsClient.EnsureZoneMetadata(ctx, zone.Name, pdnsapi.MetadataAllowAXFRFrom, policy.Addresses)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	err = r.reconcileTSIGAllowAXFR(ctx, pdnsClient, zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !policy.TSIG {
+		err := r.deleteTransferTSIGKey(ctx, pdnsClient, zone)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	err = pdnsClient.EnsureZoneMetadata(ctx, zone.Name, pdnsapi.MetadataAlsoNotify, policy.Addresses)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Reconciled zone transfers", "zone", zone.Name, "secondaries", policy.Nameservers, "tsig", policy.TSIG)
+
+	return ctrl.Result{}, nil
+}
+
+// deleteZoneTransfers revokes transfers and notifications of a zone that is no longer a Master zone.
+//
+// Only zones marked by reconcileZoneTransfers ever had transfers, so the PowerDNS API is not used for other zones.
+func (r *ZoneReconciler) deleteZoneTransfers(ctx context.Context, zone *pdnsv1.Zone, ips *PDNSIPs) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	if !metav1.HasAnnotation(zone.ObjectMeta, AnnotationZoneTransfers) {
+		return nil
+	}
+
+	pdnsClient, err := r.getPDNSClient(ctx, ips)
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range []string{pdnsapi.MetadataAlsoNotify, pdnsapi.MetadataAllowAXFRFrom} {
+		err := pdnsClient.DeleteZoneMetadata(ctx, zone.Name, kind)
+		if pdnsapi.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	err = r.reconcileTSIGAllowAXFR(ctx, pdnsClient, zone)
+	if err != nil {
+		return err
+	}
+
+	err = r.deleteTransferTSIGKey(ctx, pdnsClient, zone)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(zone.DeepCopy())
+
+	delete(zone.Annotations, AnnotationZoneTransfers)
+
+	err = r.Patch(ctx, zone, patch)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Deleted zone transfers", "zone", zone.Name)
+
+	return nil
+}
+
+// deleteTransferTSIGKey removes the transfer TSIG key of a zone from PowerDNS and deletes its secret.
+func (r *ZoneReconciler) deleteTransferTSIGKey(ctx context.Context, pdnsClient *pdnsapi.Client, zone *pdnsv1.Zone) error {
+	var secret corev1.Secret
+	err := r.Get(ctx, client.ObjectKey{Name: transferTSIGSecretName(zone), Namespace: zone.Namespace}, &secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = pdnsClient.DeleteTSIGKey(ctx, transferTSIGPrefix+zone.Name)
+	if pdnsapi.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	err = r.Delete(ctx, &secret)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,365 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"github.com/sudoswedenab/dockyards-pdns/test/fakepdns"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestZoneTransferPolicy(t *testing.T) {
+	tt := []struct {
+		name        string
+		config      map[string]string
+		annotations map[string]string
+		expected    *ZoneTransferPolicy
+		nameservers []string
+		invalid     bool
+	}{
+		{
+			name: "test default native",
+			config: map[string]string{
+				string(KeySecondaryNameservers): "ns1.secondary.net",
+			},
+			expected: &ZoneTransferPolicy{
+				Kind: "Native",
+			},
+			nameservers: []string{
+				"ns1.org-cluster.test.com",
+			},
+		},
+		{
+			name: "test master from config",
+			config: map[string]string{
+				string(KeyZoneKind):             "master",
+				string(KeySecondaryNameservers): "ns1.secondary.net., ns2.secondary.net",
+				string(KeySecondaryAddresses):   "192.0.2.1,2001:db8::1",
+			},
+			expected: &ZoneTransferPolicy{
+				Kind: "Master",
+				Nameservers: []string{
+					"ns1.secondary.net",
+					"ns2.secondary.net",
+				},
+				Addresses: []string{
+					"192.0.2.1",
+					"2001:db8::1",
+				},
+			},
+			nameservers: []string{
+				"ns1.org-cluster.test.com",
+				"ns1.secondary.net",
+				"ns2.secondary.net",
+			},
+		},
+		{
+			name: "test annotation overrides config",
+			config: map[string]string{
+				string(KeySecondaryNameservers): "ns1.secondary.net",
+				string(KeySecondaryAddresses):   "192.0.2.1",
+			},
+			annotations: map[string]string{
+				AnnotationZoneKind:           "Master",
+				AnnotationSecondaryAddresses: "198.51.100.1",
+				AnnotationTransferTSIG:       "true",
+			},
+			expected: &ZoneTransferPolicy{
+				Kind: "Master",
+				Nameservers: []string{
+					"ns1.secondary.net",
+				},
+				Addresses: []string{
+					"198.51.100.1",
+				},
+				TSIG: true,
+			},
+			nameservers: []string{
+				"ns1.org-cluster.test.com",
+				"ns1.secondary.net",
+			},
+		},
+		{
+			name: "test unsupported kind",
+			annotations: map[string]string{
+				AnnotationZoneKind: "Slave",
+			},
+			invalid: true,
+		},
+		{
+			name: "test master without secondaries",
+			config: map[string]string{
+				string(KeyZoneKind): "Master",
+			},
+			invalid: true,
+		},
+		{
+			name: "test invalid address",
+			config: map[string]string{
+				string(KeyZoneKind):             "Master",
+				string(KeySecondaryNameservers): "ns1.secondary.net",
+				string(KeySecondaryAddresses):   "192.0.2.0/24",
+			},
+			invalid: true,
+		},
+		{
+			name: "test invalid nameserver",
+			config: map[string]string{
+				string(KeyZoneKind):             "Master",
+				string(KeySecondaryNameservers): "ns1_secondary.net",
+				string(KeySecondaryAddresses):   "192.0.2.1",
+			},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := parseZoneTransferPolicy(tc.annotations, dyconfig.NewFakeConfigManager(tc.config))
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected validation error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(policy, tc.expected) {
+				t.Error(cmp.Diff(tc.expected, policy))
+			}
+
+			nameservers := policy.ZoneNameservers("org-cluster.test.com")
+			if !cmp.Equal(nameservers, tc.nameservers) {
+				t.Error(cmp.Diff(tc.nameservers, nameservers))
+			}
+		})
+	}
+}
+
+func TestSOASerial(t *testing.T) {
+	now := time.Date(2025, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+	fields := "ns1.test.com. hostmaster.test.com. 10800 3600 604800 3600"
+
+	tt := []struct {
+		name     string
+		serial   *uint32
+		written  string
+		expected uint32
+	}{
+		{
+			name:     "test new zone",
+			expected: 2025030401,
+		},
+		{
+			name:     "test older serial",
+			serial:   ptr.To(uint32(2025030305)),
+			expected: 2025030401,
+		},
+		{
+			name:     "test increased serial",
+			serial:   ptr.To(uint32(2025030407)),
+			expected: 2025030407,
+		},
+		{
+			name:     "test unchanged fields",
+			serial:   ptr.To(uint32(2025030407)),
+			written:  "2025030407 " + fields,
+			expected: 2025030407,
+		},
+		{
+			name:     "test changed fields",
+			serial:   ptr.To(uint32(2025030407)),
+			written:  "2025030407 ns1.other.net. hostmaster.test.com. 10800 3600 604800 3600",
+			expected: 2025030408,
+		},
+		{
+			name:     "test changed fields on a new day",
+			serial:   ptr.To(uint32(2025030305)),
+			written:  "2025030305 ns1.other.net. hostmaster.test.com. 10800 3600 604800 3600",
+			expected: 2025030401,
+		},
+		{
+			name:     "test stale status",
+			serial:   ptr.To(uint32(2025030407)),
+			written:  "2025030408 " + fields,
+			expected: 2025030408,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			zone := pdnsv1.Zone{
+				Status: pdnsv1.ZoneStatus{
+					Serial: tc.serial,
+				},
+			}
+
+			if tc.written != "" {
+				zone.Annotations = map[string]string{
+					AnnotationSOA: tc.written,
+				}
+			}
+
+			actual := soaSerial(&zone, fields, now)
+			if actual != tc.expected {
+				t.Errorf("expected serial %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReconcileZoneTransfers(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = corev1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	server := fakepdns.NewServer("test-api-key")
+	t.Cleanup(server.Close)
+
+	server.AddZone("org-cluster.test.com")
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-cluster.test.com",
+			Namespace: "testing",
+			UID:       "a1b2c3",
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zone).Build()
+
+	configManager := dyconfig.NewFakeConfigManager(map[string]string{
+		string(KeySecondaryNameservers): "ns1.secondary.net",
+		string(KeySecondaryAddresses):   "192.0.2.1",
+		string(KeyTransferTSIG):         "true",
+	})
+
+	r := ZoneReconciler{
+		Client:        c,
+		ConfigManager: configManager,
+	}
+
+	t.Run("test native zone without transfers", func(t *testing.T) {
+		_, err := r.reconcileZoneTransfers(ctx, &zone, &PDNSIPs{})
+		if err != nil {
+			t.Fatalf("expected no PowerDNS API calls, got %s", err)
+		}
+	})
+
+	r.PDNSClient = pdnsapi.NewClient(server.URL, "test-api-key")
+
+	t.Run("test master zone", func(t *testing.T) {
+		zone.Annotations = map[string]string{
+			AnnotationZoneKind: "Master",
+		}
+
+		err := c.Update(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileZoneTransfers(ctx, &zone, &PDNSIPs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actual pdnsv1.Zone
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !metav1.HasAnnotation(actual.ObjectMeta, AnnotationZoneTransfers) {
+			t.Error("expected zone to be marked with transfers")
+		}
+
+		expected := []string{"192.0.2.1"}
+		if !cmp.Equal(server.Metadata(pdnsapi.CanonicalName(zone.Name), pdnsapi.MetadataAlsoNotify), expected) {
+			t.Error(cmp.Diff(expected, server.Metadata(pdnsapi.CanonicalName(zone.Name), pdnsapi.MetadataAlsoNotify)))
+		}
+
+		expected = []string{transferTSIGPrefix + zone.Name}
+		if !cmp.Equal(server.Metadata(pdnsapi.CanonicalName(zone.Name), pdnsapi.MetadataTSIGAllowAXFR), expected) {
+			t.Error(cmp.Diff(expected, server.Metadata(pdnsapi.CanonicalName(zone.Name), pdnsapi.MetadataTSIGAllowAXFR)))
+		}
+
+		_, found := server.TSIGKey(pdnsapi.CanonicalName(transferTSIGPrefix + zone.Name))
+		if !found {
+			t.Error("expected transfer TSIG key to be created")
+		}
+	})
+
+	t.Run("test native zone with transfers", func(t *testing.T) {
+		zone.Annotations[AnnotationZoneKind] = "Native"
+
+		err := c.Update(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileZoneTransfers(ctx, &zone, &PDNSIPs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, kind := range []string{pdnsapi.MetadataAlsoNotify, pdnsapi.MetadataAllowAXFRFrom, pdnsapi.MetadataTSIGAllowAXFR} {
+			if len(server.Metadata(pdnsapi.CanonicalName(zone.Name), kind)) != 0 {
+				t.Errorf("expected metadata %s to be deleted", kind)
+			}
+		}
+
+		_, found := server.TSIGKey(pdnsapi.CanonicalName(transferTSIGPrefix + zone.Name))
+		if found {
+			t.Error("expected transfer TSIG key to be deleted")
+		}
+
+		var secret corev1.Secret
+		err = c.Get(ctx, client.ObjectKey{Name: transferTSIGSecretName(&zone), Namespace: zone.Namespace}, &secret)
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected transfer TSIG secret to be deleted, got %v", err)
+		}
+
+		var actual pdnsv1.Zone
+		err = c.Get(ctx, client.ObjectKeyFromObject(&zone), &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if metav1.HasAnnotation(actual.ObjectMeta, AnnotationZoneTransfers) {
+			t.Error("expected transfers mark to be removed")
+		}
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=create;patch
+
+// generateTSIGSecret returns a random base64 encoded secret suitable for hmac-sha256.
+func generateTSIGSecret() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// reconcileTSIGKey keeps a TSIG key in a secret owned by the zone and registers it with PowerDNS.
+//
+// The secret is generated once and reused afterwards so that consumers of the key are not invalidated.
+func (r *ZoneReconciler) reconcileTSIGKey(ctx context.Context, pdnsClient *pdnsapi.Client, zone *pdnsv1.Zone, secretName, keyName string) (*pdnsapi.TSIGKey, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: zone.Namespace,
+		},
+	}
+
+	err := r.Get(ctx, client.ObjectKeyFromObject(&secret), &secret)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+
+	tsigSecret := string(secret.Data[secretTSIGSecret])
+	if tsigSecret == "" || string(secret.Data[secretTSIGAlgorithm]) != tsigAlgorithm {
+		tsigSecret, err = generateTSIGSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ac := corev1ac.Secret(secret.Name, secret.Namespace).
+		WithLabels(zone.Labels).
+		WithLabels(map[string]string{
+			LabelSecret: secretTypeTSIG,
+		}).
+		WithOwnerReferences(ownerReferenceApplyConfiguration(zoneOwnerReference(zone))).
+		WithData(map[string][]byte{
+			secretTSIGSecret:    []byte(tsigSecret),
+			secretTSIGName:      []byte(keyName),
+			secretTSIGAlgorithm: []byte(tsigAlgorithm),
+		})
+
+	operationResult, err := applyObject(ctx, r.Client, &secret, ac)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Reconciled TSIG key secret", "zone", zone.Name, "secret", secret.Name, "operationResult", operationResult)
+
+	key := pdnsapi.TSIGKey{
+		Name:      keyName,
+		Algorithm: tsigAlgorithm,
+		Key:       string(secret.Data[secretTSIGSecret]),
+	}
+
+	err = pdnsClient.EnsureTSIGKey(ctx, &key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// getPDNSClient returns a PowerDNS API client of the backend with the addresses, authenticated with the key from its
+// secret.
+func (r *ZoneReconciler) getPDNSClient(ctx context.Context, ips *PDNSIPs) (*pdnsapi.Client, error) {
+	if r.PDNSClient != nil {
+		return r.PDNSClient, nil
+	}
+
+	if len(ips.APIIPs) == 0 {
+		return nil, errors.New("no available API addresses for PowerDNS")
+	}
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ips.PDNSBackend.PDNSName,
+			Namespace: ips.PDNSBackend.PDNSNamespace,
+		},
+	}
+
+	err := r.Get(ctx, client.ObjectKeyFromObject(&secret), &secret)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, ok := secret.Data[secretPDNSAPIKey]
+	if !ok || len(apiKey) == 0 {
+		return nil, errors.New(secretPDNSAPIKey + " missing from secret")
+	}
+
+	var options []pdnsapi.ClientOption
+	if r.Plan != nil {
+		options = append(options, pdnsapi.WithDryRun(r.Plan.RecordPDNSRequest))
+	}
+
+	return pdnsapi.NewClient("http://"+ips.APIIPs[0]+":"+pdnsAPIPort, string(apiKey), options...), nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"github.com/sudoswedenab/dockyards-pdns/test/fakepdns"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileExternalDNSRFC2136(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = corev1.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	server := fakepdns.NewServer("test-api-key")
+	t.Cleanup(server.Close)
+
+	server.AddZone("org-cluster.test.com")
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster",
+			Namespace: "testing",
+			UID:       "d4e5f6",
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-cluster.test.com",
+			Namespace: "testing",
+			UID:       "a1b2c3",
+			Annotations: map[string]string{
+				AnnotationExternalDNSProvider: externalDNSProviderRFC2136,
+				AnnotationZoneKind:            "Master",
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&cluster, &zone).Build()
+
+	configManager := dyconfig.NewFakeConfigManager(map[string]string{
+		string(KeySources):                  "ingress,service",
+		string(dyconfig.KeyPublicNamespace): "dockyards-public",
+		string(KeySecondaryNameservers):     "ns1.secondary.net",
+		string(KeySecondaryAddresses):       "192.0.2.1",
+		string(KeyTransferTSIG):             "true",
+	})
+
+	r := ZoneReconciler{
+		Client:        c,
+		ConfigManager: configManager,
+		PDNSClient:    pdnsapi.NewClient(server.URL, "test-api-key"),
+	}
+
+	ips := PDNSIPs{
+		DNSIP: "198.51.100.53",
+	}
+
+	zoneID := pdnsapi.CanonicalName(zone.Name)
+
+	t.Run("test rfc2136", func(t *testing.T) {
+		_, err := r.reconcileZoneTransfers(ctx, &zone, &ips)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.reconcileExternalDNSRFC2136(ctx, &zone, &cluster, &ips)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, found := server.TSIGKey(zoneID)
+		if !found {
+			t.Fatal("expected zone TSIG key to be created")
+		}
+
+		var secret corev1.Secret
+		err = c.Get(ctx, client.ObjectKey{Name: tsigSecretName(&zone), Namespace: zone.Namespace}, &secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(secret.Data[secretTSIGSecret]) != key.Key {
+			t.Error("expected secret to hold the TSIG key registered with PowerDNS")
+		}
+
+		if !metav1.IsControlledBy(&secret, &zone) {
+			t.Error("expected secret to be controlled by the zone")
+		}
+
+		expected := []string{zone.Name}
+		if !cmp.Equal(server.Metadata(zoneID, pdnsapi.MetadataTSIGAllowDNSUpdate), expected) {
+			t.Error(cmp.Diff(expected, server.Metadata(zoneID, pdnsapi.MetadataTSIGAllowDNSUpdate)))
+		}
+
+		expected = []string{transferTSIGPrefix + zone.Name, zone.Name}
+		if !cmp.Equal(server.Metadata(zoneID, pdnsapi.MetadataTSIGAllowAXFR), expected) {
+			t.Error(cmp.Diff(expected, server.Metadata(zoneID, pdnsapi.MetadataTSIGAllowAXFR)))
+		}
+
+		var workload dockyardsv1.Workload
+		err = c.Get(ctx, client.ObjectKey{Name: cluster.Name + "-external-dns", Namespace: cluster.Namespace}, &workload)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("test delete rfc2136", func(t *testing.T) {
+		zone.Annotations[AnnotationExternalDNSProvider] = externalDNSProviderPDNS
+
+		err := c.Update(ctx, &zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.deleteRFC2136(ctx, &zone, &ips)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, found := server.TSIGKey(zoneID)
+		if found {
+			t.Error("expected zone TSIG key to be deleted")
+		}
+
+		for _, kind := range []string{pdnsapi.MetadataTSIGAllowDNSUpdate, pdnsapi.MetadataAllowDNSUpdateFrom} {
+			if len(server.Metadata(zoneID, kind)) != 0 {
+				t.Errorf("expected metadata %s to be deleted", kind)
+			}
+		}
+
+		expected := []string{transferTSIGPrefix + zone.Name}
+		if !cmp.Equal(server.Metadata(zoneID, pdnsapi.MetadataTSIGAllowAXFR), expected) {
+			t.Error(cmp.Diff(expected, server.Metadata(zoneID, pdnsapi.MetadataTSIGAllowAXFR)))
+		}
+
+		var secret corev1.Secret
+		err = c.Get(ctx, client.ObjectKey{Name: tsigSecretName(&zone), Namespace: zone.Namespace}, &secret)
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected secret to be deleted, got %v", err)
+		}
+	})
+}
@@ -22,4 +22,6 @@ type PDNSIPs struct {
 	// APIIPs is a slice of strings containing all ClusterIPs associated with PowerDNS's ClusterIP service
 	// intented for API-specific traffic
 	APIIPs []string
+	// PDNSBackend is the PowerDNS installation that exports the addresses
+	PDNSBackend *PDNSBackend
 }
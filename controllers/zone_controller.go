@@ -24,28 +24,47 @@ import (
 	"time"
 
 	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/sudoswedenab/dockyards-backend/api/apiutil"
 	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
 	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
 	corev1 "k8s.io/api/core/v1"
-	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // ZoneReconciler ensures PowerDNS zones are fully configured and mirrored to Dockyards resources.
 type ZoneReconciler struct {
 	client.Client
 	*dyconfig.ConfigManager
+
+	// Plan records PowerDNS API requests instead of sending them when set, see NewPlanClient.
+	Plan *Plan
+
+	// Backend stores zones and records when set, otherwise the backend is picked by the config key.
+	Backend Backend
+
+	// PDNSClient is used for the PowerDNS API when set, otherwise a client is built from the API address of the
+	// PowerDNS backend of the zone.
+	PDNSClient *pdnsapi.Client
+
+	// Shard limits the reconciler to the zones claimed by the instance, see Shard.
+	Shard *Shard
 }
 
 // +kubebuilder:rbac:groups=dockyards.io,resources=clusters/status,verbs=patch
 // +kubebuilder:rbac:groups=dockyards.io,resources=clusters/finalizers,verbs=update
 // +kubebuilder:rbac:groups=dockyards.io,resources=workloads,verbs=create;patch;get;list;watch
 // +kubebuilder:rbac:groups=core,resources=configmaps;secrets;services,verbs=get;list;watch
-// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=rrsets,verbs=create;patch;get;list;watch
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=rrsets,verbs=create;patch;get;list;watch;delete
 // +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=zones/finalizers,verbs=update
 
 // Reconcile synchronizes RRsets and external DNS workloads once PowerDNS zones succeed.
@@ -58,21 +77,91 @@ func (r *ZoneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
-	if !zone.DeletionTimestamp.IsZero() {
+	if apierrors.IsNotFound(err) {
+		reportPaused("Zone", req.NamespacedName, false)
+
 		return ctrl.Result{}, nil
 	}
 
-	if !zone.IsInExpectedStatus(1, "Succeeded") {
-		logger.Info("Ignoring zone in non-Succeeded status", "zone", zone.Name, "syncStatus", zone.Status.SyncStatus)
+	if !r.Shard.Owns(&zone) {
+		return ctrl.Result{}, nil
+	}
+
+	reason, err := r.zonePauseReason(ctx, &zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	reportPaused("Zone", req.NamespacedName, reason != "")
+
+	if reason != "" {
+		logger.Info("Skipping paused zone", "zone", zone.Name, "reason", reason)
+
+		return ctrl.Result{RequeueAfter: pausedRequeueDelay}, nil
+	}
+
+	backend, err := r.backend(ctx, &zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !zone.DeletionTimestamp.IsZero() {
+		result, err := r.reconcileDeleteParentZone(ctx, &zone)
+		if err != nil || !result.IsZero() {
+			return result, err
+		}
+
+		return ctrl.Result{}, r.deleteBackendZone(ctx, backend, &zone)
+	}
+
+	if backendOwnsZones(backend) && !controllerutil.ContainsFinalizer(&zone, FinalizerBackendZone) {
+		patch := client.MergeFrom(zone.DeepCopy())
+
+		controllerutil.AddFinalizer(&zone, FinalizerBackendZone)
+
+		err := r.Patch(ctx, &zone, patch)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	err = backend.EnsureZone(ctx, &zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status, err := backend.ZoneStatus(ctx, &zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !status.Ready {
+		logger.Info("Ignoring zone in non-Succeeded status", "zone", zone.Name, "syncStatus", status.Message)
 
 		return ctrl.Result{}, nil
 	}
 
+	if status.Serial != nil {
+		zone.Status.Serial = status.Serial
+	}
+
 	zoneLabels := zone.GetLabels()
+	if zoneLabels[LabelZoneType] == ZoneTypeOrganization {
+		return r.reconcileStandaloneZone(ctx, &zone)
+	}
+
 	if zoneLabels[dockyardsv1.LabelClusterName] == "" {
 		return ctrl.Result{}, nil
 	}
 
+	if zoneLabels[LabelZoneType] == ZoneTypeCustomDomain || zoneLabels[LabelZoneType] == ZoneTypeACMEChallenge {
+		return r.reconcileStandaloneZone(ctx, &zone)
+	}
+
+	if zoneLabels[LabelZoneType] != "" {
+		return ctrl.Result{}, nil
+	}
+
 	cluster := dockyardsv1.Cluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      zoneLabels[dockyardsv1.LabelClusterName],
@@ -85,7 +174,11 @@ func (r *ZoneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
-	ips, err := r.getPDNSIPs(ctx)
+	if zone.Annotations[AnnotationMigrateTo] != "" {
+		return r.reconcileZoneMigration(ctx, &zone, &cluster)
+	}
+
+	ips, err := r.getPDNSIPs(ctx, &zone)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -101,47 +194,120 @@ func (r *ZoneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
-	return r.reconcileExternalDNS(ctx, &zone, &cluster, ips.APIIPs)
+	_, err = r.reconcileDelegation(ctx, &zone, ips.DNSIP)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	ownerOrganization, err := apiutil.GetOwnerOrganization(ctx, r.Client, &cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	_, err = r.reconcileRecordTemplates(ctx, &zone, &cluster, ownerOrganization)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	_, err = r.reconcileCAAPolicy(ctx, &zone, &cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	_, err = r.reconcileReverseDNS(ctx, &zone, &cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	_, err = r.reconcileACMEChallenge(ctx, &zone, &cluster, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	provider, err := r.externalDNSProvider(&zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	pendingMigration, err := r.pendingZoneMigration(ctx, &zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if pendingMigration {
+		logger.Info("Deferring ExternalDNS until zone migration has copied records", "zone", zone.Name)
+	} else if provider == externalDNSProviderRFC2136 {
+		_, err = r.reconcileExternalDNSRFC2136(ctx, &zone, &cluster, ips)
+	} else {
+		_, err = r.reconcileExternalDNS(ctx, &zone, &cluster, ips)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	_, err = r.reconcileZoneTransfers(ctx, &zone, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	result, err := r.reconcileDNSSEC(ctx, &zone, &cluster, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	probeResult, err := r.reconcileDNSServing(ctx, &zone, &cluster, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if result.RequeueAfter == 0 || (probeResult.RequeueAfter > 0 && probeResult.RequeueAfter < result.RequeueAfter) {
+		result.RequeueAfter = probeResult.RequeueAfter
+	}
+
+	return result, nil
 }
 
 // reconcileRRsets ensures SOA and NS records exist for the supplied zone and IP.
 func (r *ZoneReconciler) reconcileRRsets(ctx context.Context, zone *pdnsv1.Zone, externalIP string) (ctrl.Result, error) {
 	logger := ctrl.LoggerFrom(ctx)
 
-	nsString := "ns1." + zone.Name + "."
+	primaryNameserver := "ns1." + zone.Name
+	if len(zone.Spec.Nameservers) > 0 {
+		primaryNameserver = strings.TrimSuffix(zone.Spec.Nameservers[0], ".")
+	}
+
+	nsString := primaryNameserver + "."
 	emailString := "hostmaster." + zone.Name + "."
-	versionString := time.Now().Format("20060102") // DNS versioning format YYYYMMDDnn where nn is a counter
+
+	fields := strings.Join([]string{
+		nsString,
+		emailString,
+		strconv.Itoa(soaRefreshInterval),
+		strconv.Itoa(soaRetryInterval),
+		strconv.Itoa(soaExpireTime),
+		strconv.Itoa(soaNegativeCache),
+	}, " ")
+
+	serialString := strconv.FormatUint(uint64(soaSerial(zone, fields, time.Now())), 10) // DNS versioning format YYYYMMDDnn where nn is a counter
 
 	record := []string{
 		nsString,
 		emailString,
-		versionString + "01",
+		serialString,
 		strconv.Itoa(soaRefreshInterval),
 		strconv.Itoa(soaRetryInterval),
 		strconv.Itoa(soaExpireTime),
 		strconv.Itoa(soaNegativeCache),
 	}
 
-	soaset := pdnsv1.RRset{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "soa." + zone.Name,
-			Namespace: zone.Namespace,
-		},
+	backend, err := r.backend(ctx, zone)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	operationResult, err := controllerutil.CreateOrPatch(ctx, r.Client, &soaset, func() error {
-		soaset.Labels = zone.Labels
-		soaset.OwnerReferences = []metav1.OwnerReference{
-			{
-				APIVersion:         pdnsv1.GroupVersion.String(),
-				Kind:               "Zone", // PDNS library does not offer ZoneKind
-				Name:               zone.Name,
-				UID:                zone.UID,
-				Controller:         ptr.To(true),
-				BlockOwnerDeletion: ptr.To(true),
-			},
-		}
-		soaset.Spec = pdnsv1.RRsetSpec{
+	soaset := BackendRecord{
+		ID: "soa." + zone.Name,
+		Spec: pdnsv1.RRsetSpec{
 			Type: "SOA",
 			TTL:  uint32(3600),
 			Name: zone.Name + ".",
@@ -152,36 +318,38 @@ func (r *ZoneReconciler) reconcileRRsets(ctx context.Context, zone *pdnsv1.Zone,
 				Name: zone.Name,
 				Kind: zone.Kind,
 			},
-		}
+		},
+	}
 
-		return nil
-	})
+	operationResult, err := backend.EnsureRecord(ctx, zone, soaset)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	logger.Info("Reconciled Zone SOA RRSet", "zone", zone.Name, "operationResult", operationResult)
 
-	rrset := pdnsv1.RRset{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "ns1." + zone.Name,
-			Namespace: zone.Namespace,
-		},
-	}
+	soa := serialString + " " + fields
+	if zone.Annotations[AnnotationSOA] != soa {
+		// The annotation is patched on a copy, the patch would replace the serial the backend reported for the zone.
+		annotated := zone.DeepCopy()
 
-	operationResult, err = controllerutil.CreateOrPatch(ctx, r.Client, &rrset, func() error {
-		rrset.Labels = zone.Labels
-		rrset.OwnerReferences = []metav1.OwnerReference{
-			{
-				APIVersion:         pdnsv1.GroupVersion.String(),
-				Kind:               "Zone", // PDNS library does not offer ZoneKind
-				Name:               zone.Name,
-				UID:                zone.UID,
-				Controller:         ptr.To(true),
-				BlockOwnerDeletion: ptr.To(true),
-			},
+		metav1.SetMetaDataAnnotation(&annotated.ObjectMeta, AnnotationSOA, soa)
+
+		err := r.Patch(ctx, annotated, client.MergeFrom(zone))
+		if err != nil {
+			return ctrl.Result{}, err
 		}
-		rrset.Spec = pdnsv1.RRsetSpec{
+
+		zone.ObjectMeta = annotated.ObjectMeta
+	}
+
+	if primaryNameserver != "ns1."+zone.Name {
+		return ctrl.Result{}, nil
+	}
+
+	rrset := BackendRecord{
+		ID: "ns1." + zone.Name,
+		Spec: pdnsv1.RRsetSpec{
 			Type: "A",
 			TTL:  uint32(zoneTTL),
 			Name: "ns1",
@@ -192,10 +360,10 @@ func (r *ZoneReconciler) reconcileRRsets(ctx context.Context, zone *pdnsv1.Zone,
 				Name: zone.Name,
 				Kind: zone.Kind,
 			},
-		}
+		},
+	}
 
-		return nil
-	})
+	operationResult, err = backend.EnsureRecord(ctx, zone, rrset)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -205,127 +373,315 @@ func (r *ZoneReconciler) reconcileRRsets(ctx context.Context, zone *pdnsv1.Zone,
 	return ctrl.Result{}, nil
 }
 
-// reconcileExternalDNS configures a Dockyards Workload that runs ExternalDNS against PowerDNS.
-func (r *ZoneReconciler) reconcileExternalDNS(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, internalIPs []string) (ctrl.Result, error) {
-	logger := ctrl.LoggerFrom(ctx)
-	pdnsName, found := r.GetValueForKey(KeyPDNSName)
-	if !found {
-		return ctrl.Result{}, fmt.Errorf("config key `%s` not found", KeyPDNSName)
+// reconcileStandaloneZone maintains the SOA record and transfers of zones that do not belong to a single cluster.
+//
+// Custom domain and ACME challenge zones are served by the nameservers of the cluster zone, so no nameserver records
+// are created in them. Organization zones carry their own primary nameserver.
+func (r *ZoneReconciler) reconcileStandaloneZone(ctx context.Context, zone *pdnsv1.Zone) (ctrl.Result, error) {
+	ips, err := r.getPDNSIPs(ctx, zone)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
-	if pdnsName == "" {
-		return ctrl.Result{}, fmt.Errorf("no value for config key `%s`", KeyPDNSName)
+	if ips.DNSIP == "" {
+		return ctrl.Result{}, errors.New("no available DNS addresses for PowerDNS")
 	}
-
-	pdnsNamespace, found := r.GetValueForKey(KeyPDNSNamespace)
-	if !found {
-		return ctrl.Result{}, fmt.Errorf("config key `%s` not found", KeyPDNSNamespace)
+	if len(ips.APIIPs) == 0 {
+		return ctrl.Result{}, errors.New("no available API addresses for PowerDNS")
 	}
-	if pdnsNamespace == "" {
-		return ctrl.Result{}, fmt.Errorf("no value for config key `%s`", KeyPDNSNamespace)
+
+	_, err = r.reconcileRRsets(ctx, zone, ips.DNSIP)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	secret := corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      pdnsName,
-			Namespace: pdnsNamespace,
-		},
+	return r.reconcileZoneTransfers(ctx, zone, ips)
+}
+
+// reconcileRecordTemplates renders the organization record template into RRsets and prunes stale ones.
+func (r *ZoneReconciler) reconcileRecordTemplates(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, ownerOrganization *dockyardsv1.Organization) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var rendered []renderedRecord
+
+	templateName := zone.Annotations[AnnotationRecordTemplate]
+	if templateName != "" {
+		var configMap corev1.ConfigMap
+		err := r.Get(ctx, client.ObjectKey{Name: templateName, Namespace: zone.Namespace}, &configMap)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		templates, err := parseRecordTemplates(configMap.Data)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("invalid record template %s: %w", templateName, err)
+		}
+
+		data := RecordTemplateData{
+			Zone:         zone.Name,
+			Cluster:      cluster.Name,
+			Organization: ownerOrganization.Name,
+		}
+
+		rendered, err = renderRecordTemplates(templates, zone, data)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("invalid record template %s: %w", templateName, err)
+		}
+
+		reserved, err := r.reservedRecords(ctx, zone)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		for _, rendered := range rendered {
+			owner, found := reserved[reservedRecordKey(strings.ToLower(rrsetFQDN(rendered.spec.Name, zone.Name)), rendered.spec.Type)]
+			if found {
+				return ctrl.Result{}, fmt.Errorf("invalid record template %s: template %s collides with %s", templateName, rendered.key, owner)
+			}
+		}
 	}
-	err := r.Get(ctx, client.ObjectKeyFromObject(&secret), &secret)
+
+	backend, err := r.backend(ctx, zone)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	publicNamespace, found := r.GetValueForKey(dyconfig.KeyPublicNamespace)
-	if !found {
-		return ctrl.Result{}, fmt.Errorf("config key `%s` not found", dyconfig.KeyPublicNamespace)
+	desired := make(map[string]bool, len(rendered))
+
+	for _, rendered := range rendered {
+		record := BackendRecord{
+			ID: recordTemplateRRsetName(zone, rendered.key),
+			Labels: map[string]string{
+				LabelRecordTemplate: templateName,
+			},
+			Spec: rendered.spec,
+		}
+
+		desired[record.ID] = true
+
+		operationResult, err := backend.EnsureRecord(ctx, zone, record)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Reconciled Zone template RRSet", "zone", zone.Name, "rrset", record.ID, "operationResult", operationResult)
 	}
-	if publicNamespace == "" {
-		return ctrl.Result{}, fmt.Errorf("no value for config key `%s`", dyconfig.KeyPublicNamespace)
+
+	records, err := backend.ListRecords(ctx, zone, LabelRecordTemplate)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	sourcesValue, found := r.GetValueForKey(KeySources)
+	for _, record := range records {
+		if desired[record.ID] {
+			continue
+		}
+
+		deleted, err := backend.DeleteRecord(ctx, zone, record)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if deleted {
+			logger.Info("Deleted stale Zone template RRSet", "zone", zone.Name, "rrset", record.ID)
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// caaPolicy returns the CAA policy of the zone, the annotations override the config keys.
+func (r *ZoneReconciler) caaPolicy(zone *pdnsv1.Zone) *CAAPolicy {
+	issuers, found := zone.Annotations[AnnotationCAAIssuers]
 	if !found {
-		return ctrl.Result{}, fmt.Errorf("config key `%s` not found", KeySources)
+		issuers = r.GetValueOrDefault(KeyCAAIssuers, "")
 	}
-	if sourcesValue == "" {
-		return ctrl.Result{}, fmt.Errorf("no value for config key `%s`", KeySources)
+
+	iodef, found := zone.Annotations[AnnotationCAAIodef]
+	if !found {
+		iodef = r.GetValueOrDefault(KeyCAAIodef, "")
 	}
 
-	sources, err := parseExternalDNSSources(sourcesValue)
+	return parseCAAPolicy(issuers, iodef)
+}
+
+// reconcileCAAPolicy maintains the CAA RRset at the zone apex and reports the policy state on the cluster.
+//
+// The RRset is deleted when the policy is invalid, so that a stale policy is never published as if it was current.
+func (r *ZoneReconciler) reconcileCAAPolicy(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	policy := r.caaPolicy(zone)
+
+	backend, err := r.backend(ctx, zone)
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("invalid value for config key `%s`: %w", KeySources, err)
-	}
-	if len(sources) == 0 {
-		return ctrl.Result{}, fmt.Errorf("no value for config key `%s`", KeySources)
+		return ctrl.Result{}, err
 	}
 
-	workload := dockyardsv1.Workload{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      cluster.Name + "-external-dns",
-			Namespace: cluster.Namespace,
+	caaset := BackendRecord{
+		ID: "caa." + zone.Name,
+		Spec: pdnsv1.RRsetSpec{
+			Type:    "CAA",
+			TTL:     uint32(zoneTTL),
+			Name:    zone.Name + ".",
+			Records: policy.Records(),
+			ZoneRef: pdnsv1.ZoneRef{
+				Name: zone.Name,
+				Kind: zone.Kind,
+			},
 		},
 	}
 
-	operationResult, err := controllerutil.CreateOrPatch(ctx, r.Client, &workload, func() error {
-		if metav1.HasAnnotation(workload.ObjectMeta, dockyardsv1.AnnotationSkipRemediation) {
-			return nil
+	policyErr := policy.Validate()
+	if policy.IsEmpty() || policyErr != nil {
+		deleted, err := backend.DeleteRecord(ctx, zone, caaset)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
 
-		workload.Labels = map[string]string{
-			dockyardsv1.LabelClusterName: cluster.Name,
+		if deleted {
+			logger.Info("Deleted Zone CAA RRSet", "zone", zone.Name)
 		}
+	}
 
-		workload.OwnerReferences = []metav1.OwnerReference{
-			{
-				APIVersion:         dockyardsv1.GroupVersion.String(),
-				Kind:               dockyardsv1.ClusterKind,
-				Name:               cluster.Name,
-				UID:                cluster.UID,
-				Controller:         ptr.To(true),
-				BlockOwnerDeletion: ptr.To(true),
-			},
+	if policy.IsEmpty() {
+		patch := client.MergeFrom(cluster.DeepCopy())
+		if meta.RemoveStatusCondition(&cluster.Status.Conditions, CAAPolicyReadyCondition) {
+			return ctrl.Result{}, r.Status().Patch(ctx, cluster, patch)
 		}
 
-		workload.Spec.Provenience = dockyardsv1.ProvenienceDockyards
-		workload.Spec.ClusterComponent = true
-		workload.Spec.TargetNamespace = workloadTargetNamespace
+		return ctrl.Result{}, nil
+	}
 
-		workload.Spec.WorkloadTemplateRef = &corev1.TypedObjectReference{
-			Kind:      dockyardsv1.WorkloadTemplateKind,
-			Name:      workloadTargetNamespace,
-			Namespace: &publicNamespace,
-		}
+	if policyErr != nil {
+		logger.Info("ignoring invalid CAA policy", "zone", zone.Name, "err", policyErr)
 
-		apiKey, ok := secret.Data[secretPDNSAPIKey]
-		if !ok || len(apiKey) == 0 {
-			return fmt.Errorf("%s missing from secret", secretPDNSAPIKey)
-		}
+		return ctrl.Result{}, r.setCAAPolicyCondition(ctx, cluster, policyErr)
+	}
 
-		raw, err := json.Marshal(map[string]any{
-			"provider": "pdns",
-			"sources":  sources,
-			"credentials": map[string]string{
-				"pdnsApiKey": string(apiKey),
-			},
-			"env": map[string]string{
-				"EXTERNAL_DNS_PDNS_SERVER":   "http://" + internalIPs[0] + ":8081",
-				"EXTERNAL_DNS_DOMAIN_FILTER": zone.Name,
-			},
-		})
-		if err != nil {
-			return err
-		}
+	operationResult, err := backend.EnsureRecord(ctx, zone, caaset)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-		workload.Spec.Input = &apiextensionsv1.JSON{
-			Raw: raw,
-		}
+	logger.Info("Reconciled Zone CAA RRSet", "zone", zone.Name, "operationResult", operationResult)
+
+	return ctrl.Result{}, r.setCAAPolicyCondition(ctx, cluster, nil)
+}
+
+// setCAAPolicyCondition patches the CAA policy condition of the cluster status.
+func (r *ZoneReconciler) setCAAPolicyCondition(ctx context.Context, cluster *dockyardsv1.Cluster, policyErr error) error {
+	patch := client.MergeFrom(cluster.DeepCopy())
+
+	condition := metav1.Condition{
+		Type:               CAAPolicyReadyCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             CAAPolicyReconciledReason,
+		ObservedGeneration: cluster.Generation,
+	}
 
+	if policyErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = InvalidCAAPolicyReason
+		condition.Message = policyErr.Error()
+	}
+
+	if !meta.SetStatusCondition(&cluster.Status.Conditions, condition) {
 		return nil
+	}
+
+	return r.Status().Patch(ctx, cluster, patch)
+}
+
+// reconcileExternalDNS configures a Dockyards Workload that runs ExternalDNS against PowerDNS.
+//
+// The workload uses the API of the PowerDNS backend the zone is placed on.
+func (r *ZoneReconciler) reconcileExternalDNS(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, ips *PDNSIPs) (ctrl.Result, error) {
+	err := r.deleteRFC2136(ctx, zone, ips)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ips.PDNSBackend.PDNSName,
+			Namespace: ips.PDNSBackend.PDNSNamespace,
+		},
+	}
+	err = r.Get(ctx, client.ObjectKeyFromObject(&secret), &secret)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	apiKey, ok := secret.Data[secretPDNSAPIKey]
+	if !ok || len(apiKey) == 0 {
+		return ctrl.Result{}, fmt.Errorf("%s missing from secret", secretPDNSAPIKey)
+	}
+
+	credentials := map[string]string{
+		"pdnsApiKey": string(apiKey),
+	}
+
+	env := map[string]string{
+		"EXTERNAL_DNS_PDNS_SERVER": "http://" + ips.APIIPs[0] + ":" + pdnsAPIPort,
+	}
+
+	return r.reconcileExternalDNSWorkload(ctx, zone, cluster, externalDNSProviderPDNS, credentials, env)
+}
+
+// reconcileExternalDNSWorkload creates or patches the ExternalDNS Workload for the given provider settings.
+func (r *ZoneReconciler) reconcileExternalDNSWorkload(ctx context.Context, zone *pdnsv1.Zone, cluster *dockyardsv1.Cluster, provider string, credentials, env map[string]string) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	publicNamespace, err := requiredConfigValue(r.ConfigManager, dyconfig.KeyPublicNamespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	sources, err := parseSources(r.ConfigManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	domainFilter, err := r.externalDNSDomainFilter(ctx, zone, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	env["EXTERNAL_DNS_DOMAIN_FILTER"] = strings.Join(domainFilter, ",")
+
+	acmeEnabled, err := r.isACMEEnabled(zone)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if acmeEnabled {
+		env["EXTERNAL_DNS_EXCLUDE_DOMAINS"] = acmeChallengeZoneName(zone)
+	}
+
+	workload := dockyardsv1.Workload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.Name + "-external-dns",
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"provider":    provider,
+		"sources":     sources,
+		"credentials": credentials,
+		"env":         env,
 	})
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	ac := clusterComponentWorkloadApply(workload.Name, cluster, workloadTargetNamespace, workloadTargetNamespace, publicNamespace, raw)
+
+	operationResult, err := applyObject(ctx, r.Client, &workload, ac)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	logger.Info("Reconciled Workload", "cluster", cluster.Name, "workload", workload.Name, "operationResult", operationResult)
 
 	return ctrl.Result{}, nil
@@ -352,28 +708,22 @@ func parseExternalDNSSources(value string) ([]string, error) {
 	return out, nil
 }
 
-// getPDNSIPs fetches the DNS and API service addresses exported by PowerDNS components.
-func (r *ZoneReconciler) getPDNSIPs(ctx context.Context) (*PDNSIPs, error) {
-	pdnsName, found := r.GetValueForKey(KeyPDNSName)
-	if !found {
-		return nil, fmt.Errorf("config key `%s` not found", KeyPDNSName)
-	}
-	if pdnsName == "" {
-		return nil, fmt.Errorf("no value for config key `%s`", KeyPDNSName)
+// getPDNSIPs fetches the DNS and API service addresses exported by the PowerDNS backend the zone is placed on.
+func (r *ZoneReconciler) getPDNSIPs(ctx context.Context, zone *pdnsv1.Zone) (*PDNSIPs, error) {
+	pdnsBackend, err := pdnsBackendForZone(r.ConfigManager, zone)
+	if err != nil {
+		return nil, err
 	}
 
-	pdnsNamespace, found := r.GetValueForKey(KeyPDNSNamespace)
-	if !found {
-		return nil, fmt.Errorf("config key `%s` not found", KeyPDNSNamespace)
-	}
-	if pdnsNamespace == "" {
-		return nil, fmt.Errorf("no value for config key `%s`", KeyPDNSNamespace)
-	}
+	return r.getPDNSBackendIPs(ctx, pdnsBackend)
+}
 
+// getPDNSBackendIPs fetches the DNS and API service addresses exported by a PowerDNS backend.
+func (r *ZoneReconciler) getPDNSBackendIPs(ctx context.Context, pdnsBackend *PDNSBackend) (*PDNSIPs, error) {
 	pdnsDNSService := corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      pdnsName + "-dns",
-			Namespace: pdnsNamespace,
+			Name:      pdnsBackend.PDNSName + "-dns",
+			Namespace: pdnsBackend.PDNSNamespace,
 		},
 	}
 
@@ -384,8 +734,8 @@ func (r *ZoneReconciler) getPDNSIPs(ctx context.Context) (*PDNSIPs, error) {
 
 	pdnsAPIService := corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      pdnsName + "-api",
-			Namespace: pdnsNamespace,
+			Name:      pdnsBackend.PDNSName + "-api",
+			Namespace: pdnsBackend.PDNSNamespace,
 		},
 	}
 
@@ -394,7 +744,77 @@ func (r *ZoneReconciler) getPDNSIPs(ctx context.Context) (*PDNSIPs, error) {
 		return &PDNSIPs{}, err
 	}
 
-	return &PDNSIPs{DNSIP: pdnsDNSService.Status.LoadBalancer.Ingress[0].IP, APIIPs: pdnsAPIService.Spec.ClusterIPs}, nil
+	if len(pdnsDNSService.Status.LoadBalancer.Ingress) == 0 {
+		return &PDNSIPs{}, fmt.Errorf("service %s has no load balancer ingress", pdnsDNSService.Name)
+	}
+
+	return &PDNSIPs{DNSIP: pdnsDNSService.Status.LoadBalancer.Ingress[0].IP, APIIPs: pdnsAPIService.Spec.ClusterIPs, PDNSBackend: pdnsBackend}, nil
+}
+
+// zonesForConfigMap enqueues the zones that reference a ConfigMap as their record template.
+func (r *ZoneReconciler) zonesForConfigMap(ctx context.Context, obj client.Object) []ctrl.Request {
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList, client.InNamespace(obj.GetNamespace()), client.HasLabels{dockyardsv1.LabelClusterName})
+	if err != nil {
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for _, zone := range zoneList.Items {
+		if zone.Annotations[AnnotationRecordTemplate] != obj.GetName() {
+			continue
+		}
+
+		requests = append(requests, ctrl.Request{
+			NamespacedName: client.ObjectKeyFromObject(&zone),
+		})
+	}
+
+	return requests
+}
+
+// zonesForCluster enqueues the zones labelled with a cluster, so that pausing or resuming the cluster resyncs them.
+func (r *ZoneReconciler) zonesForCluster(ctx context.Context, obj client.Object) []ctrl.Request {
+	var zoneList pdnsv1.ZoneList
+	err := r.List(ctx, &zoneList, client.InNamespace(obj.GetNamespace()), client.MatchingLabels{dockyardsv1.LabelClusterName: obj.GetName()})
+	if err != nil {
+		return nil
+	}
+
+	requests := make([]ctrl.Request, len(zoneList.Items))
+	for i, zone := range zoneList.Items {
+		requests[i] = ctrl.Request{
+			NamespacedName: client.ObjectKeyFromObject(&zone),
+		}
+	}
+
+	return requests
+}
+
+// zonesForMigration enqueues the zone that a migrating zone moves its records to.
+func (r *ZoneReconciler) zonesForMigration(ctx context.Context, obj client.Object) []ctrl.Request {
+	migrateTo := obj.GetAnnotations()[AnnotationMigrateTo]
+	if migrateTo == "" {
+		return nil
+	}
+
+	return []ctrl.Request{
+		{
+			NamespacedName: client.ObjectKey{Name: migrateTo, Namespace: obj.GetNamespace()},
+		},
+	}
+}
+
+// zoneOwnerReference returns a controller reference pointing at the zone.
+func zoneOwnerReference(zone *pdnsv1.Zone) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion:         pdnsv1.GroupVersion.String(),
+		Kind:               "Zone", // PDNS library does not offer ZoneKind
+		Name:               zone.Name,
+		UID:                zone.UID,
+		Controller:         ptr.To(true),
+		BlockOwnerDeletion: ptr.To(true),
+	}
 }
 
 // SetupWithManager registers the Zone controller with the provided manager.
@@ -405,7 +825,11 @@ func (r *ZoneReconciler) SetupWithManager(manager ctrl.Manager) error {
 	_ = pdnsv1.AddToScheme(scheme)
 
 	err := ctrl.NewControllerManagedBy(manager).
-		For(&pdnsv1.Zone{}).
+		For(&pdnsv1.Zone{}, builder.WithPredicates(r.Shard.Predicate())).
+		Owns(&pdnsv1.Zone{}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.zonesForConfigMap)).
+		Watches(&pdnsv1.Zone{}, handler.EnqueueRequestsFromMapFunc(r.zonesForMigration)).
+		Watches(&dockyardsv1.Cluster{}, handler.EnqueueRequestsFromMapFunc(r.zonesForCluster), builder.WithPredicates(predicate.AnnotationChangedPredicate{})).
 		Complete(r)
 	if err != nil {
 		return err
@@ -0,0 +1,529 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=get;list;watch;create;patch
+// +kubebuilder:rbac:groups=dockyards.io,resources=organizations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=dns.cav.enablers.ob,resources=zones;rrsets,verbs=get;list;watch
+
+// ZoneExportPolicy describes where zone files are exported to and how often.
+type ZoneExportPolicy struct {
+	Mode      string
+	Interval  time.Duration
+	Directory string
+
+	// APIRecords adds the RRsets PowerDNS serves without an RRset resource, such as those of ExternalDNS.
+	APIRecords bool
+}
+
+// parseZoneExportPolicy reads the zone export mode, interval, directory and API records from the config keys.
+func parseZoneExportPolicy(configManager *dyconfig.ConfigManager) (*ZoneExportPolicy, error) {
+	policy := ZoneExportPolicy{
+		Mode:      configManager.GetValueOrDefault(KeyZoneExport, ZoneExportDisabled),
+		Interval:  defaultZoneExportInterval,
+		Directory: configManager.GetValueOrDefault(KeyZoneExportDirectory, ""),
+	}
+
+	switch policy.Mode {
+	case ZoneExportDisabled, ZoneExportConfigMap, ZoneExportSecret:
+	case ZoneExportDirectory:
+		if policy.Directory == "" {
+			return nil, fmt.Errorf("no value for config key `%s`", KeyZoneExportDirectory)
+		}
+	default:
+		return nil, fmt.Errorf("invalid value for config key `%s`: unsupported mode %s", KeyZoneExport, policy.Mode)
+	}
+
+	value, found := configManager.GetValueForKey(KeyZoneExportInterval)
+	if found {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid value for config key `%s`: %s", KeyZoneExportInterval, value)
+		}
+
+		policy.Interval = interval
+	}
+
+	value = configManager.GetValueOrDefault(KeyZoneExportAPIRecords, "false")
+
+	apiRecords, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for config key `%s`: %w", KeyZoneExportAPIRecords, err)
+	}
+
+	policy.APIRecords = apiRecords
+
+	return &policy, nil
+}
+
+// ZoneFile is a zone rendered in RFC 1035 master file format.
+type ZoneFile struct {
+	Organization string
+	Namespace    string
+	Zone         string
+	Content      string
+}
+
+// zoneFileOrder sorts the SOA record first and the apex NS records second, followed by the other RRsets by name and type.
+func zoneFileOrder(zoneName string) func(a, b pdnsapi.RRset) int {
+	apex := pdnsapi.CanonicalName(zoneName)
+
+	rank := func(rrset pdnsapi.RRset) int {
+		switch {
+		case rrset.Type == "SOA":
+			return 0
+		case rrset.Type == "NS" && rrset.Name == apex:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	return func(a, b pdnsapi.RRset) int {
+		if rank(a) != rank(b) {
+			return rank(a) - rank(b)
+		}
+
+		if a.Name != b.Name {
+			return strings.Compare(a.Name, b.Name)
+		}
+
+		return strings.Compare(a.Type, b.Type)
+	}
+}
+
+// renderZoneFile renders the nameservers and RRset resources of a zone as a master file.
+//
+// RRsets that PowerDNS serves without an RRset resource are added when served is set, disabled records are left out.
+func renderZoneFile(zone *pdnsv1.Zone, rrsets []pdnsv1.RRset, served *pdnsapi.Zone) (string, error) {
+	records := make(map[string]pdnsapi.RRset)
+
+	if len(zone.Spec.Nameservers) > 0 {
+		nameservers := pdnsapi.RRset{
+			Name: pdnsapi.CanonicalName(zone.Name),
+			Type: "NS",
+			TTL:  zoneTTL,
+		}
+
+		for _, nameserver := range zone.Spec.Nameservers {
+			nameservers.Records = append(nameservers.Records, pdnsapi.Record{Content: pdnsapi.CanonicalName(nameserver)})
+		}
+
+		records[rrsetKey(nameservers.Name, nameservers.Type)] = nameservers
+	}
+
+	for _, rrset := range rrsets {
+		if rrset.Spec.ZoneRef.Name != zone.Name {
+			continue
+		}
+
+		expected := desiredRRset(zone.Name, rrset.Spec)
+		records[rrsetKey(expected.Name, expected.Type)] = expected
+	}
+
+	if served != nil {
+		for _, rrset := range served.RRsets {
+			key := rrsetKey(rrset.Name, rrset.Type)
+
+			_, found := records[key]
+			if !found {
+				records[key] = rrset
+			}
+		}
+	}
+
+	sorted := slices.SortedFunc(func(yield func(pdnsapi.RRset) bool) {
+		for _, rrset := range records {
+			if !yield(rrset) {
+				return
+			}
+		}
+	}, zoneFileOrder(zone.Name))
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "; %s exported by dockyards-pdns\n", pdnsapi.CanonicalName(zone.Name))
+	fmt.Fprintf(&b, "$ORIGIN %s\n", pdnsapi.CanonicalName(zone.Name))
+
+	for _, rrset := range sorted {
+		ttl := rrset.TTL
+		if ttl == 0 {
+			ttl = zoneTTL
+		}
+
+		for _, content := range recordContents(rrset) {
+			rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", rrset.Name, ttl, rrset.Type, content))
+			if err != nil {
+				return "", fmt.Errorf("invalid record %s %s: %w", rrset.Name, rrset.Type, err)
+			}
+			if rr == nil {
+				continue
+			}
+
+			b.WriteString(rr.String())
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String(), nil
+}
+
+// isExportedZone reports whether a zone is managed by the operator and worth restoring, ACME challenge zones only hold
+// short-lived records and are left out.
+func isExportedZone(zone *pdnsv1.Zone) bool {
+	switch zone.Labels[LabelZoneType] {
+	case ZoneTypeOrganization, ZoneTypeCustomDomain:
+		return true
+	case "":
+		return zone.Labels[dockyardsv1.LabelClusterName] != ""
+	default:
+		return false
+	}
+}
+
+// ZoneExporter periodically renders every managed zone to a master file for backups.
+type ZoneExporter struct {
+	client.Client
+	*dyconfig.ConfigManager
+
+	// Plan skips writing to the export directory during a dry run when set.
+	Plan *Plan
+
+	// Shard limits the export to the zones claimed by the instance, see Shard.
+	Shard *Shard
+
+	// PDNSClient is used instead of connecting to the PowerDNS backend of each zone when set.
+	PDNSClient *pdnsapi.Client
+}
+
+// Start exports the zones every interval until the context is cancelled.
+func (e *ZoneExporter) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("zone-exporter")
+	ctx = ctrl.LoggerInto(ctx, logger)
+
+	for {
+		interval := defaultZoneExportInterval
+
+		policy, err := parseZoneExportPolicy(e.ConfigManager)
+		if err != nil {
+			logger.Error(err, "error parsing zone export policy")
+		}
+
+		if policy != nil {
+			interval = policy.Interval
+
+			if policy.Mode != ZoneExportDisabled {
+				err := e.Export(ctx, policy)
+				if err != nil {
+					logger.Error(err, "error exporting zones")
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// NeedLeaderElection makes sure only the leader exports zones.
+func (e *ZoneExporter) NeedLeaderElection() bool {
+	return true
+}
+
+// Export renders every managed zone and writes the files as the policy describes.
+func (e *ZoneExporter) Export(ctx context.Context, policy *ZoneExportPolicy) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	files, err := e.ZoneFiles(ctx, policy.APIRecords)
+	if err != nil {
+		return err
+	}
+
+	switch policy.Mode {
+	case ZoneExportConfigMap, ZoneExportSecret:
+		err = e.writeObjects(ctx, policy.Mode, files)
+	case ZoneExportDirectory:
+		err = e.writeDirectory(ctx, policy.Directory, files)
+	default:
+		err = fmt.Errorf("unsupported zone export mode %s", policy.Mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Exported zones", "mode", policy.Mode, "zones", len(files))
+
+	return nil
+}
+
+// ZoneFiles renders every managed zone, with the RRsets PowerDNS serves when apiRecords is set.
+//
+// The records of the api backend only exist in PowerDNS, so with that backend the zones are always read from PowerDNS
+// and its RRsets are limited to the SOA and the records the backend owns unless apiRecords is set.
+func (e *ZoneExporter) ZoneFiles(ctx context.Context, apiRecords bool) ([]ZoneFile, error) {
+	backend, err := parseBackend(e.ConfigManager)
+	if err != nil {
+		return nil, err
+	}
+
+	var organizationList dockyardsv1.OrganizationList
+	err = e.List(ctx, &organizationList)
+	if err != nil {
+		return nil, err
+	}
+
+	organizations := make(map[string]string)
+	for _, organization := range organizationList.Items {
+		if organization.Spec.NamespaceRef != nil {
+			organizations[organization.Spec.NamespaceRef.Name] = organization.Name
+		}
+	}
+
+	var zoneList pdnsv1.ZoneList
+	err = e.List(ctx, &zoneList)
+	if err != nil {
+		return nil, err
+	}
+
+	var rrsetList pdnsv1.RRsetList
+	err = e.List(ctx, &rrsetList)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []ZoneFile
+
+	for _, zone := range zoneList.Items {
+		if !zone.DeletionTimestamp.IsZero() || !e.Shard.Owns(&zone) || !isExportedZone(&zone) {
+			continue
+		}
+
+		var rrsets []pdnsv1.RRset
+		for _, rrset := range rrsetList.Items {
+			if rrset.Namespace == zone.Namespace {
+				rrsets = append(rrsets, rrset)
+			}
+		}
+
+		var served *pdnsapi.Zone
+		if apiRecords || backend == backendAPI {
+			pdnsClient, err := e.pdnsClient(ctx, &zone)
+			if err != nil {
+				return nil, err
+			}
+
+			served, err = pdnsClient.GetZone(ctx, zone.Name)
+			if pdnsapi.IgnoreNotFound(err) != nil {
+				return nil, err
+			}
+		}
+
+		if served != nil && !apiRecords {
+			served = backendOwnedRRsets(served)
+		}
+
+		content, err := renderZoneFile(&zone, rrsets, served)
+		if err != nil {
+			return nil, fmt.Errorf("error rendering zone %s: %w", zone.Name, err)
+		}
+
+		organization := organizations[zone.Namespace]
+		if organization == "" {
+			organization = zone.Namespace
+		}
+
+		files = append(files, ZoneFile{
+			Organization: organization,
+			Namespace:    zone.Namespace,
+			Zone:         zone.Name,
+			Content:      content,
+		})
+	}
+
+	return files, nil
+}
+
+// backendOwnedRRsets returns a copy of a served zone with only its SOA and the RRsets the api backend owns.
+func backendOwnedRRsets(served *pdnsapi.Zone) *pdnsapi.Zone {
+	owned := pdnsapi.Zone{
+		ID:   served.ID,
+		Name: served.Name,
+		Kind: served.Kind,
+	}
+
+	for _, rrset := range served.RRsets {
+		if rrset.Type == "SOA" || recordOwner(rrset) != nil {
+			owned.RRsets = append(owned.RRsets, rrset)
+		}
+	}
+
+	return &owned
+}
+
+// pdnsClient connects to the API of the PowerDNS backend of the zone with the credentials of the zone reconciler.
+func (e *ZoneExporter) pdnsClient(ctx context.Context, zone *pdnsv1.Zone) (*pdnsapi.Client, error) {
+	if e.PDNSClient != nil {
+		return e.PDNSClient, nil
+	}
+
+	z := ZoneReconciler{
+		Client:        e.Client,
+		ConfigManager: e.ConfigManager,
+		Plan:          e.Plan,
+	}
+
+	ips, err := z.getPDNSIPs(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips.APIIPs) == 0 {
+		return nil, errors.New("no available API addresses for PowerDNS")
+	}
+
+	return z.getPDNSClient(ctx, ips)
+}
+
+// writeObjects writes the zone files of every organization into a ConfigMap or Secret in its namespace, replacing the
+// files of zones that are gone.
+func (e *ZoneExporter) writeObjects(ctx context.Context, mode string, files []ZoneFile) error {
+	byNamespace := make(map[string]map[string]string)
+	for _, file := range files {
+		if byNamespace[file.Namespace] == nil {
+			byNamespace[file.Namespace] = make(map[string]string)
+		}
+
+		byNamespace[file.Namespace][file.Zone+zoneExportFileSuffix] = file.Content
+	}
+
+	for namespace, data := range byNamespace {
+		objectMeta := metav1.ObjectMeta{
+			Name:      zoneExportObjectName,
+			Namespace: namespace,
+		}
+
+		var obj client.Object
+		var ac runtime.ApplyConfiguration
+
+		if mode == ZoneExportSecret {
+			secretData := make(map[string][]byte, len(data))
+			for key, value := range data {
+				secretData[key] = []byte(value)
+			}
+
+			obj = &corev1.Secret{ObjectMeta: objectMeta}
+			ac = corev1ac.Secret(zoneExportObjectName, namespace).
+				WithLabels(map[string]string{
+					LabelSecret: secretTypeZoneExport,
+				}).
+				WithData(secretData)
+		} else {
+			obj = &corev1.ConfigMap{ObjectMeta: objectMeta}
+			ac = corev1ac.ConfigMap(zoneExportObjectName, namespace).
+				WithLabels(map[string]string{
+					LabelConfigMap: configMapTypeZoneExport,
+				}).
+				WithData(data)
+		}
+
+		operationResult, err := applyObject(ctx, e.Client, obj, ac)
+		if err != nil {
+			return err
+		}
+
+		ctrl.LoggerFrom(ctx).Info("Reconciled zone export", "namespace", namespace, "name", zoneExportObjectName, "operationResult", operationResult)
+	}
+
+	return nil
+}
+
+// writeDirectory writes every zone file to `<directory>/<organization>/<zone>.zone`, files of zones that are gone are
+// kept. Nothing is written during a dry run.
+func (e *ZoneExporter) writeDirectory(ctx context.Context, directory string, files []ZoneFile) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	for _, file := range files {
+		path := filepath.Join(directory, file.Organization, file.Zone+zoneExportFileSuffix)
+
+		if e.Plan != nil {
+			logger.Info("Skipping zone file in dry-run mode", "path", path)
+
+			continue
+		}
+
+		err := writeFileAtomic(path, []byte(file.Content))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic replaces a file through a rename so that readers never see a partial zone file.
+func writeFileAtomic(path string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,444 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"github.com/sudoswedenab/dockyards-pdns/test/fakepdns"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func exportTestObjects() (pdnsv1.Zone, []pdnsv1.RRset) {
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: "test",
+			},
+		},
+		Spec: pdnsv1.ZoneSpec{
+			Kind:        "Native",
+			Nameservers: []string{"ns1.org-test.test.com"},
+		},
+	}
+
+	zoneRef := pdnsv1.ZoneRef{Name: zone.Name, Kind: "Zone"}
+
+	rrsets := []pdnsv1.RRset{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ns1.org-test.test.com", Namespace: "testing"},
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "A",
+				TTL:     zoneTTL,
+				Name:    "ns1",
+				Records: []string{"192.0.2.1"},
+				ZoneRef: zoneRef,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "soa.org-test.test.com", Namespace: "testing"},
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "SOA",
+				TTL:     3600,
+				Name:    "org-test.test.com.",
+				Records: []string{"ns1.org-test.test.com. hostmaster.org-test.test.com. 2025010101 10800 3600 604800 3600"},
+				ZoneRef: zoneRef,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other.test.com", Namespace: "testing"},
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "A",
+				Name:    "www",
+				Records: []string{"192.0.2.9"},
+				ZoneRef: pdnsv1.ZoneRef{Name: "other.test.com", Kind: "Zone"},
+			},
+		},
+	}
+
+	return zone, rrsets
+}
+
+const exportTestZoneFile = `; org-test.test.com. exported by dockyards-pdns
+$ORIGIN org-test.test.com.
+org-test.test.com.	3600	IN	SOA	ns1.org-test.test.com. hostmaster.org-test.test.com. 2025010101 10800 3600 604800 3600
+org-test.test.com.	300	IN	NS	ns1.org-test.test.com.
+ns1.org-test.test.com.	300	IN	A	192.0.2.1
+`
+
+func TestRenderZoneFile(t *testing.T) {
+	zone, rrsets := exportTestObjects()
+
+	t.Run("test resources", func(t *testing.T) {
+		actual, err := renderZoneFile(&zone, rrsets, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != exportTestZoneFile {
+			t.Error(cmp.Diff(exportTestZoneFile, actual))
+		}
+	})
+
+	t.Run("test api records", func(t *testing.T) {
+		served := pdnsapi.Zone{
+			RRsets: []pdnsapi.RRset{
+				{
+					Name:    "ns1.org-test.test.com.",
+					Type:    "A",
+					TTL:     300,
+					Records: []pdnsapi.Record{{Content: "192.0.2.99"}},
+				},
+				{
+					Name: "app.org-test.test.com.",
+					Type: "A",
+					TTL:  60,
+					Records: []pdnsapi.Record{
+						{Content: "192.0.2.20"},
+						{Content: "192.0.2.21", Disabled: true},
+					},
+				},
+			},
+		}
+
+		actual, err := renderZoneFile(&zone, rrsets, &served)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `; org-test.test.com. exported by dockyards-pdns
+$ORIGIN org-test.test.com.
+org-test.test.com.	3600	IN	SOA	ns1.org-test.test.com. hostmaster.org-test.test.com. 2025010101 10800 3600 604800 3600
+org-test.test.com.	300	IN	NS	ns1.org-test.test.com.
+app.org-test.test.com.	60	IN	A	192.0.2.20
+ns1.org-test.test.com.	300	IN	A	192.0.2.1
+`
+
+		if actual != expected {
+			t.Error(cmp.Diff(expected, actual))
+		}
+	})
+
+	t.Run("test invalid record", func(t *testing.T) {
+		invalid := append(rrsets, pdnsv1.RRset{
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "A",
+				Name:    "bad",
+				Records: []string{"not-an-address"},
+				ZoneRef: pdnsv1.ZoneRef{Name: zone.Name, Kind: "Zone"},
+			},
+		})
+
+		_, err := renderZoneFile(&zone, invalid, nil)
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestZoneExporter(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = corev1.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	zone, rrsets := exportTestObjects()
+
+	challengeZone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "acme-challenge.org-test.test.com",
+			Namespace: "testing",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: "test",
+				LabelZoneType:                ZoneTypeACMEChallenge,
+			},
+		},
+	}
+
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org",
+		},
+		Spec: dockyardsv1.OrganizationSpec{
+			NamespaceRef: &corev1.LocalObjectReference{
+				Name: "testing",
+			},
+		},
+	}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zone, &challengeZone, &organization)
+	for i := range rrsets {
+		builder = builder.WithObjects(&rrsets[i])
+	}
+
+	c := builder.Build()
+
+	e := ZoneExporter{
+		Client:        c,
+		ConfigManager: dyconfig.NewFakeConfigManager(nil),
+	}
+
+	t.Run("test configmap", func(t *testing.T) {
+		err := e.Export(ctx, &ZoneExportPolicy{Mode: ZoneExportConfigMap})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var configMap corev1.ConfigMap
+		err = c.Get(ctx, client.ObjectKey{Name: zoneExportObjectName, Namespace: "testing"}, &configMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]string{
+			"org-test.test.com.zone": exportTestZoneFile,
+		}
+
+		if !cmp.Equal(configMap.Data, expected) {
+			t.Error(cmp.Diff(expected, configMap.Data))
+		}
+	})
+
+	t.Run("test secret", func(t *testing.T) {
+		err := e.Export(ctx, &ZoneExportPolicy{Mode: ZoneExportSecret})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var secret corev1.Secret
+		err = c.Get(ctx, client.ObjectKey{Name: zoneExportObjectName, Namespace: "testing"}, &secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(secret.Data["org-test.test.com.zone"]) != exportTestZoneFile {
+			t.Error(cmp.Diff(exportTestZoneFile, string(secret.Data["org-test.test.com.zone"])))
+		}
+	})
+
+	t.Run("test directory", func(t *testing.T) {
+		directory := t.TempDir()
+
+		err := e.Export(ctx, &ZoneExportPolicy{Mode: ZoneExportDirectory, Directory: directory})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := os.ReadFile(filepath.Join(directory, "org", "org-test.test.com.zone"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(actual) != exportTestZoneFile {
+			t.Error(cmp.Diff(exportTestZoneFile, string(actual)))
+		}
+
+		entries, err := os.ReadDir(filepath.Join(directory, "org"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) != 1 {
+			t.Errorf("expected a single zone file, got %d", len(entries))
+		}
+	})
+}
+
+func TestZoneExporterAPIBackend(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+
+	_ = corev1.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	zone, rrsets := exportTestObjects()
+
+	server := fakepdns.NewServer("test-api-key")
+	t.Cleanup(server.Close)
+
+	pdnsClient := pdnsapi.NewClient(server.URL, "test-api-key")
+
+	backend := APIBackend{
+		Client: pdnsClient,
+	}
+
+	err := backend.EnsureZone(ctx, &zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = backend.EnsureRecord(ctx, &zone, BackendRecord{ID: rrsets[0].Name, Spec: rrsets[0].Spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdnsClient.PatchRRsets(ctx, zone.Name, []pdnsapi.RRset{
+		{
+			Name:       "org-test.test.com.",
+			Type:       "SOA",
+			TTL:        3600,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    []pdnsapi.Record{{Content: "ns1.org-test.test.com. hostmaster.org-test.test.com. 2025010101 10800 3600 604800 3600"}},
+		},
+		{
+			Name:       "app.org-test.test.com.",
+			Type:       "A",
+			TTL:        60,
+			ChangeType: pdnsapi.ChangeTypeReplace,
+			Records:    []pdnsapi.Record{{Content: "192.0.2.20"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&zone).Build()
+
+	e := ZoneExporter{
+		Client: c,
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyBackend): backendAPI,
+		}),
+		PDNSClient: pdnsClient,
+	}
+
+	t.Run("test owned records", func(t *testing.T) {
+		files, err := e.ZoneFiles(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(files) != 1 {
+			t.Fatalf("expected a single zone file, got %d", len(files))
+		}
+
+		if files[0].Content != exportTestZoneFile {
+			t.Error(cmp.Diff(exportTestZoneFile, files[0].Content))
+		}
+	})
+
+	t.Run("test api records", func(t *testing.T) {
+		files, err := e.ZoneFiles(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(files) != 1 {
+			t.Fatalf("expected a single zone file, got %d", len(files))
+		}
+
+		expected := `; org-test.test.com. exported by dockyards-pdns
+$ORIGIN org-test.test.com.
+org-test.test.com.	3600	IN	SOA	ns1.org-test.test.com. hostmaster.org-test.test.com. 2025010101 10800 3600 604800 3600
+org-test.test.com.	300	IN	NS	ns1.org-test.test.com.
+app.org-test.test.com.	60	IN	A	192.0.2.20
+ns1.org-test.test.com.	300	IN	A	192.0.2.1
+`
+
+		if files[0].Content != expected {
+			t.Error(cmp.Diff(expected, files[0].Content))
+		}
+	})
+}
+
+func TestZoneExportPolicy(t *testing.T) {
+	tt := []struct {
+		name     string
+		data     map[string]string
+		expected *ZoneExportPolicy
+	}{
+		{
+			name: "test defaults",
+			expected: &ZoneExportPolicy{
+				Mode:     ZoneExportDisabled,
+				Interval: defaultZoneExportInterval,
+			},
+		},
+		{
+			name: "test directory",
+			data: map[string]string{
+				string(KeyZoneExport):           ZoneExportDirectory,
+				string(KeyZoneExportDirectory):  "/backup",
+				string(KeyZoneExportInterval):   "6h",
+				string(KeyZoneExportAPIRecords): "true",
+			},
+			expected: &ZoneExportPolicy{
+				Mode:       ZoneExportDirectory,
+				Interval:   6 * time.Hour,
+				Directory:  "/backup",
+				APIRecords: true,
+			},
+		},
+		{
+			name: "test directory without path",
+			data: map[string]string{
+				string(KeyZoneExport): ZoneExportDirectory,
+			},
+		},
+		{
+			name: "test invalid mode",
+			data: map[string]string{
+				string(KeyZoneExport): "s3",
+			},
+		},
+		{
+			name: "test invalid interval",
+			data: map[string]string{
+				string(KeyZoneExport):         ZoneExportConfigMap,
+				string(KeyZoneExportInterval): "0s",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseZoneExportPolicy(dyconfig.NewFakeConfigManager(tc.data))
+			if tc.expected == nil {
+				if err == nil {
+					t.Error("expected error")
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(actual, tc.expected) {
+				t.Error(cmp.Diff(tc.expected, actual))
+			}
+		})
+	}
+}
@@ -0,0 +1,461 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/sudoswedenab/dockyards-backend/api/apiutil"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Zone import operations.
+const (
+	ZoneImportCreate    = "create"
+	ZoneImportUpdate    = "update"
+	ZoneImportUnchanged = "unchanged"
+	ZoneImportDelete    = "delete"
+	ZoneImportSkip      = "skip"
+)
+
+// ZoneImportChange is what importing a zone file does to a single RRset.
+type ZoneImportChange struct {
+	Operation string
+	Name      string
+	Type      string
+	TTL       uint32
+	Records   []string
+
+	// Current holds the records before an update or delete.
+	Current []string
+
+	// Reason explains why an RRset is skipped.
+	Reason string
+
+	record BackendRecord
+}
+
+// ZoneImportPlan is the diff of a zone file against the imported records of a cluster zone.
+type ZoneImportPlan struct {
+	Zone    *pdnsv1.Zone
+	Changes []ZoneImportChange
+}
+
+// String renders the plan as a diff, `+` lines are added and `-` lines removed.
+func (p *ZoneImportPlan) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "; import into zone %s\n", p.Zone.Name+".")
+
+	for _, change := range p.Changes {
+		switch change.Operation {
+		case ZoneImportSkip:
+			fmt.Fprintf(&b, "# %s %s skipped, %s\n", change.Name, change.Type, change.Reason)
+		case ZoneImportUnchanged:
+			fmt.Fprintf(&b, "  %s %s unchanged\n", change.Name, change.Type)
+		default:
+			for _, content := range change.Current {
+				fmt.Fprintf(&b, "- %s\t%s\t%s\n", change.Name, change.Type, content)
+			}
+
+			for _, content := range change.Records {
+				fmt.Fprintf(&b, "+ %s\t%d\t%s\t%s\n", change.Name, change.TTL, change.Type, content)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// HasChanges reports whether applying the plan changes any RRset.
+func (p *ZoneImportPlan) HasChanges() bool {
+	return slices.ContainsFunc(p.Changes, func(change ZoneImportChange) bool {
+		return change.Operation == ZoneImportCreate || change.Operation == ZoneImportUpdate || change.Operation == ZoneImportDelete
+	})
+}
+
+// zoneImportKey identifies an RRset in a zone file.
+type zoneImportKey struct {
+	name   string
+	rrtype string
+}
+
+// parseZoneFile reads the RRsets of a zone from a master file. Records outside the zone are rejected, the SOA, apex NS
+// and primary nameserver records and the reserved RRsets, keyed by reservedRecordKey, that are maintained by the
+// controller are returned as skipped changes.
+func parseZoneFile(zoneName string, r io.Reader, filename string, reserved map[string]string) ([]pdnsv1.RRsetSpec, []ZoneImportChange, error) {
+	origin := dns.Fqdn(strings.ToLower(zoneName))
+	primaryNameserver := "ns1." + origin
+
+	rrsets := make(map[zoneImportKey]*pdnsv1.RRsetSpec)
+	skipped := make(map[zoneImportKey]*ZoneImportChange)
+
+	var keys []zoneImportKey
+
+	parser := dns.NewZoneParser(r, origin, filename)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		header := rr.Header()
+		owner := dns.CanonicalName(header.Name)
+		rrtype := dns.TypeToString[header.Rrtype]
+
+		if !dns.IsSubDomain(origin, owner) {
+			return nil, nil, fmt.Errorf("record %s %s is outside zone %s", owner, rrtype, zoneName)
+		}
+
+		if header.Rrtype == dns.TypeSOA && owner != origin {
+			return nil, nil, fmt.Errorf("zone file is for %s, expected %s", owner, origin)
+		}
+
+		name := importRecordName(origin, owner)
+		key := zoneImportKey{name: name, rrtype: rrtype}
+		content := strings.TrimPrefix(rr.String(), header.String())
+
+		var reason string
+
+		switch {
+		case header.Rrtype == dns.TypeSOA:
+			reason = "SOA is maintained by dockyards-pdns"
+		case header.Rrtype == dns.TypeNS && owner == origin:
+			reason = "apex NS is maintained by dockyards-pdns"
+		case (header.Rrtype == dns.TypeA || header.Rrtype == dns.TypeAAAA) && owner == primaryNameserver:
+			reason = "primary nameserver is maintained by dockyards-pdns"
+		case reserved[reservedRecordKey(owner, rrtype)] != "":
+			reason = reserved[reservedRecordKey(owner, rrtype)] + " is maintained by dockyards-pdns"
+		}
+
+		if reason != "" {
+			change, found := skipped[key]
+			if !found {
+				change = &ZoneImportChange{
+					Operation: ZoneImportSkip,
+					Name:      name,
+					Type:      rrtype,
+					TTL:       header.Ttl,
+					Reason:    reason,
+				}
+
+				skipped[key] = change
+				keys = append(keys, key)
+			}
+
+			change.Records = append(change.Records, content)
+
+			continue
+		}
+
+		rrset, found := rrsets[key]
+		if !found {
+			rrset = &pdnsv1.RRsetSpec{
+				Name: name,
+				Type: rrtype,
+				TTL:  header.Ttl,
+			}
+
+			rrsets[key] = rrset
+			keys = append(keys, key)
+		}
+
+		// PowerDNS keeps a single TTL per RRset, the lowest TTL in the file is used.
+		rrset.TTL = min(rrset.TTL, header.Ttl)
+
+		if !slices.Contains(rrset.Records, content) {
+			rrset.Records = append(rrset.Records, content)
+		}
+	}
+
+	err := parser.Err()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid zone file %s: %w", filename, err)
+	}
+
+	var specs []pdnsv1.RRsetSpec
+	var changes []ZoneImportChange
+
+	for _, key := range keys {
+		rrset, found := rrsets[key]
+		if found {
+			slices.Sort(rrset.Records)
+			specs = append(specs, *rrset)
+
+			continue
+		}
+
+		changes = append(changes, *skipped[key])
+	}
+
+	return specs, changes, nil
+}
+
+// importRecordName returns the name of an RRset relative to the zone, or the zone itself for the apex.
+func importRecordName(origin, owner string) string {
+	if owner == origin {
+		return origin
+	}
+
+	return strings.TrimSuffix(owner, "."+origin)
+}
+
+// importRecordID returns the id of an imported RRset, names that are not valid resource names are hashed.
+func importRecordID(zoneName, name, rrtype string) string {
+	rrtype = strings.ToLower(rrtype)
+
+	if name == dns.Fqdn(zoneName) {
+		return "import." + rrtype + "." + zoneName
+	}
+
+	id := "import." + rrtype + "." + name + "." + zoneName
+	if len(validation.IsDNS1123Subdomain(id)) == 0 {
+		return id
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+
+	return fmt.Sprintf("import.%s.%08x.%s", rrtype, h.Sum32(), zoneName)
+}
+
+// ZoneImporter seeds or restores the zone of a cluster from a zone file.
+type ZoneImporter struct {
+	client.Client
+	*dyconfig.ConfigManager
+
+	// Backend stores the imported records when set, otherwise the backend is picked by the config key.
+	Backend Backend
+}
+
+// clusterZone returns the zone that reconcileDNSZone maintains for the cluster.
+func (i *ZoneImporter) clusterZone(ctx context.Context, namespace, clusterName string) (*pdnsv1.Zone, error) {
+	var cluster dockyardsv1.Cluster
+	err := i.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, &cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerOrganization, err := apiutil.GetOwnerOrganization(ctx, i.Client, &cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if ownerOrganization == nil {
+		return nil, fmt.Errorf("cluster %s has no owner organization", cluster.Name)
+	}
+
+	naming, err := resolveClusterZoneNaming(i.ConfigManager, &cluster, ownerOrganization)
+	if err != nil {
+		return nil, err
+	}
+
+	var zone pdnsv1.Zone
+	err = i.Get(ctx, client.ObjectKey{Name: naming.Name, Namespace: cluster.Namespace}, &zone)
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("zone %s of cluster %s does not exist yet", naming.Name, cluster.Name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &zone, nil
+}
+
+func (i *ZoneImporter) reconciler() *ZoneReconciler {
+	return &ZoneReconciler{
+		Client:        i.Client,
+		ConfigManager: i.ConfigManager,
+		Backend:       i.Backend,
+	}
+}
+
+func (i *ZoneImporter) backend(ctx context.Context, zone *pdnsv1.Zone) (Backend, error) {
+	return i.reconciler().backend(ctx, zone)
+}
+
+// reservedRecords returns the RRsets of the zone that the controller maintains, keyed by reservedRecordKey, so an
+// import does not take them over. Next to the reserved records of the reconciler these are the record template and
+// ACME challenge RRsets the zone already has.
+func (i *ZoneImporter) reservedRecords(ctx context.Context, zone *pdnsv1.Zone, backend Backend) (map[string]string, error) {
+	reserved, err := i.reconciler().reservedRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, label := range []string{LabelRecordTemplate, LabelACMEChallenge} {
+		records, err := backend.ListRecords(ctx, zone, label)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			key := reservedRecordKey(strings.ToLower(rrsetFQDN(record.Spec.Name, zone.Name)), record.Spec.Type)
+
+			_, found := reserved[key]
+			if !found {
+				reserved[key] = "RRset " + record.ID
+			}
+		}
+	}
+
+	return reserved, nil
+}
+
+// Plan reads a zone file for the zone of a cluster and compares it with the records imported earlier. With prune,
+// imported records missing from the file are deleted.
+func (i *ZoneImporter) Plan(ctx context.Context, namespace, clusterName string, r io.Reader, filename string, prune bool) (*ZoneImportPlan, error) {
+	zone, err := i.clusterZone(ctx, namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := i.backend(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved, err := i.reservedRecords(ctx, zone, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, skipped, err := parseZoneFile(zone.Name, r, filename, reserved)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := backend.ListRecords(ctx, zone, LabelZoneImport)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]BackendRecord, len(records))
+	for _, record := range records {
+		current[record.ID] = record
+	}
+
+	plan := ZoneImportPlan{
+		Zone:    zone,
+		Changes: skipped,
+	}
+
+	for _, spec := range specs {
+		spec.ZoneRef = pdnsv1.ZoneRef{
+			Name: zone.Name,
+			Kind: "Zone", // PDNS library does not offer ZoneKind
+		}
+
+		record := BackendRecord{
+			ID: importRecordID(zone.Name, spec.Name, spec.Type),
+			Labels: map[string]string{
+				LabelZoneImport:              "true",
+				dockyardsv1.LabelClusterName: clusterName,
+			},
+			Spec: spec,
+		}
+
+		change := ZoneImportChange{
+			Operation: ZoneImportCreate,
+			Name:      spec.Name,
+			Type:      spec.Type,
+			TTL:       spec.TTL,
+			Records:   spec.Records,
+			record:    record,
+		}
+
+		existing, found := current[record.ID]
+		if found {
+			delete(current, record.ID)
+
+			existingRecords := slices.Sorted(slices.Values(existing.Spec.Records))
+
+			if existing.Spec.TTL == spec.TTL && slices.Equal(existingRecords, spec.Records) && maps.Equal(existing.Labels, record.Labels) {
+				change.Operation = ZoneImportUnchanged
+			} else {
+				change.Operation = ZoneImportUpdate
+				change.Current = existingRecords
+			}
+		}
+
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	if prune {
+		for _, id := range slices.Sorted(maps.Keys(current)) {
+			existing := current[id]
+
+			plan.Changes = append(plan.Changes, ZoneImportChange{
+				Operation: ZoneImportDelete,
+				Name:      existing.Spec.Name,
+				Type:      existing.Spec.Type,
+				TTL:       existing.Spec.TTL,
+				Current:   slices.Sorted(slices.Values(existing.Spec.Records)),
+				record:    existing,
+			})
+		}
+	}
+
+	return &plan, nil
+}
+
+// Apply writes the changes of a plan to the backend.
+func (i *ZoneImporter) Apply(ctx context.Context, plan *ZoneImportPlan) error {
+	logger := ctrl.Log.WithName("zone-importer")
+
+	backend, err := i.backend(ctx, plan.Zone)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, change := range plan.Changes {
+		switch change.Operation {
+		case ZoneImportCreate, ZoneImportUpdate:
+			operationResult, err := backend.EnsureRecord(ctx, plan.Zone, change.record)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error importing %s %s: %w", change.Name, change.Type, err))
+
+				continue
+			}
+
+			logger.Info("Imported RRSet", "zone", plan.Zone.Name, "rrset", change.record.ID, "operationResult", operationResult)
+		case ZoneImportDelete:
+			deleted, err := backend.DeleteRecord(ctx, plan.Zone, change.record)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error pruning %s %s: %w", change.Name, change.Type, err))
+
+				continue
+			}
+
+			if deleted {
+				logger.Info("Pruned imported RRSet", "zone", plan.Zone.Name, "rrset", change.record.ID)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
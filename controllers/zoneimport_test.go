@@ -0,0 +1,386 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const importTestZoneFile = `$ORIGIN org-test.test.com.
+$TTL 300
+@	3600	IN	SOA	ns1 hostmaster 2025010101 10800 3600 604800 3600
+@		IN	NS	ns1
+ns1		IN	A	192.0.2.1
+@		IN	MX	10 mail
+www	60	IN	A	192.0.2.21
+www	120	IN	A	192.0.2.20
+_dmarc		IN	TXT	"v=DMARC1; p=none"
+`
+
+func TestParseZoneFile(t *testing.T) {
+	t.Run("test records", func(t *testing.T) {
+		specs, skipped, err := parseZoneFile("org-test.test.com", strings.NewReader(importTestZoneFile), "test.zone", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []pdnsv1.RRsetSpec{
+			{
+				Name:    "org-test.test.com.",
+				Type:    "MX",
+				TTL:     300,
+				Records: []string{"10 mail.org-test.test.com."},
+			},
+			{
+				Name:    "www",
+				Type:    "A",
+				TTL:     60,
+				Records: []string{"192.0.2.20", "192.0.2.21"},
+			},
+			{
+				Name:    "_dmarc",
+				Type:    "TXT",
+				TTL:     300,
+				Records: []string{`"v=DMARC1; p=none"`},
+			},
+		}
+
+		if !cmp.Equal(specs, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, specs))
+		}
+
+		var types []string
+		for _, change := range skipped {
+			if change.Operation != ZoneImportSkip {
+				t.Errorf("expected skip, got %s", change.Operation)
+			}
+
+			types = append(types, change.Type)
+		}
+
+		expectedTypes := []string{"SOA", "NS", "A"}
+		if !cmp.Equal(types, expectedTypes) {
+			t.Errorf("diff: %s", cmp.Diff(expectedTypes, types))
+		}
+	})
+
+	t.Run("test reserved records", func(t *testing.T) {
+		reserved := map[string]string{
+			reservedRecordKey("www.org-test.test.com.", "A"): "the record template web",
+		}
+
+		specs, skipped, err := parseZoneFile("org-test.test.com", strings.NewReader(importTestZoneFile), "test.zone", reserved)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, spec := range specs {
+			if spec.Name == "www" {
+				t.Errorf("expected reserved www to be skipped, got %v", spec)
+			}
+		}
+
+		found := slices.ContainsFunc(skipped, func(change ZoneImportChange) bool {
+			return change.Name == "www" && change.Type == "A" && change.Reason == "the record template web is maintained by dockyards-pdns"
+		})
+		if !found {
+			t.Errorf("expected skipped www, got %v", skipped)
+		}
+	})
+
+	t.Run("test other zone", func(t *testing.T) {
+		_, _, err := parseZoneFile("org-other.test.com", strings.NewReader(importTestZoneFile), "test.zone", nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("test record outside zone", func(t *testing.T) {
+		zoneFile := "www.example.com. 300 IN A 192.0.2.1\n"
+
+		_, _, err := parseZoneFile("org-test.test.com", strings.NewReader(zoneFile), "test.zone", nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("test invalid zone file", func(t *testing.T) {
+		zoneFile := "www 300 IN A not-an-address\n"
+
+		_, _, err := parseZoneFile("org-test.test.com", strings.NewReader(zoneFile), "test.zone", nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestImportRecordID(t *testing.T) {
+	tt := []struct {
+		name     string
+		record   string
+		rrtype   string
+		expected string
+	}{
+		{
+			name:     "test apex",
+			record:   "org-test.test.com.",
+			rrtype:   "MX",
+			expected: "import.mx.org-test.test.com",
+		},
+		{
+			name:     "test relative",
+			record:   "www",
+			rrtype:   "A",
+			expected: "import.a.www.org-test.test.com",
+		},
+		{
+			name:     "test hashed",
+			record:   "_dmarc",
+			rrtype:   "TXT",
+			expected: "import.txt.3fce51c7.org-test.test.com",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := importRecordID("org-test.test.com", tc.record, tc.rrtype)
+			if actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestZoneImporter(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	organization := dockyardsv1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "org",
+			UID:  "org-uid",
+		},
+	}
+
+	cluster := dockyardsv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testing",
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: dockyardsv1.GroupVersion.String(),
+					Kind:       dockyardsv1.OrganizationKind,
+					Name:       organization.Name,
+					UID:        organization.UID,
+				},
+			},
+		},
+	}
+
+	zone := pdnsv1.Zone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-test.test.com",
+			Namespace: "testing",
+			Labels: map[string]string{
+				dockyardsv1.LabelClusterName: "test",
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&organization, &cluster, &zone).Build()
+
+	backend := NewMemoryBackend()
+
+	i := ZoneImporter{
+		Client: c,
+		ConfigManager: dyconfig.NewFakeConfigManager(map[string]string{
+			string(KeyManagementDomain): "test.com",
+		}),
+		Backend: backend,
+	}
+
+	t.Run("test create", func(t *testing.T) {
+		plan, err := i.Plan(ctx, "testing", "test", strings.NewReader(importTestZoneFile), "test.zone", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !plan.HasChanges() {
+			t.Fatal("expected changes")
+		}
+
+		if !strings.Contains(plan.String(), "+ www\t60\tA\t192.0.2.20\n") {
+			t.Errorf("expected www in diff, got %s", plan)
+		}
+
+		err = i.Apply(ctx, plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		record, found := backend.Record("testing", "import.a.www.org-test.test.com")
+		if !found {
+			t.Fatal("expected imported record")
+		}
+
+		expected := map[string]string{
+			LabelZoneImport:              "true",
+			dockyardsv1.LabelClusterName: "test",
+		}
+
+		if !cmp.Equal(record.Labels, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, record.Labels))
+		}
+
+		_, found = backend.Record("testing", "ns1.org-test.test.com")
+		if found {
+			t.Error("expected primary nameserver to be skipped")
+		}
+	})
+
+	t.Run("test unchanged", func(t *testing.T) {
+		plan, err := i.Plan(ctx, "testing", "test", strings.NewReader(importTestZoneFile), "test.zone", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if plan.HasChanges() {
+			t.Errorf("expected no changes, got %s", plan)
+		}
+	})
+
+	t.Run("test update and prune", func(t *testing.T) {
+		zoneFile := "www 60 IN A 192.0.2.30\n"
+
+		plan, err := i.Plan(ctx, "testing", "test", strings.NewReader(zoneFile), "test.zone", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		operations := make(map[string]string)
+		for _, change := range plan.Changes {
+			operations[change.Name+"/"+change.Type] = change.Operation
+		}
+
+		expected := map[string]string{
+			"www/A":                 ZoneImportUpdate,
+			"org-test.test.com./MX": ZoneImportDelete,
+			"_dmarc/TXT":            ZoneImportDelete,
+		}
+
+		if !cmp.Equal(operations, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, operations))
+		}
+
+		err = i.Apply(ctx, plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, found := backend.Record("testing", "import.mx.org-test.test.com")
+		if found {
+			t.Error("expected pruned record")
+		}
+
+		record, _ := backend.Record("testing", "import.a.www.org-test.test.com")
+		if !cmp.Equal(record.Spec.Records, []string{"192.0.2.30"}) {
+			t.Errorf("expected updated record, got %v", record.Spec.Records)
+		}
+	})
+
+	t.Run("test controller records", func(t *testing.T) {
+		child := pdnsv1.Zone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dev.org-test.test.com",
+				Namespace: "testing",
+				Annotations: map[string]string{
+					AnnotationParentZone: zone.Name,
+				},
+			},
+		}
+
+		err := c.Create(ctx, &child)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = backend.EnsureRecord(ctx, &zone, BackendRecord{
+			ID: "tmpl.web." + zone.Name,
+			Labels: map[string]string{
+				LabelRecordTemplate: "web",
+			},
+			Spec: pdnsv1.RRsetSpec{
+				Type:    "A",
+				Name:    "web",
+				TTL:     300,
+				Records: []string{"192.0.2.40"},
+				ZoneRef: pdnsv1.ZoneRef{Name: zone.Name, Kind: "Zone"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zoneFile := `web	IN	A	192.0.2.41
+dev	IN	NS	ns1.dev
+ns1.dev	IN	A	192.0.2.42
+app	IN	A	192.0.2.43
+`
+
+		plan, err := i.Plan(ctx, "testing", "test", strings.NewReader(zoneFile), "test.zone", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		operations := make(map[string]string)
+		for _, change := range plan.Changes {
+			operations[change.Name+"/"+change.Type] = change.Operation
+		}
+
+		expected := map[string]string{
+			"web/A":     ZoneImportSkip,
+			"dev/NS":    ZoneImportSkip,
+			"ns1.dev/A": ZoneImportSkip,
+			"app/A":     ZoneImportCreate,
+		}
+
+		if !cmp.Equal(operations, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, operations))
+		}
+	})
+
+	t.Run("test missing cluster", func(t *testing.T) {
+		_, err := i.Plan(ctx, "testing", "missing", strings.NewReader(importTestZoneFile), "test.zone", false)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,255 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/pflag"
+	controllers "github.com/sudoswedenab/dockyards-pdns/controllers"
+)
+
+// runStatus prints the zone, RRsets, workloads and PowerDNS endpoints of a cluster.
+func runStatus(ctx context.Context, args []string) error {
+	flags := pflag.NewFlagSet("status", pflag.ExitOnError)
+
+	var dockyardsNamespace string
+	var configMap string
+	var namespace string
+	flags.StringVar(&configMap, "config-map", "dockyards-system", "ConfigMap name")
+	flags.StringVar(&dockyardsNamespace, "dockyards-namespace", "dockyards-system", "dockyards namespace")
+	flags.StringVar(&namespace, "namespace", "", "namespace of the cluster")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if namespace == "" || flags.NArg() != 1 {
+		return errors.New("expected --namespace and a single cluster")
+	}
+
+	c, dockyardsConfig, err := newCommandClient(ctx, dockyardsNamespace, configMap)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := controllers.Diagnostics{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+	}
+
+	status, err := diagnostics.ClusterStatus(ctx, namespace, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Cluster:\t%s/%s\n", status.Namespace, status.Cluster)
+	fmt.Fprintf(w, "Organization:\t%s\n", status.Organization)
+
+	switch {
+	case status.Zone == nil:
+		fmt.Fprintf(w, "Zone:\t%s (not created)\n", status.ZoneName)
+	case status.Zone.Status.Serial != nil:
+		fmt.Fprintf(w, "Zone:\t%s (%s, serial %d)\n", status.ZoneName, status.SyncStatus, *status.Zone.Status.Serial)
+	default:
+		fmt.Fprintf(w, "Zone:\t%s (%s)\n", status.ZoneName, status.SyncStatus)
+	}
+
+	if status.ParentZone != "" {
+		fmt.Fprintf(w, "Parent zone:\t%s\n", status.ParentZone)
+	}
+
+	if status.EndpointsError != "" {
+		fmt.Fprintf(w, "Endpoints:\terror: %s\n", status.EndpointsError)
+	} else {
+		fmt.Fprintf(w, "Endpoints:\t%s dns=%s api=%s\n", status.Endpoints.PDNSBackend.Name, status.Endpoints.DNSIP, strings.Join(status.Endpoints.APIIPs, ","))
+	}
+
+	fmt.Fprintln(w, "\nCONDITION\tSTATUS\tREASON\tMESSAGE")
+
+	for _, condition := range status.Conditions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+	}
+
+	fmt.Fprintln(w, "\nWORKLOAD\tREADY\tREASON\tMESSAGE")
+
+	for _, workload := range status.Workloads {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", workload.Name, workload.Ready, workload.Reason, workload.Message)
+	}
+
+	fmt.Fprintln(w, "\nRRSET\tTYPE\tTTL\tSTATUS\tRECORDS")
+
+	if status.RRsetsError != "" {
+		fmt.Fprintf(w, "error: %s\n", status.RRsetsError)
+	}
+
+	for _, rrset := range status.RRsets {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", rrset.Name, rrset.Type, rrset.TTL, rrset.SyncStatus, strings.Join(rrset.Records, ", "))
+	}
+
+	return w.Flush()
+}
+
+// runResync annotates clusters so that their zones are reconciled right away.
+func runResync(ctx context.Context, args []string) error {
+	flags := pflag.NewFlagSet("resync", pflag.ExitOnError)
+
+	var dockyardsNamespace string
+	var configMap string
+	var namespace string
+	flags.StringVar(&configMap, "config-map", "dockyards-system", "ConfigMap name")
+	flags.StringVar(&dockyardsNamespace, "dockyards-namespace", "dockyards-system", "dockyards namespace")
+	flags.StringVar(&namespace, "namespace", "", "namespace of the clusters")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if namespace == "" || flags.NArg() == 0 {
+		return errors.New("expected --namespace and at least one cluster")
+	}
+
+	c, dockyardsConfig, err := newCommandClient(ctx, dockyardsNamespace, configMap)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := controllers.Diagnostics{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+	}
+
+	for _, name := range flags.Args() {
+		err := diagnostics.Resync(ctx, namespace, name, time.Now())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(os.Stderr, "requested resync of cluster", namespace+"/"+name)
+	}
+
+	return nil
+}
+
+// runDoctor checks the configuration and the PowerDNS installation and fails if any check fails.
+func runDoctor(ctx context.Context, args []string) error {
+	flags := pflag.NewFlagSet("doctor", pflag.ExitOnError)
+
+	var dockyardsNamespace string
+	var configMap string
+	flags.StringVar(&configMap, "config-map", "dockyards-system", "ConfigMap name")
+	flags.StringVar(&dockyardsNamespace, "dockyards-namespace", "dockyards-system", "dockyards namespace")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	c, dockyardsConfig, err := newCommandClient(ctx, dockyardsNamespace, configMap)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := controllers.Diagnostics{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	failed := 0
+
+	for _, check := range diagnostics.Doctor(ctx) {
+		if check.Err != nil {
+			failed++
+
+			fmt.Fprintf(w, "FAIL\t%s\t%s\n", check.Name, check.Err)
+
+			continue
+		}
+
+		fmt.Fprintf(w, "OK\t%s\t%s\n", check.Name, check.Message)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return errors.New(strconv.Itoa(failed) + " checks failed")
+	}
+
+	return nil
+}
+
+// runListOrphans prints the zones and RRsets that the garbage collector considers orphaned.
+func runListOrphans(ctx context.Context, args []string) error {
+	flags := pflag.NewFlagSet("list-orphans", pflag.ExitOnError)
+
+	var dockyardsNamespace string
+	var configMap string
+	var shardName string
+	flags.StringVar(&configMap, "config-map", "dockyards-system", "ConfigMap name")
+	flags.StringVar(&dockyardsNamespace, "dockyards-namespace", "dockyards-system", "dockyards namespace")
+	flags.StringVar(&shardName, "shard", "", "only list the orphans of the zones claimed by the shard")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	c, dockyardsConfig, err := newCommandClient(ctx, dockyardsNamespace, configMap)
+	if err != nil {
+		return err
+	}
+
+	shard, err := newShard(shardName, "", "", nil)
+	if err != nil {
+		return err
+	}
+
+	g := controllers.GarbageCollector{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+		Shard:         shard,
+	}
+
+	orphans, err := g.Orphans(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tORPHANED SINCE\tREASON")
+
+	for _, orphan := range orphans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", orphan.Kind, orphan.Namespace, orphan.Name, orphan.OrphanedSince, orphan.Reason)
+	}
+
+	return w.Flush()
+}
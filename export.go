@@ -0,0 +1,113 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	pdnsv1 "github.com/powerdns-operator/powerdns-operator/api/v1alpha2"
+	"github.com/spf13/pflag"
+	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
+	dockyardsv1 "github.com/sudoswedenab/dockyards-backend/api/v1alpha3"
+	controllers "github.com/sudoswedenab/dockyards-pdns/controllers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// newCommandClient returns a client and the Dockyards config for commands that run once without a manager.
+func newCommandClient(ctx context.Context, dockyardsNamespace, configMap string) (client.Client, *dyconfig.ConfigManager, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scheme := runtime.NewScheme()
+
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = dockyardsv1.AddToScheme(scheme)
+	_ = pdnsv1.AddToScheme(scheme)
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dockyardsConfig corev1.ConfigMap
+	err = c.Get(ctx, client.ObjectKey{Namespace: dockyardsNamespace, Name: configMap}, &dockyardsConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The config is read once, commands do not run long enough to follow changes.
+	return c, dyconfig.NewFakeConfigManager(dockyardsConfig.Data), nil
+}
+
+// runExport renders every managed zone to a master file once and writes the files to a directory, or to a ConfigMap
+// or Secret per organization.
+func runExport(ctx context.Context, args []string) error {
+	flags := pflag.NewFlagSet("export", pflag.ExitOnError)
+
+	var dockyardsNamespace string
+	var configMap string
+	var policy controllers.ZoneExportPolicy
+	var shardName string
+	flags.StringVar(&configMap, "config-map", "dockyards-system", "ConfigMap name")
+	flags.StringVar(&dockyardsNamespace, "dockyards-namespace", "dockyards-system", "dockyards namespace")
+	flags.StringVar(&policy.Mode, "output", controllers.ZoneExportDirectory, "where to write zone files, directory, configmap or secret")
+	flags.StringVar(&policy.Directory, "directory", ".", "directory to write zone files to")
+	flags.BoolVar(&policy.APIRecords, "api-records", false, "include RRsets served by the PowerDNS API without an RRset resource")
+	flags.StringVar(&shardName, "shard", "", "only export the zones claimed by the shard")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	switch policy.Mode {
+	case controllers.ZoneExportDirectory, controllers.ZoneExportConfigMap, controllers.ZoneExportSecret:
+	default:
+		return fmt.Errorf("unsupported output %s", policy.Mode)
+	}
+
+	c, dockyardsConfig, err := newCommandClient(ctx, dockyardsNamespace, configMap)
+	if err != nil {
+		return err
+	}
+
+	shard, err := newShard(shardName, "", "", nil)
+	if err != nil {
+		return err
+	}
+
+	exporter := controllers.ZoneExporter{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+		Shard:         shard,
+	}
+
+	err = exporter.Export(ctx, &policy)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "exported zones to", policy.Mode)
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+	controllers "github.com/sudoswedenab/dockyards-pdns/controllers"
+)
+
+// runImport reads a zone file into the zone of a cluster. The diff against the records imported earlier is printed
+// before anything is written.
+func runImport(ctx context.Context, args []string) error {
+	flags := pflag.NewFlagSet("import", pflag.ExitOnError)
+
+	var dockyardsNamespace string
+	var configMap string
+	var clusterName string
+	var namespace string
+	var dryRun bool
+	var prune bool
+	flags.StringVar(&configMap, "config-map", "dockyards-system", "ConfigMap name")
+	flags.StringVar(&dockyardsNamespace, "dockyards-namespace", "dockyards-system", "dockyards namespace")
+	flags.StringVar(&clusterName, "cluster", "", "name of the cluster to import into")
+	flags.StringVar(&namespace, "namespace", "", "namespace of the cluster")
+	flags.BoolVar(&dryRun, "dry-run", false, "print the diff without importing")
+	flags.BoolVar(&prune, "prune", false, "delete imported RRsets missing from the zone file")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if clusterName == "" || namespace == "" {
+		return errors.New("--cluster and --namespace are required")
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("expected a single zone file")
+	}
+
+	filename := flags.Arg(0)
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	c, dockyardsConfig, err := newCommandClient(ctx, dockyardsNamespace, configMap)
+	if err != nil {
+		return err
+	}
+
+	importer := controllers.ZoneImporter{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+	}
+
+	plan, err := importer.Plan(ctx, namespace, clusterName, file, filename, prune)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(plan)
+
+	if dryRun || !plan.HasChanges() {
+		return nil
+	}
+
+	err = importer.Apply(ctx, plan)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "imported zone file into", plan.Zone.Name)
+
+	return nil
+}
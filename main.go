@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -24,19 +25,80 @@ import (
 	"github.com/spf13/pflag"
 	dyconfig "github.com/sudoswedenab/dockyards-backend/api/config"
 	controllers "github.com/sudoswedenab/dockyards-pdns/controllers"
+	"k8s.io/apimachinery/pkg/labels"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;patch;watch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=create;get;list;update;watch
+
+// commands run once instead of starting the manager when named by the first argument.
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"export":       runExport,
+	"import":       runImport,
+	"status":       runStatus,
+	"resync":       runResync,
+	"doctor":       runDoctor,
+	"list-orphans": runListOrphans,
+}
 
 func main() {
+	if len(os.Args) > 1 {
+		command, found := commands[os.Args[1]]
+		if found {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			ctrl.SetLogger(logr.FromSlogHandler(slog.NewTextHandler(os.Stderr, nil)))
+
+			err := command(ctx, os.Args[2:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error running %s: %s\n", os.Args[1], err)
+
+				os.Exit(1)
+			}
+
+			return
+		}
+	}
+
 	var dockyardsNamespace string
 	var configMap string
+	var dryRun bool
+	var dryRunReport string
+	var configStatus string
+	var healthProbeBindAddress string
+	var cacheLayout controllers.CacheLayout
+	var cacheServiceSelector string
+	var cacheClusterWide bool
+	var shardName string
+	var shardClusterSelector string
+	var shardOrganizationSelector string
+	var shardNamespaces []string
+	var leaderElection bool
+	var leaderElectionID string
+	var leaderElectionNamespace string
 	pflag.StringVar(&configMap, "config-map", "dockyards-system", "ConfigMap name")
 	pflag.StringVar(&dockyardsNamespace, "dockyards-namespace", "dockyards-system", "dockyards namespace")
+	pflag.BoolVar(&dryRun, "dry-run", false, "plan changes without applying them")
+	pflag.StringVar(&dryRunReport, "dry-run-report", "dockyards-pdns-plan", "ConfigMap name for the dry-run report")
+	pflag.StringVar(&configStatus, "config-status", "dockyards-pdns-status", "ConfigMap name for the configuration status")
+	pflag.StringVar(&healthProbeBindAddress, "health-probe-bind-address", ":8081", "address the health and readiness probes bind to")
+	pflag.StringVar(&cacheLayout.PDNSName, "pdns-name", "", "name of the PowerDNS secret to cache, defaults to the PowerDNS backends in the config")
+	pflag.StringVar(&cacheLayout.PDNSNamespace, "pdns-namespace", "", "namespace to cache the PowerDNS secret and services in, defaults to the PowerDNS backends in the config")
+	pflag.StringVar(&cacheServiceSelector, "cache-service-selector", "", "label selector for the services cached in the PowerDNS namespace")
+	pflag.BoolVar(&cacheClusterWide, "cache-cluster-wide", false, "cache secrets, services and config maps in every namespace")
+	pflag.StringVar(&shardName, "shard", "", "name of the shard claiming the zones of this instance, unsharded when empty")
+	pflag.StringVar(&shardClusterSelector, "shard-cluster-selector", "", "label selector for the clusters of the shard")
+	pflag.StringVar(&shardOrganizationSelector, "shard-organization-selector", "", "label selector for the organizations of the shard")
+	pflag.StringSliceVar(&shardNamespaces, "shard-namespaces", nil, "namespaces of the shard, all namespaces when empty")
+	pflag.BoolVar(&leaderElection, "leader-elect", false, "elect a leader among the replicas, required when running more than one")
+	pflag.StringVar(&leaderElectionID, "leader-election-id", "", "name of the leader election lease, defaults to dockyards-pdns or dockyards-pdns-<shard>")
+	pflag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "namespace of the leader election lease, defaults to the dockyards namespace")
 	pflag.Parse()
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
@@ -54,7 +116,71 @@ func main() {
 		os.Exit(1)
 	}
 
-	m, err := manager.New(cfg, manager.Options{})
+	if cacheClusterWide {
+		cacheLayout = controllers.CacheLayout{}
+	} else if cacheLayout.PDNSName == "" || cacheLayout.PDNSNamespace == "" {
+		// The cache is created with the manager, so the config is read once here and changes need a restart.
+		_, startupConfig, err := newCommandClient(ctx, dockyardsNamespace, configMap)
+		if err == nil {
+			cacheLayout.Backends, err = controllers.ParsePDNSBackends(startupConfig)
+		}
+		if err != nil {
+			logger.Error("error reading config for the cache layout, caching secrets and services in every namespace", "err", err)
+
+			cacheLayout = controllers.CacheLayout{}
+		}
+	}
+
+	if !cacheClusterWide {
+		cacheLayout.ConfigNamespace = dockyardsNamespace
+	}
+
+	if cacheServiceSelector != "" {
+		cacheLayout.ServiceSelector, err = labels.Parse(cacheServiceSelector)
+		if err != nil {
+			logger.Error("error parsing cache service selector", "err", err)
+
+			os.Exit(1)
+		}
+	}
+
+	shard, err := newShard(shardName, shardClusterSelector, shardOrganizationSelector, shardNamespaces)
+	if err != nil {
+		logger.Error("error creating shard", "err", err)
+
+		os.Exit(1)
+	}
+
+	if shard != nil {
+		logger.Info("using shard", "shard", shard.Name, "clusterSelector", shardClusterSelector, "organizationSelector", shardOrganizationSelector, "namespaces", shardNamespaces)
+	}
+
+	if leaderElectionID == "" {
+		leaderElectionID = "dockyards-pdns"
+
+		if shard != nil {
+			leaderElectionID = "dockyards-pdns-" + shard.Name
+		}
+	}
+
+	if leaderElectionNamespace == "" {
+		leaderElectionNamespace = dockyardsNamespace
+	}
+
+	if leaderElection {
+		logger.Info("using leader election", "id", leaderElectionID, "namespace", leaderElectionNamespace)
+	}
+
+	logger.Info("using cache layout", "pdnsName", cacheLayout.PDNSName, "pdnsNamespace", cacheLayout.PDNSNamespace, "backends", len(cacheLayout.Backends), "serviceSelector", cacheServiceSelector, "configNamespace", cacheLayout.ConfigNamespace)
+
+	m, err := manager.New(cfg, manager.Options{
+		Cache:                         cacheLayout.Options(),
+		HealthProbeBindAddress:        healthProbeBindAddress,
+		LeaderElection:                leaderElection,
+		LeaderElectionID:              leaderElectionID,
+		LeaderElectionNamespace:       leaderElectionNamespace,
+		LeaderElectionReleaseOnCancel: true,
+	})
 	if err != nil {
 		logger.Error("error creating manager", "err", err)
 
@@ -71,9 +197,49 @@ func main() {
 		os.Exit(1)
 	}
 
+	c := controllers.NewFieldOwnershipClient(m.GetClient(), dockyardsConfig, m.GetEventRecorderFor("dockyards-pdns"))
+
+	var plan *controllers.Plan
+	if dryRun {
+		plan = controllers.NewPlan()
+		c = controllers.NewPlanClient(c, plan)
+
+		err := m.Add(&controllers.PlanReporter{
+			Client:    m.GetClient(),
+			Plan:      plan,
+			ConfigMap: client.ObjectKey{Namespace: dockyardsNamespace, Name: dryRunReport},
+		})
+		if err != nil {
+			logger.Error("error adding plan reporter", "err", err)
+
+			os.Exit(1)
+		}
+
+		logger.Info("running in dry-run mode", "report", dryRunReport)
+	}
+
+	if cacheLayout.Restricted() {
+		err := controllers.LabelTSIGSecrets(ctx, m.GetAPIReader(), c)
+		if err != nil {
+			logger.Error("error labelling TSIG secrets", "err", err)
+
+			os.Exit(1)
+		}
+	}
+
+	if cacheLayout.ConfigNamespace != "" {
+		err := controllers.LabelRecordTemplateConfigMaps(ctx, m.GetAPIReader(), c)
+		if err != nil {
+			logger.Error("error labelling record template config maps", "err", err)
+
+			os.Exit(1)
+		}
+	}
+
 	err = (&controllers.DockyardsClusterReconciler{
-		Client:        m.GetClient(),
+		Client:        c,
 		ConfigManager: dockyardsConfig,
+		Shard:         shard,
 	}).SetupWithManager(m)
 	if err != nil {
 		logger.Error("error creating new dockyards cluster reconciler", "err", err)
@@ -82,8 +248,10 @@ func main() {
 	}
 
 	err = (&controllers.ZoneReconciler{
-		Client:        m.GetClient(),
+		Client:        c,
 		ConfigManager: dockyardsConfig,
+		Plan:          plan,
+		Shard:         shard,
 	}).SetupWithManager(m)
 	if err != nil {
 		logger.Error("error creating new zone reconciler", "err", err)
@@ -91,6 +259,82 @@ func main() {
 		os.Exit(1)
 	}
 
+	err = (&controllers.OrganizationReconciler{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+		Shard:         shard,
+	}).SetupWithManager(m)
+	if err != nil {
+		logger.Error("error creating new organization reconciler", "err", err)
+
+		os.Exit(1)
+	}
+
+	err = m.Add(&controllers.GarbageCollector{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+		Recorder:      m.GetEventRecorderFor("dockyards-pdns"),
+		Shard:         shard,
+	})
+	if err != nil {
+		logger.Error("error adding garbage collector", "err", err)
+
+		os.Exit(1)
+	}
+
+	err = m.Add(&controllers.DriftAuditor{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+		Recorder:      m.GetEventRecorderFor("dockyards-pdns"),
+		Plan:          plan,
+		Shard:         shard,
+	})
+	if err != nil {
+		logger.Error("error adding drift auditor", "err", err)
+
+		os.Exit(1)
+	}
+
+	err = m.Add(&controllers.ZoneExporter{
+		Client:        c,
+		ConfigManager: dockyardsConfig,
+		Plan:          plan,
+		Shard:         shard,
+	})
+	if err != nil {
+		logger.Error("error adding zone exporter", "err", err)
+
+		os.Exit(1)
+	}
+
+	configValidator := controllers.ConfigValidator{
+		Client:        m.GetClient(),
+		ConfigManager: dockyardsConfig,
+		Reader:        m.GetAPIReader(),
+		ConfigMap:     client.ObjectKey{Namespace: dockyardsNamespace, Name: configStatus},
+	}
+
+	err = m.Add(&configValidator)
+	if err != nil {
+		logger.Error("error adding config validator", "err", err)
+
+		os.Exit(1)
+	}
+
+	err = m.AddHealthzCheck("ping", healthz.Ping)
+	if err != nil {
+		logger.Error("error adding health check", "err", err)
+
+		os.Exit(1)
+	}
+
+	err = m.AddReadyzCheck("config", configValidator.ReadyCheck)
+	if err != nil {
+		logger.Error("error adding readiness check", "err", err)
+
+		os.Exit(1)
+	}
+
 	err = m.Start(ctx)
 	if err != nil {
 		logger.Error("error running manager", "err", err)
@@ -98,3 +342,37 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newShard returns the shard of the instance, nil when it is unsharded.
+func newShard(name, clusterSelector, organizationSelector string, namespaces []string) (*controllers.Shard, error) {
+	if name == "" {
+		if clusterSelector != "" || organizationSelector != "" || len(namespaces) != 0 {
+			return nil, fmt.Errorf("shard selectors require a shard name")
+		}
+
+		return nil, nil
+	}
+
+	shard := controllers.Shard{
+		Name:       name,
+		Namespaces: namespaces,
+	}
+
+	var err error
+
+	if clusterSelector != "" {
+		shard.ClusterSelector, err = labels.Parse(clusterSelector)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing shard cluster selector: %w", err)
+		}
+	}
+
+	if organizationSelector != "" {
+		shard.OrganizationSelector, err = labels.Parse(organizationSelector)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing shard organization selector: %w", err)
+		}
+	}
+
+	return &shard, nil
+}
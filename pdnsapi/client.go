@@ -0,0 +1,192 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pdnsapi implements the parts of the PowerDNS Authoritative HTTP API that are not covered by the powerdns-operator CRDs.
+package pdnsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultServerID = "localhost"
+
+// Client talks to a single PowerDNS server through its HTTP API.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	serverID   string
+	httpClient *http.Client
+	dryRun     func(method, path string, body []byte)
+}
+
+// ClientOption configures optional Client settings.
+type ClientOption func(*Client)
+
+// WithServerID sets the PowerDNS server id, defaults to "localhost".
+func WithServerID(serverID string) ClientOption {
+	return func(c *Client) {
+		c.serverID = serverID
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for requests.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithDryRun makes the client skip every request that is not a GET, record is called with the request instead.
+func WithDryRun(record func(method, path string, body []byte)) ClientOption {
+	return func(c *Client) {
+		c.dryRun = record
+	}
+}
+
+// NewClient returns a client for the PowerDNS API served at baseURL (e.g. "http://10.0.0.1:8081").
+func NewClient(baseURL, apiKey string, options ...ClientOption) *Client {
+	c := Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		apiKey:   apiKey,
+		serverID: defaultServerID,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+
+	for _, option := range options {
+		option(&c)
+	}
+
+	return &c
+}
+
+// StatusError is returned when the PowerDNS API responds with a non-successful status code.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("powerdns api returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a PowerDNS API not found error.
+func IsNotFound(err error) bool {
+	var statusError *StatusError
+
+	return errors.As(err, &statusError) && statusError.StatusCode == http.StatusNotFound
+}
+
+// IgnoreNotFound returns nil on PowerDNS API not found errors.
+func IgnoreNotFound(err error) error {
+	if IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+// CanonicalName returns name with a single trailing dot as used for zone and key ids.
+func CanonicalName(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+func (c *Client) serverPath(elements ...string) string {
+	escaped := make([]string, len(elements))
+	for i, element := range elements {
+		escaped[i] = url.PathEscape(element)
+	}
+
+	return "/api/v1/servers/" + url.PathEscape(c.serverID) + "/" + strings.Join(escaped, "/")
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var data []byte
+	if in != nil {
+		var err error
+
+		data, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.dryRun != nil && method != http.MethodGet {
+		c.dryRun(method, path, data)
+
+		return nil
+	}
+
+	var body io.Reader
+	if in != nil {
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-API-Key", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		statusError := StatusError{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(b)),
+		}
+
+		var apiError struct {
+			Error string `json:"error"`
+		}
+
+		if json.Unmarshal(b, &apiError) == nil && apiError.Error != "" {
+			statusError.Message = apiError.Error
+		}
+
+		return &statusError
+	}
+
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,327 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdnsapi_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+	"github.com/sudoswedenab/dockyards-pdns/test/fakepdns"
+)
+
+func TestClient(t *testing.T) {
+	server := fakepdns.NewServer("test-api-key")
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+
+	c := pdnsapi.NewClient(server.URL, "test-api-key")
+
+	t.Run("test ensure tsig key", func(t *testing.T) {
+		key := pdnsapi.TSIGKey{
+			Name:      "acme-challenge.org-cluster.test.com",
+			Algorithm: "hmac-sha256",
+			Key:       "c2VjcmV0",
+		}
+
+		err := c.EnsureTSIGKey(ctx, &key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key.Key = "cm90YXRlZA=="
+
+		err = c.EnsureTSIGKey(ctx, &key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := c.GetTSIGKey(ctx, key.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual.Key != key.Key {
+			t.Errorf("expected key %s, got %s", key.Key, actual.Key)
+		}
+
+		err = c.DeleteTSIGKey(ctx, key.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = c.GetTSIGKey(ctx, key.Name)
+		if !pdnsapi.IsNotFound(err) {
+			t.Errorf("expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("test ensure zone metadata", func(t *testing.T) {
+		expected := []string{
+			"0.0.0.0/0",
+			"::/0",
+		}
+
+		err := c.EnsureZoneMetadata(ctx, "acme-challenge.org-cluster.test.com", pdnsapi.MetadataAllowDNSUpdateFrom, expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual := server.Metadata("acme-challenge.org-cluster.test.com.", pdnsapi.MetadataAllowDNSUpdateFrom)
+		if !cmp.Equal(actual, expected) {
+			t.Error(cmp.Diff(expected, actual))
+		}
+	})
+
+	t.Run("test cryptokeys", func(t *testing.T) {
+		zone := "org-cluster.test.com"
+
+		server.AddZone(zone)
+
+		cryptokey := pdnsapi.Cryptokey{
+			KeyType:   pdnsapi.KeyTypeCSK,
+			Active:    true,
+			Algorithm: "ecdsap256sha256",
+		}
+
+		created, err := c.CreateCryptokey(ctx, zone, &cryptokey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(created.DS) == 0 {
+			t.Error("expected DS records for csk")
+		}
+
+		err = c.SetNSEC3Param(ctx, zone, "1 0 0 -")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.RectifyZone(ctx, zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := c.GetZone(ctx, zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !actual.DNSSEC || actual.NSEC3Param != "1 0 0 -" {
+			t.Errorf("expected signed zone with NSEC3, got %+v", actual)
+		}
+
+		err = c.DeleteCryptokey(ctx, zone, created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cryptokeys, err := c.ListCryptokeys(ctx, zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(cryptokeys) != 0 {
+			t.Errorf("expected no cryptokeys, got %v", cryptokeys)
+		}
+
+		_, err = c.GetZone(ctx, "unknown.test.com")
+		if !pdnsapi.IsNotFound(err) {
+			t.Errorf("expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("test patch rrsets", func(t *testing.T) {
+		zone := "org-other.test.com"
+
+		server.AddZone(zone)
+
+		rrsets := []pdnsapi.RRset{
+			{
+				Name:       "www.org-other.test.com.",
+				Type:       "A",
+				TTL:        300,
+				ChangeType: pdnsapi.ChangeTypeReplace,
+				Records: []pdnsapi.Record{
+					{Content: "192.0.2.1"},
+				},
+			},
+			{
+				Name:       "api.org-other.test.com.",
+				Type:       "A",
+				TTL:        300,
+				ChangeType: pdnsapi.ChangeTypeReplace,
+				Records: []pdnsapi.Record{
+					{Content: "192.0.2.2"},
+				},
+			},
+		}
+
+		err := c.PatchRRsets(ctx, zone, rrsets)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.PatchRRsets(ctx, zone, []pdnsapi.RRset{{Name: "api.org-other.test.com.", Type: "A", ChangeType: pdnsapi.ChangeTypeDelete}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := c.GetZone(ctx, zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []pdnsapi.RRset{
+			{
+				Name: "www.org-other.test.com.",
+				Type: "A",
+				TTL:  300,
+				Records: []pdnsapi.Record{
+					{Content: "192.0.2.1"},
+				},
+			},
+		}
+
+		if !cmp.Equal(actual.RRsets, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, actual.RRsets))
+		}
+	})
+
+	t.Run("test list zones", func(t *testing.T) {
+		zones, err := c.ListZones(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var found *pdnsapi.Zone
+		for i := range zones {
+			if zones[i].ID == "org-other.test.com." {
+				found = &zones[i]
+			}
+		}
+
+		if found == nil {
+			t.Fatalf("expected zone org-other.test.com. in %v", zones)
+		}
+
+		if len(found.RRsets) != 0 {
+			t.Errorf("expected zone without rrsets, got %v", found.RRsets)
+		}
+	})
+
+	t.Run("test create and delete zone", func(t *testing.T) {
+		zone := pdnsapi.Zone{
+			Name:        "org-new.test.com",
+			Kind:        "Master",
+			Nameservers: []string{"ns1.org-new.test.com"},
+		}
+
+		created, err := c.CreateZone(ctx, zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []pdnsapi.RRset{
+			{
+				Name: "org-new.test.com.",
+				Type: "NS",
+				TTL:  3600,
+				Records: []pdnsapi.Record{
+					{Content: "ns1.org-new.test.com."},
+				},
+			},
+		}
+
+		if !cmp.Equal(created.RRsets, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, created.RRsets))
+		}
+
+		_, err = c.CreateZone(ctx, zone)
+		if err == nil {
+			t.Error("expected error creating existing zone")
+		}
+
+		err = c.SetKind(ctx, zone.Name, "Native")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := c.GetZone(ctx, zone.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual.Kind != "Native" {
+			t.Errorf("expected kind Native, got %s", actual.Kind)
+		}
+
+		err = c.DeleteZone(ctx, zone.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = c.GetZone(ctx, zone.Name)
+		if !pdnsapi.IsNotFound(err) {
+			t.Errorf("expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("test dry run", func(t *testing.T) {
+		var requests []string
+
+		c := pdnsapi.NewClient(server.URL, "test-api-key", pdnsapi.WithDryRun(func(method, path string, _ []byte) {
+			requests = append(requests, method+" "+path)
+		}))
+
+		zone := "org-dry.test.com"
+
+		server.AddZone(zone)
+
+		err := c.SetZoneMetadata(ctx, zone, pdnsapi.MetadataAlsoNotify, []string{"192.0.2.1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{
+			"PUT /api/v1/servers/localhost/zones/org-dry.test.com./metadata/ALSO-NOTIFY",
+		}
+
+		if !cmp.Equal(requests, expected) {
+			t.Errorf("diff: %s", cmp.Diff(expected, requests))
+		}
+
+		metadata := server.Metadata(pdnsapi.CanonicalName(zone), pdnsapi.MetadataAlsoNotify)
+		if len(metadata) != 0 {
+			t.Errorf("expected no metadata, got %v", metadata)
+		}
+	})
+
+	t.Run("test unauthorized", func(t *testing.T) {
+		c := pdnsapi.NewClient(server.URL, "wrong-api-key")
+
+		_, err := c.GetTSIGKey(ctx, "test")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if pdnsapi.IsNotFound(err) {
+			t.Error("expected unauthorized error")
+		}
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdnsapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Cryptokey key types.
+const (
+	KeyTypeKSK = "ksk"
+	KeyTypeZSK = "zsk"
+	KeyTypeCSK = "csk"
+)
+
+// Cryptokey is a DNSSEC key of a zone.
+type Cryptokey struct {
+	ID        int      `json:"id,omitempty"`
+	KeyType   string   `json:"keytype"`
+	Active    bool     `json:"active"`
+	Published bool     `json:"published,omitempty"`
+	Algorithm string   `json:"algorithm,omitempty"`
+	Bits      int      `json:"bits,omitempty"`
+	DNSKey    string   `json:"dnskey,omitempty"`
+	DS        []string `json:"ds,omitempty"`
+}
+
+// ListCryptokeys returns the DNSSEC keys of a zone.
+func (c *Client) ListCryptokeys(ctx context.Context, zone string) ([]Cryptokey, error) {
+	var cryptokeys []Cryptokey
+
+	err := c.do(ctx, http.MethodGet, c.serverPath("zones", CanonicalName(zone), "cryptokeys"), nil, &cryptokeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return cryptokeys, nil
+}
+
+// CreateCryptokey generates a new DNSSEC key for a zone.
+func (c *Client) CreateCryptokey(ctx context.Context, zone string, cryptokey *Cryptokey) (*Cryptokey, error) {
+	var created Cryptokey
+
+	err := c.do(ctx, http.MethodPost, c.serverPath("zones", CanonicalName(zone), "cryptokeys"), cryptokey, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// DeleteCryptokey removes a DNSSEC key from a zone.
+func (c *Client) DeleteCryptokey(ctx context.Context, zone string, id int) error {
+	return c.do(ctx, http.MethodDelete, c.serverPath("zones", CanonicalName(zone), "cryptokeys", strconv.Itoa(id)), nil, nil)
+}
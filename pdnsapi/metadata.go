@@ -0,0 +1,83 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdnsapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// Zone metadata kinds used by dockyards-pdns.
+const (
+	MetadataTSIGAllowDNSUpdate = "TSIG-ALLOW-DNSUPDATE"
+	MetadataAllowDNSUpdateFrom = "ALLOW-DNSUPDATE-FROM"
+	MetadataTSIGAllowAXFR      = "TSIG-ALLOW-AXFR"
+	MetadataAllowAXFRFrom      = "ALLOW-AXFR-FROM"
+	MetadataAlsoNotify         = "ALSO-NOTIFY"
+)
+
+// Metadata is a single zone metadata kind and its values.
+type Metadata struct {
+	Kind     string   `json:"kind"`
+	Metadata []string `json:"metadata"`
+}
+
+// GetZoneMetadata returns the values of a metadata kind for a zone.
+func (c *Client) GetZoneMetadata(ctx context.Context, zone, kind string) ([]string, error) {
+	var metadata Metadata
+
+	err := c.do(ctx, http.MethodGet, c.serverPath("zones", CanonicalName(zone), "metadata", kind), nil, &metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.Metadata, nil
+}
+
+// SetZoneMetadata replaces the values of a metadata kind for a zone.
+func (c *Client) SetZoneMetadata(ctx context.Context, zone, kind string, values []string) error {
+	metadata := Metadata{
+		Kind:     kind,
+		Metadata: values,
+	}
+
+	return c.do(ctx, http.MethodPut, c.serverPath("zones", CanonicalName(zone), "metadata", kind), &metadata, nil)
+}
+
+// DeleteZoneMetadata removes all values of a metadata kind from a zone.
+func (c *Client) DeleteZoneMetadata(ctx context.Context, zone, kind string) error {
+	return c.do(ctx, http.MethodDelete, c.serverPath("zones", CanonicalName(zone), "metadata", kind), nil, nil)
+}
+
+// EnsureZoneMetadata sets the values of a metadata kind unless they are already present, in any order.
+func (c *Client) EnsureZoneMetadata(ctx context.Context, zone, kind string, values []string) error {
+	existing, err := c.GetZoneMetadata(ctx, zone, kind)
+	if IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	existing = slices.Clone(existing)
+	desired := slices.Clone(values)
+
+	slices.Sort(existing)
+	slices.Sort(desired)
+
+	if slices.Equal(existing, desired) {
+		return nil
+	}
+
+	return c.SetZoneMetadata(ctx, zone, kind, values)
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdnsapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// TSIGKey is a shared secret used to authenticate DNS messages such as dynamic updates and transfers.
+type TSIGKey struct {
+	// ID is the opaque key id, usually the key name with a trailing dot.
+	ID string `json:"id,omitempty"`
+	// Name of the key.
+	Name string `json:"name"`
+	// Algorithm of the key (e.g. "hmac-sha256").
+	Algorithm string `json:"algorithm"`
+	// Key is the base64 encoded secret.
+	Key string `json:"key,omitempty"`
+	// Type is set to "TSIGKey" by PowerDNS.
+	Type string `json:"type,omitempty"`
+}
+
+// GetTSIGKey returns the TSIG key with the given name, including its secret.
+func (c *Client) GetTSIGKey(ctx context.Context, name string) (*TSIGKey, error) {
+	var key TSIGKey
+
+	err := c.do(ctx, http.MethodGet, c.serverPath("tsigkeys", CanonicalName(name)), nil, &key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// CreateTSIGKey creates a TSIG key, PowerDNS generates a secret when Key is empty.
+func (c *Client) CreateTSIGKey(ctx context.Context, key *TSIGKey) (*TSIGKey, error) {
+	var created TSIGKey
+
+	err := c.do(ctx, http.MethodPost, c.serverPath("tsigkeys"), key, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// UpdateTSIGKey replaces the algorithm and secret of an existing TSIG key.
+func (c *Client) UpdateTSIGKey(ctx context.Context, key *TSIGKey) (*TSIGKey, error) {
+	var updated TSIGKey
+
+	err := c.do(ctx, http.MethodPut, c.serverPath("tsigkeys", CanonicalName(key.Name)), key, &updated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// DeleteTSIGKey removes the TSIG key with the given name.
+func (c *Client) DeleteTSIGKey(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, c.serverPath("tsigkeys", CanonicalName(name)), nil, nil)
+}
+
+// EnsureTSIGKey creates the key or updates it when the algorithm or secret differ.
+func (c *Client) EnsureTSIGKey(ctx context.Context, key *TSIGKey) error {
+	existing, err := c.GetTSIGKey(ctx, key.Name)
+	if IsNotFound(err) {
+		_, err = c.CreateTSIGKey(ctx, key)
+
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.Algorithm == key.Algorithm && existing.Key == key.Key {
+		return nil
+	}
+
+	_, err = c.UpdateTSIGKey(ctx, key)
+
+	return err
+}
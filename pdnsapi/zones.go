@@ -0,0 +1,146 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdnsapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// Zone is a zone as returned by the PowerDNS API.
+type Zone struct {
+	ID             string   `json:"id,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	Kind           string   `json:"kind,omitempty"`
+	Serial         uint32   `json:"serial,omitempty"`
+	NotifiedSerial uint32   `json:"notified_serial,omitempty"`
+	Masters        []string `json:"masters,omitempty"`
+	Nameservers    []string `json:"nameservers,omitempty"`
+	DNSSEC         bool     `json:"dnssec,omitempty"`
+	NSEC3Param     string   `json:"nsec3param,omitempty"`
+	SOAEditAPI     string   `json:"soa_edit_api,omitempty"`
+	RRsets         []RRset  `json:"rrsets,omitempty"`
+}
+
+// RRset change types.
+const (
+	ChangeTypeReplace = "REPLACE"
+	ChangeTypeDelete  = "DELETE"
+)
+
+// RRset is a resource record set of a zone.
+type RRset struct {
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	TTL        uint32    `json:"ttl,omitempty"`
+	ChangeType string    `json:"changetype,omitempty"`
+	Records    []Record  `json:"records"`
+	Comments   []Comment `json:"comments,omitempty"`
+}
+
+// Comment is a comment attached to an RRset.
+type Comment struct {
+	Content    string `json:"content"`
+	Account    string `json:"account"`
+	ModifiedAt int64  `json:"modified_at,omitempty"`
+}
+
+// Record is a single record of an RRset.
+type Record struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+// ListZones returns all zones of the server without their RRsets.
+func (c *Client) ListZones(ctx context.Context) ([]Zone, error) {
+	var zones []Zone
+
+	err := c.do(ctx, http.MethodGet, c.serverPath("zones"), nil, &zones)
+	if err != nil {
+		return nil, err
+	}
+
+	return zones, nil
+}
+
+// GetZone returns a zone including its RRsets.
+func (c *Client) GetZone(ctx context.Context, zone string) (*Zone, error) {
+	var z Zone
+
+	err := c.do(ctx, http.MethodGet, c.serverPath("zones", CanonicalName(zone)), nil, &z)
+	if err != nil {
+		return nil, err
+	}
+
+	return &z, nil
+}
+
+// CreateZone creates a zone, the nameservers of the zone are added as its apex NS records.
+func (c *Client) CreateZone(ctx context.Context, zone Zone) (*Zone, error) {
+	zone.Name = CanonicalName(zone.Name)
+
+	nameservers := make([]string, len(zone.Nameservers))
+	for i, nameserver := range zone.Nameservers {
+		nameservers[i] = CanonicalName(nameserver)
+	}
+
+	zone.Nameservers = nameservers
+
+	var z Zone
+
+	err := c.do(ctx, http.MethodPost, c.serverPath("zones"), zone, &z)
+	if err != nil {
+		return nil, err
+	}
+
+	return &z, nil
+}
+
+// DeleteZone deletes a zone and all of its records.
+func (c *Client) DeleteZone(ctx context.Context, zone string) error {
+	return c.do(ctx, http.MethodDelete, c.serverPath("zones", CanonicalName(zone)), nil, nil)
+}
+
+// SetKind changes the kind of a zone.
+func (c *Client) SetKind(ctx context.Context, zone, kind string) error {
+	body := map[string]any{
+		"kind": kind,
+	}
+
+	return c.do(ctx, http.MethodPut, c.serverPath("zones", CanonicalName(zone)), body, nil)
+}
+
+// SetNSEC3Param sets the NSEC3 parameters of a zone, an empty value switches the zone to NSEC.
+func (c *Client) SetNSEC3Param(ctx context.Context, zone, nsec3Param string) error {
+	body := map[string]any{
+		"nsec3param": nsec3Param,
+	}
+
+	return c.do(ctx, http.MethodPut, c.serverPath("zones", CanonicalName(zone)), body, nil)
+}
+
+// RectifyZone rectifies the ordering and auth data of a DNSSEC signed zone.
+func (c *Client) RectifyZone(ctx context.Context, zone string) error {
+	return c.do(ctx, http.MethodPut, c.serverPath("zones", CanonicalName(zone), "rectify"), nil, nil)
+}
+
+// PatchRRsets replaces or deletes RRsets of a zone according to their change type.
+func (c *Client) PatchRRsets(ctx context.Context, zone string, rrsets []RRset) error {
+	body := map[string]any{
+		"rrsets": rrsets,
+	}
+
+	return c.do(ctx, http.MethodPatch, c.serverPath("zones", CanonicalName(zone)), body, nil)
+}
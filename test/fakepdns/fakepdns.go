@@ -0,0 +1,574 @@
+// Copyright 2025 Sudo Sweden AB
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakepdns provides an in-memory stand-in for the PowerDNS HTTP API.
+package fakepdns
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sudoswedenab/dockyards-pdns/pdnsapi"
+)
+
+// Server is a fake PowerDNS API server backed by maps.
+type Server struct {
+	*httptest.Server
+
+	APIKey string
+
+	mu         sync.Mutex
+	tsigKeys   map[string]pdnsapi.TSIGKey
+	metadata   map[string]map[string][]string
+	zones      map[string]pdnsapi.Zone
+	cryptokeys map[string][]pdnsapi.Cryptokey
+	nextKeyID  int
+}
+
+// NewServer starts a fake PowerDNS API server that requires apiKey.
+func NewServer(apiKey string) *Server {
+	s := Server{
+		APIKey:     apiKey,
+		tsigKeys:   make(map[string]pdnsapi.TSIGKey),
+		metadata:   make(map[string]map[string][]string),
+		zones:      make(map[string]pdnsapi.Zone),
+		cryptokeys: make(map[string][]pdnsapi.Cryptokey),
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/servers/localhost/tsigkeys/{id}", s.getTSIGKey)
+	mux.HandleFunc("POST /api/v1/servers/localhost/tsigkeys", s.createTSIGKey)
+	mux.HandleFunc("PUT /api/v1/servers/localhost/tsigkeys/{id}", s.updateTSIGKey)
+	mux.HandleFunc("DELETE /api/v1/servers/localhost/tsigkeys/{id}", s.deleteTSIGKey)
+	mux.HandleFunc("GET /api/v1/servers/localhost/zones/{zone}/metadata/{kind}", s.getMetadata)
+	mux.HandleFunc("PUT /api/v1/servers/localhost/zones/{zone}/metadata/{kind}", s.putMetadata)
+	mux.HandleFunc("DELETE /api/v1/servers/localhost/zones/{zone}/metadata/{kind}", s.deleteMetadata)
+	mux.HandleFunc("GET /api/v1/servers/localhost/zones", s.listZones)
+	mux.HandleFunc("POST /api/v1/servers/localhost/zones", s.createZone)
+	mux.HandleFunc("GET /api/v1/servers/localhost/zones/{zone}", s.getZone)
+	mux.HandleFunc("PUT /api/v1/servers/localhost/zones/{zone}", s.updateZone)
+	mux.HandleFunc("PATCH /api/v1/servers/localhost/zones/{zone}", s.patchZone)
+	mux.HandleFunc("DELETE /api/v1/servers/localhost/zones/{zone}", s.deleteZone)
+	mux.HandleFunc("PUT /api/v1/servers/localhost/zones/{zone}/rectify", s.rectifyZone)
+	mux.HandleFunc("GET /api/v1/servers/localhost/zones/{zone}/cryptokeys", s.listCryptokeys)
+	mux.HandleFunc("POST /api/v1/servers/localhost/zones/{zone}/cryptokeys", s.createCryptokey)
+	mux.HandleFunc("DELETE /api/v1/servers/localhost/zones/{zone}/cryptokeys/{id}", s.deleteCryptokey)
+
+	s.Server = httptest.NewServer(s.authenticate(mux))
+
+	return &s
+}
+
+// TSIGKey returns a stored TSIG key by id.
+func (s *Server) TSIGKey(id string) (pdnsapi.TSIGKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, found := s.tsigKeys[id]
+
+	return key, found
+}
+
+// Metadata returns the stored metadata values of a zone.
+func (s *Server) Metadata(zone, kind string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.metadata[zone][kind]
+}
+
+// AddZone creates an empty Native zone.
+func (s *Server) AddZone(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := pdnsapi.CanonicalName(name)
+
+	s.zones[id] = pdnsapi.Zone{
+		ID:   id,
+		Name: id,
+		Kind: "Native",
+	}
+}
+
+// Zone returns a stored zone by id.
+func (s *Server) Zone(id string) (pdnsapi.Zone, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zone, found := s.zones[id]
+	if found {
+		zone.DNSSEC = len(s.cryptokeys[id]) > 0
+	}
+
+	return zone, found
+}
+
+// Cryptokeys returns the stored DNSSEC keys of a zone.
+func (s *Server) Cryptokeys(zone string) []pdnsapi.Cryptokey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cryptokeys[zone]
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != s.APIKey {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"error": message})
+}
+
+func (s *Server) getTSIGKey(w http.ResponseWriter, r *http.Request) {
+	key, found := s.TSIGKey(r.PathValue("id"))
+	if !found {
+		writeError(w, http.StatusNotFound, "TSIG key not found")
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, key)
+}
+
+func (s *Server) createTSIGKey(w http.ResponseWriter, r *http.Request) {
+	var key pdnsapi.TSIGKey
+
+	err := json.NewDecoder(r.Body).Decode(&key)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	key.ID = pdnsapi.CanonicalName(key.Name)
+	key.Type = "TSIGKey"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.tsigKeys[key.ID]
+	if found {
+		writeError(w, http.StatusConflict, "TSIG key already exists")
+
+		return
+	}
+
+	s.tsigKeys[key.ID] = key
+
+	writeJSON(w, http.StatusCreated, key)
+}
+
+func (s *Server) updateTSIGKey(w http.ResponseWriter, r *http.Request) {
+	var key pdnsapi.TSIGKey
+
+	err := json.NewDecoder(r.Body).Decode(&key)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.tsigKeys[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "TSIG key not found")
+
+		return
+	}
+
+	key.ID = id
+	key.Type = "TSIGKey"
+	s.tsigKeys[id] = key
+
+	writeJSON(w, http.StatusOK, key)
+}
+
+func (s *Server) deleteTSIGKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.tsigKeys[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "TSIG key not found")
+
+		return
+	}
+
+	delete(s.tsigKeys, id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getMetadata(w http.ResponseWriter, r *http.Request) {
+	zone := r.PathValue("zone")
+	kind := r.PathValue("kind")
+
+	writeJSON(w, http.StatusOK, pdnsapi.Metadata{Kind: kind, Metadata: s.Metadata(zone, kind)})
+}
+
+func (s *Server) putMetadata(w http.ResponseWriter, r *http.Request) {
+	var metadata pdnsapi.Metadata
+
+	err := json.NewDecoder(r.Body).Decode(&metadata)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	zone := r.PathValue("zone")
+	kind := r.PathValue("kind")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.metadata[zone] == nil {
+		s.metadata[zone] = make(map[string][]string)
+	}
+
+	s.metadata[zone][kind] = metadata.Metadata
+
+	writeJSON(w, http.StatusOK, pdnsapi.Metadata{Kind: kind, Metadata: metadata.Metadata})
+}
+
+func (s *Server) deleteMetadata(w http.ResponseWriter, r *http.Request) {
+	zone := r.PathValue("zone")
+	kind := r.PathValue("kind")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.metadata[zone], kind)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listZones(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zones := make([]pdnsapi.Zone, 0, len(s.zones))
+	for _, zone := range s.zones {
+		zone.RRsets = nil
+		zones = append(zones, zone)
+	}
+
+	slices.SortFunc(zones, func(a, b pdnsapi.Zone) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	writeJSON(w, http.StatusOK, zones)
+}
+
+func (s *Server) createZone(w http.ResponseWriter, r *http.Request) {
+	var zone pdnsapi.Zone
+
+	err := json.NewDecoder(r.Body).Decode(&zone)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zone.ID = zone.Name
+
+	_, found := s.zones[zone.ID]
+	if found {
+		writeError(w, http.StatusConflict, "Domain '"+zone.Name+"' already exists")
+
+		return
+	}
+
+	if zone.Kind == "" {
+		zone.Kind = "Native"
+	}
+
+	if len(zone.Nameservers) > 0 {
+		rrset := pdnsapi.RRset{
+			Name: zone.Name,
+			Type: "NS",
+			TTL:  3600,
+		}
+
+		for _, nameserver := range zone.Nameservers {
+			rrset.Records = append(rrset.Records, pdnsapi.Record{Content: nameserver})
+		}
+
+		zone.RRsets = append(zone.RRsets, rrset)
+		zone.Nameservers = nil
+	}
+
+	s.zones[zone.ID] = zone
+
+	writeJSON(w, http.StatusCreated, zone)
+}
+
+func (s *Server) deleteZone(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("zone")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.zones[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "Could not find domain")
+
+		return
+	}
+
+	delete(s.zones, id)
+	delete(s.metadata, id)
+	delete(s.cryptokeys, id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getZone(w http.ResponseWriter, r *http.Request) {
+	zone, found := s.Zone(r.PathValue("zone"))
+	if !found {
+		writeError(w, http.StatusNotFound, "Could not find domain")
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, zone)
+}
+
+func (s *Server) updateZone(w http.ResponseWriter, r *http.Request) {
+	var update map[string]json.RawMessage
+
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	id := r.PathValue("zone")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zone, found := s.zones[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "Could not find domain")
+
+		return
+	}
+
+	value, found := update["nsec3param"]
+	if found {
+		err := json.Unmarshal(value, &zone.NSEC3Param)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+			return
+		}
+	}
+
+	value, found = update["kind"]
+	if found {
+		err := json.Unmarshal(value, &zone.Kind)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+			return
+		}
+	}
+
+	s.zones[id] = zone
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) patchZone(w http.ResponseWriter, r *http.Request) {
+	var patch struct {
+		RRsets []pdnsapi.RRset `json:"rrsets"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&patch)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	id := r.PathValue("zone")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zone, found := s.zones[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "Could not find domain")
+
+		return
+	}
+
+	for _, change := range patch.RRsets {
+		rrsets := zone.RRsets[:0:0]
+		for _, rrset := range zone.RRsets {
+			if rrset.Name == change.Name && rrset.Type == change.Type {
+				continue
+			}
+
+			rrsets = append(rrsets, rrset)
+		}
+
+		switch change.ChangeType {
+		case pdnsapi.ChangeTypeReplace:
+			change.ChangeType = ""
+			rrsets = append(rrsets, change)
+		case pdnsapi.ChangeTypeDelete:
+		default:
+			writeError(w, http.StatusUnprocessableEntity, "Invalid changetype "+change.ChangeType)
+
+			return
+		}
+
+		zone.RRsets = rrsets
+	}
+
+	s.zones[id] = zone
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) rectifyZone(w http.ResponseWriter, r *http.Request) {
+	_, found := s.Zone(r.PathValue("zone"))
+	if !found {
+		writeError(w, http.StatusNotFound, "Could not find domain")
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"result": "Rectified"})
+}
+
+func (s *Server) listCryptokeys(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("zone")
+
+	_, found := s.Zone(id)
+	if !found {
+		writeError(w, http.StatusNotFound, "Could not find domain")
+
+		return
+	}
+
+	cryptokeys := s.Cryptokeys(id)
+	if cryptokeys == nil {
+		cryptokeys = []pdnsapi.Cryptokey{}
+	}
+
+	writeJSON(w, http.StatusOK, cryptokeys)
+}
+
+func (s *Server) createCryptokey(w http.ResponseWriter, r *http.Request) {
+	var cryptokey pdnsapi.Cryptokey
+
+	err := json.NewDecoder(r.Body).Decode(&cryptokey)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	id := r.PathValue("zone")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.zones[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "Could not find domain")
+
+		return
+	}
+
+	s.nextKeyID++
+
+	cryptokey.ID = s.nextKeyID
+	cryptokey.Published = true
+	cryptokey.DNSKey = fmt.Sprintf("257 3 13 fakekey%d", cryptokey.ID)
+
+	if cryptokey.KeyType != pdnsapi.KeyTypeZSK {
+		cryptokey.DS = []string{
+			fmt.Sprintf("%d 13 1 %040x", cryptokey.ID, cryptokey.ID),
+			fmt.Sprintf("%d 13 2 %064x", cryptokey.ID, cryptokey.ID),
+			fmt.Sprintf("%d 13 4 %096x", cryptokey.ID, cryptokey.ID),
+		}
+	}
+
+	s.cryptokeys[id] = append(s.cryptokeys[id], cryptokey)
+
+	writeJSON(w, http.StatusCreated, cryptokey)
+}
+
+func (s *Server) deleteCryptokey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("zone")
+
+	keyID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, cryptokey := range s.cryptokeys[id] {
+		if cryptokey.ID == keyID {
+			s.cryptokeys[id] = append(s.cryptokeys[id][:i], s.cryptokeys[id][i+1:]...)
+
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "Could not find cryptokey")
+}